@@ -0,0 +1,196 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+// AMIIndexFile lists the AWS images of Calyptia Core per region, as consumed
+// by scripts/aws/ec2-instance-templates.py.
+const AMIIndexFile = "operator.aws.index.json"
+
+type (
+	// AMI is an image of the AWS index.
+	AMI struct {
+		Region  string  `json:"region"`
+		ImageID string  `json:"ImageId"`
+		Version string  `json:"release"`
+		Tags    AMITags `json:"Tags,omitempty"`
+	}
+
+	// AMITags decodes both the list of Key and Value pairs returned by the
+	// EC2 API and a plain JSON object. It encodes as the former.
+	AMITags map[string]string
+
+	//go:generate moq -out ami_index_fetch_mock.go . AMIIndexFetch
+	AMIIndexFetch interface {
+		// GetAMIs returns every image of the index, or of the test index.
+		GetAMIs(ctx context.Context, test bool) ([]AMI, error)
+	}
+
+	// AMIIndex picks images from the AWS index according to FilterOpts.
+	AMIIndex struct {
+		Fetcher AMIIndexFetch
+	}
+
+	AMIIndexFetcher struct {
+		// BaseURL holding the index files, defaults to DefaultBaseURL.
+		BaseURL string
+		// Client used for the request, defaults to http.DefaultClient.
+		Client *http.Client
+		// Verifier checks the integrity of the index when set.
+		Verifier Verifier
+		// TestName is the file below BaseURL listing the images published
+		// for testing. This repository publishes none, so the test index is
+		// reported with ErrNotFound unless set.
+		TestName string
+	}
+
+	// AMIIndexFSFetcher reads the index from a filesystem such as a checkout
+	// of this repository or an embed.FS.
+	AMIIndexFSFetcher struct {
+		FS fs.FS
+		// Verifier checks the integrity of the index when set.
+		Verifier Verifier
+		// TestName is the file of FS listing the images published for
+		// testing, the test index is reported with ErrNotFound unless set.
+		TestName string
+	}
+
+	amiTag struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	}
+)
+
+func (t *AMITags) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return json.Unmarshal(trimmed, (*map[string]string)(t))
+	}
+
+	var tags []amiTag
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return err
+	}
+	*t = make(AMITags, len(tags))
+	for _, tag := range tags {
+		(*t)[tag.Key] = tag.Value
+	}
+	return nil
+}
+
+func (t AMITags) MarshalJSON() ([]byte, error) {
+	tags := make([]amiTag, 0, len(t))
+	for key, value := range t {
+		tags = append(tags, amiTag{Key: key, Value: value})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return json.Marshal(tags)
+}
+
+func (f *AMIIndexFetcher) GetAMIs(ctx context.Context, test bool) ([]AMI, error) {
+	base := strings.TrimSuffix(f.BaseURL, "/")
+	if base == "" {
+		base = DefaultBaseURL
+	}
+
+	name, err := amiIndexFile(test, f.TestName)
+	if err != nil {
+		return nil, err
+	}
+	var out []AMI
+	err = fetchJSON(ctx, f.Client, base+"/"+name, f.Verifier, &out)
+	return out, err
+}
+
+func (f *AMIIndexFSFetcher) GetAMIs(ctx context.Context, test bool) ([]AMI, error) {
+	name, err := amiIndexFile(test, f.TestName)
+	if err != nil {
+		return nil, err
+	}
+	var out []AMI
+	err = readJSON(ctx, f.FS, name, f.Verifier, &out)
+	return out, err
+}
+
+func amiIndexFile(test bool, testName string) (string, error) {
+	if !test {
+		return AMIIndexFile, nil
+	}
+	if testName == "" {
+		return "", fmt.Errorf("no AMI test index configured: %w", ErrNotFound)
+	}
+	return testName, nil
+}
+
+// Images returns the images of the index, or of the test index of the fetcher
+// when opts.TestIndex is set, available in opts.Region and equal to opts.Version.
+// Empty options match every image. The images are sorted by region then
+// from the newest version.
+func (i *AMIIndex) Images(ctx context.Context, opts FilterOpts) ([]AMI, error) {
+	var want *semver.Version
+	if opts.Version != "" {
+		var err error
+		if want, err = semver.NewVersion(opts.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	images, err := i.Fetcher.GetAMIs(ctx, opts.TestIndex)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get AMI index: %w", err)
+	}
+
+	type match struct {
+		AMI
+		version *semver.Version
+	}
+	var matches []match
+	for _, image := range images {
+		if opts.Region != "" && image.Region != opts.Region {
+			continue
+		}
+		ver, err := semver.NewVersion(image.Version)
+		if err != nil || (want != nil && !ver.Equal(want)) {
+			continue
+		}
+		matches = append(matches, match{AMI: image, version: ver})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Region != matches[j].Region {
+			return matches[i].Region < matches[j].Region
+		}
+		return matches[i].version.GreaterThan(matches[j].version)
+	})
+
+	out := make([]AMI, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.AMI)
+	}
+	return out, nil
+}
+
+// Image returns the image to deploy in opts.Region: the one of opts.Version
+// when set, the newest otherwise.
+func (i *AMIIndex) Image(ctx context.Context, opts FilterOpts) (AMI, error) {
+	if opts.Region == "" {
+		return AMI{}, fmt.Errorf("a region is required to pick an image")
+	}
+	images, err := i.Images(ctx, opts)
+	if err != nil {
+		return AMI{}, err
+	}
+	if len(images) == 0 {
+		return AMI{}, ErrNoMatchingImage
+	}
+	return images[0], nil
+}
@@ -0,0 +1,169 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAMITags_UnmarshalJSON(t *testing.T) {
+	tt := []struct {
+		name string
+		data string
+		want AMITags
+	}{
+		{
+			name: "ec2 list",
+			data: `[{"Key":"Name","Value":"calyptia-core"},{"Key":"Version","Value":"1.0.0"}]`,
+			want: AMITags{"Name": "calyptia-core", "Version": "1.0.0"},
+		},
+		{
+			name: "object",
+			data: ` {"Name":"calyptia-core"}`,
+			want: AMITags{"Name": "calyptia-core"},
+		},
+		{
+			name: "empty list",
+			data: `[]`,
+			want: AMITags{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got AMITags
+			if err := json.Unmarshal([]byte(tc.data), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %v != got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAMITags_MarshalJSON(t *testing.T) {
+	got, err := json.Marshal(AMITags{"Version": "1.0.0", "Name": "calyptia-core"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"Key":"Name","Value":"calyptia-core"},{"Key":"Version","Value":"1.0.0"}]`; want != string(got) {
+		t.Errorf("want: %v != got: %v", want, string(got))
+	}
+}
+
+func TestAMIIndex_Images(t *testing.T) {
+	fsys := fstest.MapFS{
+		AMIIndexFile: {Data: []byte(`[
+			{"region": "us-east-1", "release": "1.0.0", "ImageId": "ami-1", "Tags": [{"Key": "Name", "Value": "core"}]},
+			{"region": "us-east-1", "release": "1.1.0", "ImageId": "ami-2"},
+			{"region": "eu-west-1", "release": "1.0.0", "ImageId": "ami-3"},
+			{"region": "us-east-1", "release": "invalid", "ImageId": "ami-4"}
+		]`)},
+		"ami.test.json": {Data: []byte(`[
+			{"region": "us-east-1", "release": "1.2.0-rc1", "ImageId": "ami-5"}
+		]`)},
+	}
+	index := AMIIndex{Fetcher: &AMIIndexFSFetcher{FS: fsys, TestName: "ami.test.json"}}
+
+	tt := []struct {
+		name    string
+		opts    FilterOpts
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "all",
+			want: []string{"ami-3", "ami-2", "ami-1"},
+		},
+		{
+			name: "region",
+			opts: FilterOpts{Region: "us-east-1"},
+			want: []string{"ami-2", "ami-1"},
+		},
+		{
+			name: "version",
+			opts: FilterOpts{Version: "v1.0.0"},
+			want: []string{"ami-3", "ami-1"},
+		},
+		{
+			name: "region and version",
+			opts: FilterOpts{Region: "eu-west-1", Version: "1.1.0"},
+			want: []string{},
+		},
+		{
+			name: "test index",
+			opts: FilterOpts{TestIndex: true},
+			want: []string{"ami-5"},
+		},
+		{
+			name:    "invalid version",
+			opts:    FilterOpts{Version: "latest"},
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			images, err := index.Images(ctx, tc.opts)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, image := range images {
+				got = append(got, image.ImageID)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %v != got: %v", tc.want, got)
+			}
+		})
+	}
+
+	// No test index is published with the repository.
+	index = AMIIndex{Fetcher: &AMIIndexFSFetcher{FS: fsys}}
+	if _, err := index.Images(ctx, FilterOpts{TestIndex: true}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want: %v != got: %v", ErrNotFound, err)
+	}
+}
+
+func TestAMIIndex_Image(t *testing.T) {
+	fetcher := &AMIIndexFetchMock{
+		GetAMIsFunc: func(ctx context.Context, test bool) ([]AMI, error) {
+			return []AMI{
+				{Region: "us-east-1", ImageID: "ami-1", Version: "1.0.0"},
+				{Region: "us-east-1", ImageID: "ami-2", Version: "1.1.0", Tags: AMITags{"Name": "core"}},
+			}, nil
+		},
+	}
+	index := AMIIndex{Fetcher: fetcher}
+	ctx := context.Background()
+
+	got, err := index.Image(ctx, FilterOpts{Region: "us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AMI{Region: "us-east-1", ImageID: "ami-2", Version: "1.1.0", Tags: AMITags{"Name": "core"}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+
+	if _, err := index.Image(ctx, FilterOpts{Region: "eu-west-1"}); !errors.Is(err, ErrNoMatchingImage) {
+		t.Errorf("want: %v != got: %v", ErrNoMatchingImage, err)
+	}
+	if _, err := index.Image(ctx, FilterOpts{}); err == nil {
+		t.Error("expected an error without region")
+	}
+	if want, got := 2, len(fetcher.GetAMIsCalls()); want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
@@ -0,0 +1,268 @@
+// Package bundle assembles the index files, schemas and package names of an
+// operator release into a self-describing bundle for air-gapped deployments.
+// A bundle has the layout of this repository plus a manifest, so it is read
+// back with index.OpenSource, which checks the files of a zip archive or of a
+// directory written by WriteDir against the manifest, or index.NewFSSource.
+package bundle
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/install"
+)
+
+const (
+	// ManifestFile describes the bundle, at its root.
+	ManifestFile = index.BundleManifestFile
+	// FormatVersion is the version of the bundle layout.
+	FormatVersion = 1
+
+	dirPerm  = 0o755
+	filePerm = 0o644
+)
+
+var (
+	// indexFiles are bundled when the source has them, the operator index
+	// and mappings are required.
+	indexFiles = []string{
+		index.OperatorIndexFile,
+		index.OperatorChannelsFile,
+		index.OperatorYankedFile,
+		index.OperatorMappingsFile,
+		index.ContainerIndexFile,
+		index.ContainerChannelsFile,
+		index.ContainerYankedFile,
+	}
+
+	// schemaFiles are bundled when the source has them, the core schema is
+	// required.
+	schemaFiles = []string{
+		index.SchemaFile,
+		"core-fluent-bit-pretty.json",
+		index.LuaSchemaFile,
+		"core-fluent-bit-lua-pretty.json",
+		index.PluginsSchemaFile,
+		"core-fluent-bit-plugins-pretty.json",
+	}
+)
+
+type (
+	// Options select the release and the packages to bundle.
+	Options struct {
+		// Version is an exact version such as 3.119.0, a constraint, or empty
+		// for the latest stable release.
+		Version string
+		// CoreFluentBitVersion is the schema version to bundle when the
+		// mappings have no tagged image for the release.
+		CoreFluentBitVersion string
+		// OS families and Arch of the packages, every supported one when
+		// empty.
+		OS     []install.OSFamily
+		Arch   []string
+		Flavor install.Flavor
+		// PackageBaseURL defaults to install.DefaultBaseURL.
+		PackageBaseURL string
+		RPMRelease     string
+	}
+
+	// Manifest describes the content of a bundle.
+	Manifest struct {
+		FormatVersion int `json:"format_version"`
+		// Operator is the tag of the bundled release, such as v3.119.0.
+		Operator string `json:"operator"`
+		// CoreFluentBitImage is the default image of the release, empty when
+		// the mappings do not tag it.
+		CoreFluentBitImage string `json:"core_fluent_bit_image,omitempty"`
+		// CoreFluentBitVersion is the version of the bundled schemas.
+		CoreFluentBitVersion string `json:"core_fluent_bit_version"`
+		// Packages are the packages to install the release, to be added next
+		// to the bundle by their file name.
+		Packages []install.Plan `json:"packages"`
+		Files    []File         `json:"files"`
+	}
+
+	// File is a file of the bundle with its checksum.
+	File = index.BundleFile
+
+	// Bundle is an assembled bundle held in memory. It is an fs.FS with the
+	// layout of this repository.
+	Bundle struct {
+		Manifest Manifest
+		files    map[string][]byte
+	}
+)
+
+// Build resolves the release of opts through the files of src and bundles
+// its index files, schemas and package names.
+func Build(ctx context.Context, src index.FileFetch, opts Options) (*Bundle, error) {
+	b := &Bundle{files: map[string][]byte{}}
+	for _, name := range indexFiles {
+		required := name == index.OperatorIndexFile || name == index.OperatorMappingsFile
+		if err := b.copy(ctx, src, name, required); err != nil {
+			return nil, err
+		}
+	}
+
+	// The release is resolved from the bundled files, which ensures the
+	// bundle is usable as a source.
+	operator := &index.Operator{Fetcher: &index.OperatorIndexFSFetcher{FS: b}}
+	packages, err := plan(ctx, operator, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Manifest = Manifest{
+		FormatVersion:        FormatVersion,
+		Operator:             packages[0].Tag,
+		CoreFluentBitImage:   packages[0].CoreFluentBitImage,
+		CoreFluentBitVersion: index.ImageTag(packages[0].CoreFluentBitImage),
+		Packages:             packages,
+	}
+	if b.Manifest.CoreFluentBitVersion == "" {
+		b.Manifest.CoreFluentBitVersion = opts.CoreFluentBitVersion
+	}
+	if b.Manifest.CoreFluentBitVersion == "" {
+		return nil, fmt.Errorf("%w: no tagged Core Fluent Bit image for %s, set the Core Fluent Bit version",
+			index.ErrNoMatchingImage, b.Manifest.Operator)
+	}
+
+	for _, file := range schemaFiles {
+		name := path.Join(index.SchemaDir, b.Manifest.CoreFluentBitVersion, file)
+		if err := b.copy(ctx, src, name, file == index.SchemaFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(b.files)) {
+		sum := sha256.Sum256(b.files[name])
+		b.Manifest.Files = append(b.Manifest.Files, File{
+			Name:   name,
+			Size:   int64(len(b.files[name])),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	b.files[ManifestFile] = append(manifest, '\n')
+	return b, nil
+}
+
+// copy adds the file name of src to the bundle, skipping missing optional
+// files.
+func (b *Bundle) copy(ctx context.Context, src index.FileFetch, name string, required bool) error {
+	data, err := src.GetFile(ctx, name)
+	if errors.Is(err, index.ErrNotFound) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot bundle %s: %w", name, err)
+	}
+	b.files[name] = data
+	return nil
+}
+
+// plan returns the packages of the release for every OS family and arch.
+func plan(ctx context.Context, operator *index.Operator, opts Options) ([]install.Plan, error) {
+	if len(opts.OS) == 0 {
+		opts.OS = []install.OSFamily{install.OSDebian, install.OSRHEL, install.OSAlpine}
+	}
+	if len(opts.Arch) == 0 {
+		opts.Arch = []string{"amd64", "arm64"}
+	}
+
+	planner := &install.Planner{Operator: operator}
+	version := opts.Version
+	var out []install.Plan
+	for _, family := range opts.OS {
+		for _, arch := range opts.Arch {
+			p, err := planner.Plan(ctx, install.Request{
+				OS:         family,
+				Arch:       arch,
+				Flavor:     opts.Flavor,
+				Version:    version,
+				BaseURL:    opts.PackageBaseURL,
+				RPMRelease: opts.RPMRelease,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot plan %s %s packages: %w", family, arch, err)
+			}
+			// Every package is of the release resolved first.
+			version = p.Version
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// WriteDir writes the bundle below dir.
+func (b *Bundle) WriteDir(dir string) error {
+	for name, data := range b.files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), dirPerm); err != nil {
+			return err
+		}
+		if err := index.WriteFileAtomic(target, data, filePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteZip writes the bundle as a zip archive, the manifest first.
+func (b *Bundle) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	names := append([]string{ManifestFile}, fileNames(b.Manifest.Files)...)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(b.files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadManifest reads the manifest of the bundle in fsys and checks the
+// checksum of every file it lists.
+func ReadManifest(fsys fs.FS) (Manifest, error) {
+	data, err := fs.ReadFile(fsys, ManifestFile)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("cannot read bundle manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("cannot decode bundle manifest: %w", err)
+	}
+	if m.FormatVersion != FormatVersion {
+		return Manifest{}, fmt.Errorf("unsupported bundle format %d", m.FormatVersion)
+	}
+
+	return m, index.VerifyBundleFiles(fsys, m.Files)
+}
+
+func fileNames(files []File) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Name
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/install"
+)
+
+const testSchema = `{"fluent-bit":{"version":"24.7.1","schema_version":"1","os":"linux"},` +
+	`"customs":[],"inputs":[{"type":"input","name":"dummy","description":"Generate dummy data","properties":{}}],"filters":[],"outputs":[]}`
+
+func testRepository() fstest.MapFS {
+	return fstest.MapFS{
+		index.OperatorIndexFile:  {Data: []byte(`["v3.116.0","v3.119.0","v3.120.0"]`)},
+		index.OperatorYankedFile: {Data: []byte(`[{"version":"v3.120.0","reason":"broken"}]`)},
+		index.OperatorMappingsFile: {Data: []byte(`{
+			"v3.119.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1",
+			"v3.116.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:"
+		}`)},
+		index.ContainerIndexFile:                     {Data: []byte(`["v24.7.1"]`)},
+		"schemas/24.7.1/" + index.SchemaFile:         {Data: []byte(testSchema)},
+		"schemas/24.7.1/" + index.LuaSchemaFile:      {Data: []byte(`{"processingRules":{}}`)},
+		"schemas/24.6.0/" + index.SchemaFile:         {Data: []byte(testSchema)},
+		"schemas/24.6.0/core-fluent-bit-pretty.json": {Data: []byte(testSchema)},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	src := &index.FSFileFetcher{FS: testRepository()}
+
+	b, err := Build(ctx, src, Options{OS: []install.OSFamily{install.OSDebian, install.OSRHEL}, Arch: []string{"x86_64"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := b.Manifest
+	if m.Operator != "v3.119.0" || m.CoreFluentBitVersion != "24.7.1" || m.CoreFluentBitImage != "ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1" {
+		t.Fatalf("want: v3.119.0 24.7.1 != got: %s %s (%s)", m.Operator, m.CoreFluentBitVersion, m.CoreFluentBitImage)
+	}
+
+	var packages []string
+	for _, p := range m.Packages {
+		packages = append(packages, p.Filename)
+	}
+	wantPackages := []string{"calyptia-core-operator_3.119.0_amd64.deb", "calyptia-core-operator-3.119.0-1.x86_64.rpm"}
+	if !reflect.DeepEqual(wantPackages, packages) {
+		t.Fatalf("want: %v != got: %v", wantPackages, packages)
+	}
+
+	var files []string
+	for _, f := range m.Files {
+		files = append(files, f.Name)
+	}
+	wantFiles := []string{
+		index.ContainerIndexFile,
+		index.OperatorIndexFile,
+		index.OperatorYankedFile,
+		index.OperatorMappingsFile,
+		"schemas/24.7.1/" + index.LuaSchemaFile,
+		"schemas/24.7.1/" + index.SchemaFile,
+	}
+	if !reflect.DeepEqual(wantFiles, files) {
+		t.Fatalf("want: %v != got: %v", wantFiles, files)
+	}
+
+	if err := fstest.TestFS(b, append(wantFiles, ManifestFile)...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(b); err != nil {
+		t.Fatal(err)
+	}
+	assertSource(t, index.NewFSSource("bundle", b))
+}
+
+func TestBuild_untaggedImage(t *testing.T) {
+	ctx := context.Background()
+	src := &index.FSFileFetcher{FS: testRepository()}
+
+	_, err := Build(ctx, src, Options{Version: "3.116.0"})
+	if !errors.Is(err, index.ErrNoMatchingImage) {
+		t.Fatalf("want: %v != got: %v", index.ErrNoMatchingImage, err)
+	}
+
+	b, err := Build(ctx, src, Options{Version: "3.116.0", CoreFluentBitVersion: "24.6.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Manifest.CoreFluentBitImage != "" || len(b.Manifest.Packages) != 6 {
+		t.Fatalf("want: no image and 6 packages != got: %q and %d", b.Manifest.CoreFluentBitImage, len(b.Manifest.Packages))
+	}
+	if _, err := fs.Stat(b, "schemas/24.6.0/core-fluent-bit-pretty.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Build(ctx, src, Options{Version: "3.116.0", CoreFluentBitVersion: "24.5.0"})
+	if !errors.Is(err, index.ErrNotFound) {
+		t.Fatalf("want: %v != got: %v", index.ErrNotFound, err)
+	}
+}
+
+func TestBundle_Write(t *testing.T) {
+	b, err := Build(context.Background(), &index.FSFileFetcher{FS: testRepository()}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	archive := filepath.Join(dir, "bundle.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.WriteZip(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertSource(t, index.OpenSource(archive))
+
+	tree := filepath.Join(dir, "tree")
+	if err := b.WriteDir(tree); err != nil {
+		t.Fatal(err)
+	}
+	assertSource(t, index.OpenSource(tree))
+
+	// A modified file no longer matches the manifest.
+	if err := os.WriteFile(filepath.Join(tree, index.OperatorIndexFile), []byte(`["v3.119.0"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(os.DirFS(tree)); !errors.Is(err, index.ErrVerification) {
+		t.Fatalf("want: %v != got: %v", index.ErrVerification, err)
+	}
+	_, err = index.OpenSource(tree).Schemas.GetSchema(context.Background(), "24.7.1")
+	if !errors.Is(err, index.ErrVerification) || errors.Is(err, index.ErrNotFound) {
+		t.Fatalf("want: %v != got: %v", index.ErrVerification, err)
+	}
+
+	// So does a modified file of an archive, checked when it is opened.
+	b.files[index.OperatorIndexFile] = []byte(`["v3.119.0"]`)
+	corrupted := filepath.Join(dir, "corrupted.zip")
+	f, err = os.Create(corrupted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.WriteZip(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = index.OpenSource(corrupted).Operator.GetImages(context.Background())
+	if !errors.Is(err, index.ErrVerification) || errors.Is(err, index.ErrNotFound) {
+		t.Fatalf("want: %v != got: %v", index.ErrVerification, err)
+	}
+}
+
+func assertSource(t *testing.T, src index.Source) {
+	t.Helper()
+	ctx := context.Background()
+
+	latest, err := (&index.Operator{Fetcher: src.Operator}).Latest(ctx, index.ChannelStable)
+	if err != nil || latest != "v3.119.0" {
+		t.Fatalf("want: v3.119.0 != got: %s (%v)", latest, err)
+	}
+	schema, err := src.Schemas.GetSchema(ctx, "24.7.1")
+	if err != nil || len(schema.Inputs) != 1 {
+		t.Fatalf("want: 1 input != got: %d (%v)", len(schema.Inputs), err)
+	}
+	versions, err := src.Schemas.(*index.SchemaFSFetcher).Versions(ctx)
+	if err != nil || !reflect.DeepEqual(versions, []string{"24.7.1"}) {
+		t.Fatalf("want: [24.7.1] != got: %v (%v)", versions, err)
+	}
+}
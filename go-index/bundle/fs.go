@@ -0,0 +1,136 @@
+package bundle
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	// file is an open file of a bundle.
+	file struct {
+		*bytes.Reader
+		info fileInfo
+	}
+
+	// dir is an open directory of a bundle.
+	dir struct {
+		info    fileInfo
+		entries []fs.DirEntry
+	}
+
+	fileInfo struct {
+		name string
+		size int64
+		dir  bool
+	}
+
+	dirEntry struct{ fileInfo }
+)
+
+// Open opens a file, or a directory to Stat it, in the layout of this
+// repository.
+func (b *Bundle) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := b.files[name]; ok {
+		return &file{Reader: bytes.NewReader(data), info: fileInfo{name: path.Base(name), size: int64(len(data))}}, nil
+	}
+	if entries, err := b.ReadDir(name); err == nil {
+		return &dir{info: fileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists the files and directories directly below name.
+func (b *Bundle) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) || !b.isDir(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := map[string]fileInfo{}
+	for file, data := range b.files {
+		rest, ok := strings.CutPrefix(file, dirPrefix(name))
+		if !ok {
+			continue
+		}
+		if child, _, nested := strings.Cut(rest, "/"); nested {
+			entries[child] = fileInfo{name: child, dir: true}
+		} else {
+			entries[rest] = fileInfo{name: rest, size: int64(len(data))}
+		}
+	}
+
+	out := make([]fs.DirEntry, 0, len(entries))
+	for _, info := range entries {
+		out = append(out, dirEntry{info})
+	}
+	slices.SortFunc(out, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return out, nil
+}
+
+func (b *Bundle) isDir(name string) bool {
+	if name == "." {
+		return true
+	}
+	for file := range b.files {
+		if strings.HasPrefix(file, dirPrefix(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func dirPrefix(name string) string {
+	if name == "." {
+		return ""
+	}
+	return name + "/"
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir returns the next n entries, every remaining one when n <= 0.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		out := d.entries
+		d.entries = nil
+		return out, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	out := d.entries[:n]
+	d.entries = d.entries[n:]
+	return out, nil
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.dir }
+func (i fileInfo) Sys() any           { return nil }
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | dirPerm
+	}
+	return filePerm
+}
+
+func (e dirEntry) Type() fs.FileMode          { return e.Mode().Type() }
+func (e dirEntry) Info() (fs.FileInfo, error) { return e.fileInfo, nil }
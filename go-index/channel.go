@@ -0,0 +1,201 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+const (
+	ChannelEdge = "edge"
+	ChannelLTS  = "lts"
+
+	// ContainerChannelsFile and OperatorChannelsFile hold the channel
+	// definitions, next to the index they apply to.
+	ContainerChannelsFile = "container.channels.json"
+	OperatorChannelsFile  = "operator.channels.json"
+)
+
+var ErrUnknownChannel = fmt.Errorf("unknown release channel")
+
+// DefaultChannels apply when no channel definitions are published: stable
+// follows the releases, edge the pre-releases too and lts the patches of the
+// minor before the latest one. Published definitions are merged over these.
+var DefaultChannels = Channels{
+	ChannelStable: {},
+	ChannelEdge:   {PreReleases: true},
+	ChannelLTS:    {PreviousMinors: 1},
+}
+
+type (
+	// ChannelPolicy selects the releases followed by a channel. Aliases such as
+	// v1.2 and end of life releases are never selected.
+	ChannelPolicy struct {
+		// PreReleases includes release candidates.
+		PreReleases bool `json:"pre_releases,omitempty"`
+		// Minors restricts the channel to the patches of these minor versions,
+		// such as "1.2", as designated for long term support.
+		Minors []string `json:"minors,omitempty"`
+		// PreviousMinors, when Minors is empty, restricts the channel to the
+		// patches of the minor that many minors before the latest release,
+		// such as 1.1 for 1 when 1.2.0 is the latest release.
+		PreviousMinors int `json:"previous_minors,omitempty"`
+		// Constraint restricts the channel to a version range, such as
+		// ">= 1.0, < 2.0".
+		Constraint string `json:"constraint,omitempty"`
+	}
+
+	// Channels maps channel names to their policy.
+	Channels map[string]ChannelPolicy
+
+	// ChannelFetch is implemented by the fetchers able to read the channel
+	// definitions published next to an index.
+	ChannelFetch interface {
+		GetChannels(ctx context.Context) (Channels, error)
+	}
+)
+
+// Latest returns the highest release followed by channel.
+func (c Channels) Latest(channel string, releases Releases) (string, error) {
+	policy, ok := c[channel]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownChannel, channel)
+	}
+
+	var constraint semver.Constraints
+	if policy.Constraint != "" {
+		var err error
+		if constraint, err = semver.NewConstraint(policy.Constraint); err != nil {
+			return "", fmt.Errorf("invalid constraint of channel %s: %w", channel, err)
+		}
+	}
+
+	if len(policy.Minors) == 0 && policy.PreviousMinors > 0 {
+		minor, ok := policy.previousMinor(releases, constraint)
+		if !ok {
+			return "", fmt.Errorf("%w in channel %s", ErrNoMatchingImage, channel)
+		}
+		policy.Minors = []string{minor}
+	}
+
+	var (
+		latest *semver.Version
+		tag    string
+	)
+	for _, release := range releases {
+		ver, ok := policy.allows(release, constraint)
+		if ok && (latest == nil || ver.GreaterThan(latest)) {
+			latest, tag = ver, release.Tag
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("%w in channel %s", ErrNoMatchingImage, channel)
+	}
+	return tag, nil
+}
+
+func (p ChannelPolicy) allows(release Release, constraint semver.Constraints) (*semver.Version, bool) {
+	if release.EOL || aliasTag.MatchString(release.Tag) {
+		return nil, false
+	}
+	ver, err := semver.NewSemver(release.Tag)
+	if err != nil {
+		return nil, false
+	}
+
+	if !p.PreReleases && (ver.Prerelease() != "" || release.Channel == ChannelRC) {
+		return nil, false
+	}
+	if len(p.Minors) > 0 {
+		segments := ver.Segments()
+		minor := fmt.Sprintf("%d.%d", segments[0], segments[1])
+		if !slices.ContainsFunc(p.Minors, func(m string) bool { return strings.TrimPrefix(m, "v") == minor }) {
+			return nil, false
+		}
+	}
+	if constraint != nil && !constraint.Check(ver.Core()) {
+		return nil, false
+	}
+	return ver, true
+}
+
+// previousMinor returns the minor PreviousMinors before the latest one among
+// the releases allowed by p.
+func (p ChannelPolicy) previousMinor(releases Releases, constraint semver.Constraints) (string, bool) {
+	seen := map[string]bool{}
+	var minors []*semver.Version
+	for _, release := range releases {
+		ver, ok := p.allows(release, constraint)
+		if !ok {
+			continue
+		}
+		segments := ver.Segments()
+		minor := fmt.Sprintf("%d.%d", segments[0], segments[1])
+		if !seen[minor] {
+			seen[minor] = true
+			minors = append(minors, semver.Must(semver.NewVersion(minor)))
+		}
+	}
+	if len(minors) <= p.PreviousMinors {
+		return "", false
+	}
+	slices.SortFunc(minors, func(a, b *semver.Version) int { return b.Compare(a) })
+	segments := minors[p.PreviousMinors].Segments()
+	return fmt.Sprintf("%d.%d", segments[0], segments[1]), true
+}
+
+// GetChannels reads the channel definitions published next to the index.
+func (c *ContainerIndexFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	var out Channels
+	url := c.url()
+	err := fetchJSON(ctx, c.Client, url[:strings.LastIndex(url, "/")+1]+ContainerChannelsFile, c.Verifier, &out)
+	return out, err
+}
+
+// GetChannels reads the channel definitions stored next to the index.
+func (c *ContainerIndexFSFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	var out Channels
+	err := readJSON(ctx, c.FS, path.Join(path.Dir(c.name()), ContainerChannelsFile), c.Verifier, &out)
+	return out, err
+}
+
+// GetChannels reads the channel definitions published next to the index.
+func (c *OperatorIndexFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	var out Channels
+	url := c.url()
+	err := fetchJSON(ctx, c.Client, url[:strings.LastIndex(url, "/")+1]+OperatorChannelsFile, c.Verifier, &out)
+	return out, err
+}
+
+// GetChannels reads the channel definitions stored next to the index.
+func (c *OperatorIndexFSFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	var out Channels
+	err := readJSON(ctx, c.FS, path.Join(path.Dir(c.name()), OperatorChannelsFile), c.Verifier, &out)
+	return out, err
+}
+
+// fetchChannels merges the channel definitions of fetcher, when it
+// implements ChannelFetch and publishes them, over DefaultChannels.
+func fetchChannels(ctx context.Context, fetcher any) (Channels, error) {
+	out := maps.Clone(DefaultChannels)
+
+	f, ok := fetcher.(ChannelFetch)
+	if !ok {
+		return out, nil
+	}
+	channels, err := f.GetChannels(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get channels: %w", err)
+	}
+	maps.Copy(out, channels)
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestChannels_Latest(t *testing.T) {
+	releases := Releases{
+		{Tag: "v1.0.0"},
+		{Tag: "v1.1.0"},
+		{Tag: "v1.1.3"},
+		{Tag: "v1.2.0"},
+		{Tag: "v1.2.1", EOL: true},
+		{Tag: "v1.3.0"},
+		{Tag: "v1.4.0-rc1"},
+		{Tag: "v1.4.0", Channel: ChannelRC},
+		{Tag: "v2"},
+	}
+	channels := Channels{
+		ChannelStable: {},
+		ChannelEdge:   {PreReleases: true},
+		ChannelLTS:    {Minors: []string{"1.1", "v1.2"}},
+		"v1.2":        {Constraint: "~> 1.2.0"},
+		"previous":    {PreviousMinors: 1},
+		"oldest":      {PreviousMinors: 3},
+		"too old":     {PreviousMinors: 4},
+		"broken":      {Constraint: "nope"},
+	}
+
+	tt := []struct {
+		channel string
+		want    string
+		wantErr error
+	}{
+		{channel: ChannelStable, want: "v1.3.0"},
+		{channel: ChannelEdge, want: "v1.4.0"},
+		{channel: ChannelLTS, want: "v1.2.0"},
+		{channel: "v1.2", want: "v1.2.0"},
+		{channel: "previous", want: "v1.2.0"},
+		{channel: "oldest", want: "v1.0.0"},
+		{channel: "too old", wantErr: ErrNoMatchingImage},
+		{channel: "nightly", wantErr: ErrUnknownChannel},
+	}
+	for _, tc := range tt {
+		t.Run(tc.channel, func(t *testing.T) {
+			got, err := channels.Latest(tc.channel, releases)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("want: %v != got: %v", tc.wantErr, err)
+			}
+			if tc.want != got {
+				t.Errorf("want: %v != got: %v", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := channels.Latest("broken", releases); err == nil {
+		t.Error("want error for an invalid constraint")
+	}
+}
+
+func TestOperator_Latest(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		OperatorIndexFile:    &fstest.MapFile{Data: []byte(`["v1.0.0","v1.0.1","v1.1.0","v1.2.0-rc1"]`)},
+		OperatorChannelsFile: &fstest.MapFile{Data: []byte(`{"lts":{"minors":["1.0"]}}`)},
+	}
+	operator := &Operator{Fetcher: &OperatorIndexFSFetcher{FS: fsys}}
+
+	for channel, want := range map[string]string{
+		ChannelStable: "v1.1.0",
+		ChannelEdge:   "v1.2.0-rc1",
+		ChannelLTS:    "v1.0.1",
+	} {
+		got, err := operator.Latest(ctx, channel)
+		if err != nil {
+			t.Fatalf("%s: %v", channel, err)
+		}
+		if want != got {
+			t.Errorf("want: %v != got: %v", want, got)
+		}
+	}
+
+	// Without a channels file lts follows the minor before the latest one.
+	delete(fsys, OperatorChannelsFile)
+	if got, err := operator.Latest(ctx, ChannelLTS); err != nil || got != "v1.0.1" {
+		t.Errorf("want: v1.0.1 != got: %v (%v)", got, err)
+	}
+	if _, err := operator.Latest(ctx, "nightly"); !errors.Is(err, ErrUnknownChannel) {
+		t.Errorf("want: %v != got: %v", ErrUnknownChannel, err)
+	}
+}
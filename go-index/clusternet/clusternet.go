@@ -0,0 +1,291 @@
+// Package clusternet validates the network plan of a Calyptia Core cluster:
+// the pod and service CIDRs, the cluster DNS address and the node port range,
+// against each other and against the networks of the host.
+package clusternet
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+
+	// CodeInvalid reports a value that cannot be parsed.
+	CodeInvalid = "invalid"
+	// CodeNotMasked reports a CIDR with host bits set, such as 10.42.0.1/16.
+	CodeNotMasked = "not-masked"
+	// CodeFamily reports CIDRs of different address families.
+	CodeFamily = "family"
+	// CodeSize reports a CIDR too small or too large for its use.
+	CodeSize = "size"
+	// CodeOverlap reports a CIDR overlapping another CIDR, an address or a
+	// network of the host.
+	CodeOverlap = "overlap"
+	// CodeDNSOutside reports a cluster DNS address outside the service CIDR.
+	CodeDNSOutside = "dns-outside-service-cidr"
+	// CodeDNSReserved reports a cluster DNS address that cannot be assigned to
+	// a service.
+	CodeDNSReserved = "dns-reserved"
+	// CodePortRange reports an invalid node port range.
+	CodePortRange = "port-range"
+
+	FieldClusterCIDR   = "cluster-cidr"
+	FieldServiceCIDR   = "service-cidr"
+	FieldClusterDNS    = "cluster-dns"
+	FieldNodePortRange = "service-node-port-range"
+
+	// nodeMaskV4 and nodeMaskV6 are the sizes of the pod CIDR of each node,
+	// carved out of the cluster CIDR.
+	nodeMaskV4 = 24
+	nodeMaskV6 = 64
+	// maxServiceHostBits is the largest service CIDR accepted by the API
+	// server, a /12 in IPv4 and a /108 in IPv6.
+	maxServiceHostBits = 20
+	// minNodePort keeps the node ports out of the well known ports.
+	minNodePort = 1024
+)
+
+var fieldLabels = map[string]string{
+	FieldClusterCIDR: "cluster CIDR",
+	FieldServiceCIDR: "service CIDR",
+}
+
+type (
+	Severity string
+
+	// Plan is the network configuration of the cluster, as given to the
+	// installer: CIDRs such as 10.42.0.0/16, an address and a port range such
+	// as 30000-32767.
+	Plan struct {
+		ClusterCIDR   string `json:"cluster_cidr"`
+		ServiceCIDR   string `json:"service_cidr"`
+		ClusterDNS    string `json:"cluster_dns"`
+		NodePortRange string `json:"service_node_port_range"`
+	}
+
+	// HostNetwork holds the networks of the host the cluster runs on.
+	HostNetwork struct {
+		// Interfaces are the addresses of the interfaces with their prefix
+		// length, such as 192.168.1.10/24.
+		Interfaces []netip.Prefix `json:"interfaces,omitempty"`
+		// Routes are the destinations of the routing table. Default routes
+		// are ignored.
+		Routes      []netip.Prefix `json:"routes,omitempty"`
+		Nameservers []netip.Addr   `json:"nameservers,omitempty"`
+	}
+
+	// Diagnostic is a problem of the plan. Conflict holds the value the field
+	// conflicts with, if any.
+	Diagnostic struct {
+		Severity Severity `json:"severity"`
+		Code     string   `json:"code"`
+		Field    string   `json:"field"`
+		Value    string   `json:"value"`
+		Conflict string   `json:"conflict,omitempty"`
+		Message  string   `json:"message"`
+	}
+
+	// PortRange is an inclusive range of ports.
+	PortRange struct {
+		First, Last uint16
+	}
+
+	validator struct {
+		diagnostics []Diagnostic
+	}
+)
+
+// Validate checks plan against host and returns the problems found, none when
+// the plan is valid. Checks depending on a value that cannot be parsed are
+// skipped.
+func Validate(plan Plan, host HostNetwork) []Diagnostic {
+	v := &validator{}
+
+	cluster, clusterOK := v.prefix(FieldClusterCIDR, plan.ClusterCIDR)
+	service, serviceOK := v.prefix(FieldServiceCIDR, plan.ServiceCIDR)
+	if clusterOK {
+		v.clusterSize(cluster)
+		v.host(FieldClusterCIDR, cluster, host)
+	}
+	if serviceOK {
+		v.serviceSize(service)
+		v.host(FieldServiceCIDR, service, host)
+	}
+	if clusterOK && serviceOK {
+		switch {
+		case cluster.Addr().Is4() != service.Addr().Is4():
+			v.report(SeverityError, CodeFamily, FieldServiceCIDR, service.String(), cluster.String(),
+				"service CIDR %s and cluster CIDR %s are of different address families", service, cluster)
+		case cluster.Overlaps(service):
+			v.report(SeverityError, CodeOverlap, FieldServiceCIDR, service.String(), cluster.String(),
+				"service CIDR %s overlaps cluster CIDR %s", service, cluster)
+		}
+	}
+
+	v.dns(plan.ClusterDNS, service, serviceOK)
+	if plan.NodePortRange != "" {
+		if _, err := ParsePortRange(plan.NodePortRange); err != nil {
+			v.report(SeverityError, CodePortRange, FieldNodePortRange, plan.NodePortRange, "", "%v", err)
+		}
+	}
+	return v.diagnostics
+}
+
+func (v *validator) report(severity Severity, code, field, value, conflict, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Field:    field,
+		Value:    value,
+		Conflict: conflict,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// prefix parses a CIDR, reporting it when it is invalid or has host bits set.
+// The masked prefix is returned in the latter case.
+func (v *validator) prefix(field, value string) (netip.Prefix, bool) {
+	p, err := netip.ParsePrefix(value)
+	if err != nil {
+		v.report(SeverityError, CodeInvalid, field, value, "", "invalid CIDR %q: %v", value, err)
+		return netip.Prefix{}, false
+	}
+	if masked := p.Masked(); masked != p {
+		v.report(SeverityWarning, CodeNotMasked, field, value, "", "%s has host bits set, it is used as %s", p, masked)
+		p = masked
+	}
+	return p, true
+}
+
+func (v *validator) clusterSize(cluster netip.Prefix) {
+	nodeMask := nodeMaskV4
+	if cluster.Addr().Is6() {
+		nodeMask = nodeMaskV6
+	}
+	if cluster.Bits() > nodeMask {
+		v.report(SeverityError, CodeSize, FieldClusterCIDR, cluster.String(), "",
+			"cluster CIDR %s is too small to allocate a /%d per node", cluster, nodeMask)
+	}
+}
+
+func (v *validator) serviceSize(service netip.Prefix) {
+	if hostBits := service.Addr().BitLen() - service.Bits(); hostBits > maxServiceHostBits {
+		v.report(SeverityError, CodeSize, FieldServiceCIDR, service.String(), "",
+			"service CIDR %s is too large, at most /%d is allowed", service, service.Addr().BitLen()-maxServiceHostBits)
+	}
+}
+
+// host reports the overlaps of p with the networks of the host.
+func (v *validator) host(field string, p netip.Prefix, host HostNetwork) {
+	label := fieldLabels[field]
+	// The connected route of an interface is reported with the interface.
+	reported := map[netip.Prefix]bool{}
+	for _, iface := range host.Interfaces {
+		if p.Overlaps(iface.Masked()) {
+			reported[iface.Masked()] = true
+			v.report(SeverityError, CodeOverlap, field, p.String(), iface.String(),
+				"%s %s overlaps the network %s of host address %s", label, p, iface.Masked(), iface.Addr())
+		}
+	}
+	for _, route := range host.Routes {
+		if route.Bits() == 0 || reported[route.Masked()] {
+			continue
+		}
+		if p.Overlaps(route.Masked()) {
+			v.report(SeverityError, CodeOverlap, field, p.String(), route.String(),
+				"%s %s overlaps the route to %s", label, p, route.Masked())
+		}
+	}
+	for _, ns := range host.Nameservers {
+		if p.Contains(ns.WithZone("")) {
+			v.report(SeverityError, CodeOverlap, field, p.String(), ns.String(),
+				"%s %s contains the nameserver %s, which pods could not reach", label, p, ns)
+		}
+	}
+}
+
+func (v *validator) dns(value string, service netip.Prefix, serviceOK bool) {
+	dns, err := netip.ParseAddr(value)
+	if err != nil {
+		v.report(SeverityError, CodeInvalid, FieldClusterDNS, value, "", "invalid cluster DNS address %q: %v", value, err)
+		return
+	}
+	if !serviceOK {
+		return
+	}
+
+	if !service.Contains(dns) {
+		v.report(SeverityError, CodeDNSOutside, FieldClusterDNS, value, service.String(),
+			"cluster DNS %s is not in the service CIDR %s", dns, service)
+		return
+	}
+
+	// The network address is not assignable and the first address is taken by
+	// the kubernetes API service.
+	first := service.Addr()
+	switch {
+	case dns == first:
+		v.report(SeverityError, CodeDNSReserved, FieldClusterDNS, value, service.String(),
+			"cluster DNS %s is the network address of the service CIDR %s", dns, service)
+	case dns == first.Next():
+		v.report(SeverityError, CodeDNSReserved, FieldClusterDNS, value, service.String(),
+			"cluster DNS %s is reserved for the kubernetes API service", dns)
+	case dns.Is4() && dns == lastAddr(service):
+		v.report(SeverityError, CodeDNSReserved, FieldClusterDNS, value, service.String(),
+			"cluster DNS %s is the broadcast address of the service CIDR %s", dns, service)
+	}
+}
+
+// lastAddr returns the last address of p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// ParsePortRange parses a node port range such as 30000-32767.
+func ParsePortRange(s string) (PortRange, error) {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		return PortRange{}, fmt.Errorf("invalid port range %q, expected first-last", s)
+	}
+	a, err := parsePort(first)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	b, err := parsePort(last)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	if a > b {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %d is greater than %d", s, a, b)
+	}
+	if a < minNodePort {
+		return PortRange{}, fmt.Errorf("port range %q includes well known ports below %d", s, minNodePort)
+	}
+	return PortRange{First: a, Last: b}, nil
+}
+
+func parsePort(s string) (uint16, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("%q is not a port", s)
+	}
+	return uint16(n), nil
+}
+
+func (r PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// Contains reports whether port is in the range.
+func (r PortRange) Contains(port uint16) bool {
+	return port >= r.First && port <= r.Last
+}
@@ -0,0 +1,158 @@
+package clusternet
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Plan{
+		ClusterCIDR:   "10.42.0.0/16",
+		ServiceCIDR:   "10.43.0.0/16",
+		ClusterDNS:    "10.43.0.10",
+		NodePortRange: "30000-32767",
+	}
+
+	tt := []struct {
+		name string
+		plan func(*Plan)
+		host HostNetwork
+		want []Diagnostic
+	}{
+		{
+			name: "valid",
+			host: HostNetwork{
+				Interfaces:  []netip.Prefix{netip.MustParsePrefix("10.4.0.5/24")},
+				Routes:      []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("10.4.0.0/24")},
+				Nameservers: []netip.Addr{netip.MustParseAddr("10.4.0.2")},
+			},
+		},
+		{
+			name: "invalid values",
+			plan: func(p *Plan) {
+				p.ClusterCIDR, p.ServiceCIDR, p.ClusterDNS, p.NodePortRange = "10.42", "10.43.0.0/16", "dns", "30000"
+			},
+			want: []Diagnostic{
+				{Severity: SeverityError, Code: CodeInvalid, Field: FieldClusterCIDR, Value: "10.42", Message: `invalid CIDR "10.42": netip.ParsePrefix("10.42"): no '/'`},
+				{Severity: SeverityError, Code: CodeInvalid, Field: FieldClusterDNS, Value: "dns", Message: `invalid cluster DNS address "dns": ParseAddr("dns"): unable to parse IP`},
+				{Severity: SeverityError, Code: CodePortRange, Field: FieldNodePortRange, Value: "30000", Message: `invalid port range "30000", expected first-last`},
+			},
+		},
+		{
+			name: "overlapping cidrs",
+			plan: func(p *Plan) { p.ClusterCIDR, p.ServiceCIDR = "10.0.0.0/8", "10.43.0.1/16" },
+			want: []Diagnostic{
+				{Severity: SeverityWarning, Code: CodeNotMasked, Field: FieldServiceCIDR, Value: "10.43.0.1/16", Message: "10.43.0.1/16 has host bits set, it is used as 10.43.0.0/16"},
+				{Severity: SeverityError, Code: CodeOverlap, Field: FieldServiceCIDR, Value: "10.43.0.0/16", Conflict: "10.0.0.0/8", Message: "service CIDR 10.43.0.0/16 overlaps cluster CIDR 10.0.0.0/8"},
+			},
+		},
+		{
+			name: "host conflicts",
+			host: HostNetwork{
+				Interfaces:  []netip.Prefix{netip.MustParsePrefix("10.42.7.1/24")},
+				Routes:      []netip.Prefix{netip.MustParsePrefix("10.42.7.0/24"), netip.MustParsePrefix("10.40.0.0/14")},
+				Nameservers: []netip.Addr{netip.MustParseAddr("10.43.0.53")},
+			},
+			want: []Diagnostic{
+				{Severity: SeverityError, Code: CodeOverlap, Field: FieldClusterCIDR, Value: "10.42.0.0/16", Conflict: "10.42.7.1/24", Message: "cluster CIDR 10.42.0.0/16 overlaps the network 10.42.7.0/24 of host address 10.42.7.1"},
+				{Severity: SeverityError, Code: CodeOverlap, Field: FieldClusterCIDR, Value: "10.42.0.0/16", Conflict: "10.40.0.0/14", Message: "cluster CIDR 10.42.0.0/16 overlaps the route to 10.40.0.0/14"},
+				{Severity: SeverityError, Code: CodeOverlap, Field: FieldServiceCIDR, Value: "10.43.0.0/16", Conflict: "10.40.0.0/14", Message: "service CIDR 10.43.0.0/16 overlaps the route to 10.40.0.0/14"},
+				{Severity: SeverityError, Code: CodeOverlap, Field: FieldServiceCIDR, Value: "10.43.0.0/16", Conflict: "10.43.0.53", Message: "service CIDR 10.43.0.0/16 contains the nameserver 10.43.0.53, which pods could not reach"},
+			},
+		},
+		{
+			name: "sizes",
+			plan: func(p *Plan) {
+				p.ClusterCIDR, p.ServiceCIDR, p.ClusterDNS = "10.42.0.0/25", "172.0.0.0/11", "172.0.0.10"
+			},
+			want: []Diagnostic{
+				{Severity: SeverityError, Code: CodeSize, Field: FieldClusterCIDR, Value: "10.42.0.0/25", Message: "cluster CIDR 10.42.0.0/25 is too small to allocate a /24 per node"},
+				{Severity: SeverityError, Code: CodeSize, Field: FieldServiceCIDR, Value: "172.0.0.0/11", Message: "service CIDR 172.0.0.0/11 is too large, at most /12 is allowed"},
+			},
+		},
+		{
+			name: "single node cluster",
+			plan: func(p *Plan) { p.ClusterCIDR = "10.42.0.0/24" },
+		},
+		{
+			name: "single node IPv6 cluster",
+			plan: func(p *Plan) {
+				p.ClusterCIDR, p.ServiceCIDR, p.ClusterDNS = "fd00:42::/64", "fd00:43::/112", "fd00:43::a"
+			},
+		},
+		{
+			name: "mixed families",
+			plan: func(p *Plan) { p.ServiceCIDR, p.ClusterDNS = "fd00:43::/112", "fd00:43::a" },
+			want: []Diagnostic{
+				{Severity: SeverityError, Code: CodeFamily, Field: FieldServiceCIDR, Value: "fd00:43::/112", Conflict: "10.42.0.0/16", Message: "service CIDR fd00:43::/112 and cluster CIDR 10.42.0.0/16 are of different address families"},
+			},
+		},
+		{
+			// install-core.sh only compares the first two octets.
+			name: "dns outside service cidr",
+			plan: func(p *Plan) { p.ServiceCIDR = "10.43.128.0/17" },
+			want: []Diagnostic{
+				{Severity: SeverityError, Code: CodeDNSOutside, Field: FieldClusterDNS, Value: "10.43.0.10", Conflict: "10.43.128.0/17", Message: "cluster DNS 10.43.0.10 is not in the service CIDR 10.43.128.0/17"},
+			},
+		},
+		{
+			name: "dns reserved for the api",
+			plan: func(p *Plan) { p.ClusterDNS = "10.43.0.1" },
+			want: []Diagnostic{
+				{Severity: SeverityError, Code: CodeDNSReserved, Field: FieldClusterDNS, Value: "10.43.0.1", Conflict: "10.43.0.0/16", Message: "cluster DNS 10.43.0.1 is reserved for the kubernetes API service"},
+			},
+		},
+		{
+			name: "dns broadcast",
+			plan: func(p *Plan) { p.ClusterDNS = "10.43.255.255" },
+			want: []Diagnostic{
+				{Severity: SeverityError, Code: CodeDNSReserved, Field: FieldClusterDNS, Value: "10.43.255.255", Conflict: "10.43.0.0/16", Message: "cluster DNS 10.43.255.255 is the broadcast address of the service CIDR 10.43.0.0/16"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := valid
+			if tc.plan != nil {
+				tc.plan(&plan)
+			}
+			got := Validate(plan, tc.host)
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %+v != got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	tt := []struct {
+		in      string
+		want    PortRange
+		wantErr bool
+	}{
+		{in: "30000-32767", want: PortRange{First: 30000, Last: 32767}},
+		{in: "30000-30000", want: PortRange{First: 30000, Last: 30000}},
+		{in: "32767-30000", wantErr: true},
+		{in: "80-8080", wantErr: true},
+		{in: "30000-70000", wantErr: true},
+		{in: "0-100", wantErr: true},
+		{in: "a-b", wantErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParsePortRange(tc.in)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.want != got {
+				t.Errorf("want: %v != got: %v", tc.want, got)
+			}
+			if err == nil && (!got.Contains(got.First) || got.Contains(got.Last+1)) {
+				t.Errorf("%v does not contain its bounds", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/bundle"
+	"github.com/calyptia/core-images-index/go-index/install"
+)
+
+func runBundle(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	location := flags.String("source", index.DefaultBaseURL, "base URL or local directory holding the index files")
+	out := flags.String("out", "core-bundle.zip", "zip archive, or directory, to write the bundle to")
+	systems := flags.String("os", "debian,rhel,alpine", "comma separated OS families of the packages")
+	arches := flags.String("arch", "amd64,arm64", "comma separated architectures of the packages")
+	var opts bundle.Options
+	flags.StringVar(&opts.Version, "version", "", "operator version or version constraint, the latest stable release when empty")
+	flags.StringVar(&opts.CoreFluentBitVersion, "core-fluent-bit-version", "", "schema version to bundle when the mappings have no tagged image")
+	flags.StringVar((*string)(&opts.Flavor), "flavor", string(install.FlavorOperator), "package name prefix: calyptia-core-operator or calyptia-core")
+	flags.StringVar(&opts.PackageBaseURL, "base-url", install.DefaultBaseURL, "base URL of the packages, without the version")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	for _, s := range splitList(*systems) {
+		opts.OS = append(opts.OS, install.OSFamily(s))
+	}
+	opts.Arch = splitList(*arches)
+
+	src := index.OpenSource(*location)
+	if strings.Contains(*location, "://") {
+		src = src.WithRetry(index.RetryPolicy{})
+	}
+	b, err := bundle.Build(ctx, src.Files, opts)
+	if err != nil {
+		return fmt.Errorf("cannot build bundle: %w", err)
+	}
+
+	if strings.HasSuffix(*out, ".zip") {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		if err := b.WriteZip(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	} else if err := b.WriteDir(*out); err != nil {
+		return err
+	}
+
+	log.Printf("bundled operator %s with Core Fluent Bit %s schemas and %d packages in %s",
+		b.Manifest.Operator, b.Manifest.CoreFluentBitVersion, len(b.Manifest.Packages), *out)
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
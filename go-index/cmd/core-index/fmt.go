@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+// formatters lists the documents rewritten by fmt.
+var formatters = []struct {
+	name   string
+	format func([]byte) ([]byte, error)
+}{
+	{name: index.ContainerIndexFile, format: index.FormatIndex},
+	{name: index.OperatorIndexFile, format: index.FormatIndex},
+	{name: index.OperatorMappingsFile, format: index.FormatMappings},
+}
+
+func runFmt(_ context.Context, args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "root of the index repository")
+	check := flags.Bool("check", false, "list the files that are not formatted instead of rewriting them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var unformatted int
+	for _, f := range formatters {
+		name := filepath.Join(*dir, filepath.FromSlash(f.name))
+		data, err := os.ReadFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		formatted, err := f.format(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if bytes.Equal(data, formatted) {
+			continue
+		}
+
+		unformatted++
+		fmt.Println(name)
+		if *check {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if err := index.WriteFileAtomic(name, formatted, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	if *check && unformatted > 0 {
+		return fmt.Errorf("%d files are not formatted, run core-index fmt", unformatted)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/calyptia/core-images-index/go-index/generate"
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+func runGenerate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "root of the index repository")
+	registry := flags.String("registry", oci.DefaultRegistry, "container registry to list tags from")
+	githubAPI := flags.String("github-api", generate.DefaultGitHubAPI, "GitHub API to read operator releases from")
+	targets := flags.String("targets", "container,operator,mappings", "comma separated documents to generate")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Same token handling as the scripts: ghcr.io accepts the base64 encoded
+	// GitHub token as a bearer token.
+	token := os.Getenv("GITHUB_TOKEN")
+	client := &oci.Client{BaseURL: *registry}
+	if token != "" {
+		client.Token = base64.StdEncoding.EncodeToString([]byte(token))
+	}
+
+	g := &generate.Generator{
+		Registry: client,
+		Releases: &generate.GitHub{BaseURL: *githubAPI, Token: token},
+		Dir:      *dir,
+		Logf:     log.Printf,
+	}
+
+	var only []generate.Target
+	for _, target := range strings.Split(*targets, ",") {
+		only = append(only, generate.Target(strings.TrimSpace(target)))
+	}
+	return g.Generate(ctx, only...)
+}
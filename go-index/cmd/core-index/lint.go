@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+func runLint(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "root of the index repository")
+	format := flags.String("format", "json", "output format, json or text")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := index.Lint(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	case "text":
+		for _, f := range report.Findings {
+			fmt.Printf("%s: %s: [%s] %s\n", f.Path, f.Severity, f.Check, f.Message)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("%d errors, %d warnings", report.Errors, report.Warnings)
+	}
+	return nil
+}
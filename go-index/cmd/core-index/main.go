@@ -0,0 +1,67 @@
+// Command core-index provides tooling around the container, operator and
+// schema indexes of Calyptia Core.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "serve", usage: "serve the indexes and schemas over HTTP", run: runServe},
+	{name: "generate", usage: "generate the index files from the registry and releases", run: runGenerate},
+	{name: "schemas", usage: "extract the schemas of new Core Fluent Bit images", run: runSchemas},
+	{name: "lint", usage: "check the consistency of the index and schema tree", run: runLint},
+	{name: "fmt", usage: "rewrite the index files in their canonical form", run: runFmt},
+	{name: "support", usage: "evaluate a support policy against the releases", run: runSupport},
+	{name: "preflight", usage: "check the host is ready to install Calyptia Core", run: runPreflight},
+	{name: "plan", usage: "resolve the package and image to install for a host", run: runPlan},
+	{name: "settings", usage: "validate and diff the installer settings", run: runSettings},
+	{name: "packages", usage: "verify the local packages of an offline installation", run: runPackages},
+	{name: "bundle", usage: "assemble the indexes and schemas of a release for offline use", run: runBundle},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "core-index:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing command")
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(ctx, args[1:])
+		}
+	}
+
+	usage()
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: core-index <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
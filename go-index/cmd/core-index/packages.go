@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/install"
+	"github.com/calyptia/core-images-index/go-index/preflight"
+)
+
+func runPackages(_ context.Context, args []string) error {
+	flags := flag.NewFlagSet("packages", flag.ContinueOnError)
+	dir := flags.String("dir", env("LOCAL_PACKAGE", "."), "directory holding the packages")
+	systems := flags.String("os", "debian,rhel,alpine", "comma separated OS families whose packages are expected")
+	var req install.VerifyRequest
+	flags.StringVar(&req.Arch, "arch", env("ARCH", runtime.GOARCH), "architecture of the packages")
+	flags.StringVar((*string)(&req.Flavor), "flavor", env("PACKAGE_NAME_PREFIX", string(install.FlavorOperator)), "package name prefix: calyptia-core-operator or calyptia-core")
+	flags.StringVar(&req.Version, "version", env("RELEASE_VERSION", preflight.DefaultReleaseVersion), "version of the packages")
+	flags.StringVar(&req.RPMRelease, "rpm-release", install.DefaultRPMRelease, "suffix of the version in RPM file names")
+	flags.StringVar(&req.Checksums, "checksums", index.ChecksumsFile, "checksum manifest within the directory")
+	flags.BoolVar(&req.RequireChecksums, "require-checksums", false, "fail when the checksum manifest is missing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	for _, s := range splitList(*systems) {
+		req.OS = append(req.OS, install.OSFamily(s))
+	}
+
+	report, err := install.VerifyPackages(os.DirFS(*dir), req)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if err := report.Err(); err != nil {
+		return fmt.Errorf("invalid packages in %s:\n%w", *dir, err)
+	}
+	return nil
+}
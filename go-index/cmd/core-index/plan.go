@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/install"
+)
+
+func runPlan(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("plan", flag.ContinueOnError)
+	location := flags.String("source", index.DefaultBaseURL, "comma separated base URLs or local directories holding the index files, tried in order")
+	var req install.Request
+	flags.StringVar((*string)(&req.OS), "os", string(install.OSDebian), "OS family: debian, rhel or alpine")
+	flags.StringVar(&req.Arch, "arch", runtime.GOARCH, "architecture to install")
+	flags.StringVar((*string)(&req.Flavor), "flavor", string(install.FlavorOperator), "package name prefix: calyptia-core-operator or calyptia-core")
+	flags.StringVar(&req.Version, "version", "", "version or version constraint, the latest stable release when empty")
+	flags.StringVar(&req.BaseURL, "base-url", install.DefaultBaseURL, "base URL of the packages, without the version")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	planner := &install.Planner{Operator: &index.Operator{Fetcher: openSource(*location).operator}}
+	plan, err := planner.Plan(ctx, req)
+	if err != nil {
+		return fmt.Errorf("cannot plan installation: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
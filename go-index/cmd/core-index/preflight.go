@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/calyptia/core-images-index/go-index/preflight"
+)
+
+func runPreflight(ctx context.Context, args []string) error {
+	cfg := preflight.DefaultConfig()
+	if u, err := user.Current(); err == nil {
+		cfg.User = u.Username
+		if g, err := user.LookupGroupId(u.Gid); err == nil {
+			cfg.Group = g.Name
+		}
+	}
+
+	flags := flag.NewFlagSet("preflight", flag.ContinueOnError)
+	// Defaults follow the INSTALL_CALYPTIA_ variables of install-core.sh.
+	flags.StringVar(&cfg.User, "user", env("PROVISIONED_USER", cfg.User), "user to install Calyptia Core as")
+	flags.StringVar(&cfg.Group, "group", env("PROVISIONED_GROUP", cfg.Group), "group to install Calyptia Core as")
+	flags.StringVar(&cfg.ReleaseVersion, "core-version", env("RELEASE_VERSION", cfg.ReleaseVersion), "Calyptia Core version to install")
+	flags.StringVar(&cfg.ClusterCIDR, "cluster-cidr", env("CLUSTER_CIDR", cfg.ClusterCIDR), "pod CIDR of the cluster")
+	flags.StringVar(&cfg.ServiceCIDR, "service-cidr", env("SERVICE_CIDR", cfg.ServiceCIDR), "service CIDR of the cluster")
+	flags.StringVar(&cfg.ClusterDNS, "cluster-dns", env("CLUSTER_DNS", cfg.ClusterDNS), "cluster DNS address, within the service CIDR")
+	flags.StringVar(&cfg.NodePortRange, "service-node-port-range", env("SERVICE_NODE_PORT_RANGE", cfg.NodePortRange), "port range of the node port services")
+	flags.StringVar(&cfg.LocalPackage, "package", env("LOCAL_PACKAGE", cfg.LocalPackage), "local package file or directory to install")
+	flags.StringVar(&cfg.PackageNamePrefix, "package-name-prefix", env("PACKAGE_NAME_PREFIX", cfg.PackageNamePrefix), "name prefix of the package")
+	flags.StringVar(&cfg.Arch, "arch", env("ARCH", cfg.Arch), "architecture to install")
+	force := flags.Bool("force", env("IGNORE_ERRORS", "no") != "no", "treat ignorable errors as warnings")
+	format := flags.String("format", "json", "output format, json or text")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.LocalPackage != "" {
+		abs, err := filepath.Abs(cfg.LocalPackage)
+		if err != nil {
+			return err
+		}
+		cfg.LocalPackage = abs
+	}
+
+	report := preflight.Run(ctx, preflight.LocalHost(), cfg)
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	case "text":
+		for _, r := range report.Results {
+			status := string(r.Status)
+			if r.Severity != "" {
+				status += " (" + string(r.Severity) + ")"
+			}
+			fmt.Printf("%s: %s: %s\n", r.Check, status, r.Message)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	if !report.OK(*force) {
+		return fmt.Errorf("%d fatal, %d ignorable errors", report.Fatal, report.Ignorable)
+	}
+	return nil
+}
+
+// env returns the INSTALL_CALYPTIA_ variable name, or def when unset.
+func env(name, def string) string {
+	if v, ok := os.LookupEnv("INSTALL_CALYPTIA_" + name); ok {
+		return v
+	}
+	return def
+}
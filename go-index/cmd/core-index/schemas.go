@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/generate"
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+func runSchemas(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("schemas", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "root of the index repository")
+	registry := flags.String("registry", oci.DefaultRegistry, "container registry to read images from")
+	repository := flags.String("repository", index.CoreFluentBitRepository, "Core Fluent Bit image repository")
+	platform := flags.String("platform", generate.DefaultPlatform, "image platform to read")
+	tags := flags.String("tags", "", "comma separated tags to extract, defaults to every release tag")
+	force := flags.Bool("force", false, "extract versions whose schema is already committed")
+	runtime := flags.String("runtime", "", "container runtime, such as docker, running the image with -J to generate core-fluent-bit.json, "+
+		"which is not a file of the image; without it only the lua and plugins schemas are written")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	client := &oci.Client{BaseURL: *registry}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		client.Token = base64.StdEncoding.EncodeToString([]byte(token))
+	}
+
+	e := &generate.SchemaExtractor{
+		Images:     client,
+		Dir:        *dir,
+		Repository: *repository,
+		Platform:   *platform,
+		Force:      *force,
+		Logf:       log.Printf,
+	}
+	if *runtime != "" {
+		e.CoreSchema = func(ctx context.Context, image string) ([]byte, error) {
+			return runSchemaDump(ctx, *runtime, *platform, client.Host()+"/"+image)
+		}
+	}
+
+	var only []string
+	if *tags != "" {
+		for _, tag := range strings.Split(*tags, ",") {
+			only = append(only, strings.TrimSpace(tag))
+		}
+	} else {
+		fetcher := &index.RegistryFetcher{Client: client, Repository: *repository, Filter: index.CoreFluentBitTagFilter}
+		all, err := fetcher.Tags(ctx)
+		if err != nil {
+			return err
+		}
+		only = all
+	}
+
+	_, err := e.ExtractAll(ctx, only)
+	return err
+}
+
+// runSchemaDump runs image with -J, which prints the Core Fluent Bit schema.
+func runSchemaDump(ctx context.Context, runtime, platform, image string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, runtime, "run", "--rm", "--platform", platform, image, "-J") //nolint:gosec // the runtime is chosen by the operator of the command.
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s run %s: %w: %s", runtime, image, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
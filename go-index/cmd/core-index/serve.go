@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/server"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+func runServe(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	location := flags.String("source", index.DefaultBaseURL, "comma separated base URLs or local directories holding the index files, tried in order")
+	maxAge := flags.Duration("max-age", 5*time.Minute, "max-age advertised in Cache-Control, 0 disables caching")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	src := openSource(*location)
+	srv := &http.Server{
+		Addr: *addr,
+		Handler: server.New(server.Config{
+			Container: &index.Container{Fetcher: src.container},
+			Operator:  &index.Operator{Fetcher: src.operator},
+			Schemas:   src.schemas,
+			MaxAge:    *maxAge,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("serving %s on %s", *location, *addr)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("could not serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("could not shutdown: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/calyptia/core-images-index/go-index/install"
+	"github.com/calyptia/core-images-index/go-index/preflight"
+)
+
+func runSettings(_ context.Context, args []string) error {
+	flags := flag.NewFlagSet("settings", flag.ContinueOnError)
+	file := flags.String("file", install.SettingsPath(preflight.DefaultCoreDir), "settings file of the installation")
+	diff := flags.Bool("diff", false, "print the changes from the settings file instead of the settings")
+	write := flags.Bool("write", false, "write the settings file, owned by the provisioned user and group")
+	force := flags.Bool("force", false, "overwrite an existing settings file with -write")
+	checkAccounts := flags.Bool("check-accounts", true, "check that the user and group exist")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Defaults, then the INSTALL_CALYPTIA_ variables, like install-core.sh.
+	settings := install.DefaultSettings()
+	if u, err := user.Current(); err == nil {
+		settings.ProvisionedUser = u.Username
+		if g, err := user.LookupGroupId(u.Gid); err == nil {
+			settings.ProvisionedGroup = g.Name
+		}
+	}
+	settings.LoadEnv(os.LookupEnv)
+
+	var lookup install.AccountLookup
+	if *checkAccounts {
+		lookup = install.OSAccountLookup{}
+	}
+	if err := settings.Validate(lookup); err != nil {
+		return fmt.Errorf("invalid settings:\n%w", err)
+	}
+
+	switch {
+	case *diff:
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		installed, err := install.ParseSettings(f)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", *file, err)
+		}
+		changes := install.DiffSettings(installed, settings)
+		if changes == nil {
+			changes = []install.SettingChange{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	case *write:
+		return install.WriteSettings(*file, settings, *force)
+	default:
+		data, err := install.MarshalSettings(settings)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+}
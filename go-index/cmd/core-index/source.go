@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+type source struct {
+	container index.ContainerIndexFetch
+	operator  index.OperatorIndexFetch
+	schemas   index.SchemaFetch
+}
+
+// openSource returns fetchers for a comma separated list of base URLs or local
+// checkouts of the index repository, tried in order. Remote fetches are
+// retried on transient failures.
+func openSource(locations string) source {
+	multi := &index.MultiSource{
+		OnServed: func(report index.SourceReport) {
+			log.Printf("%s index served by %s", report.Index, report.Source)
+		},
+	}
+	for _, location := range strings.Split(locations, ",") {
+		src := index.OpenSource(strings.TrimSpace(location))
+		if strings.Contains(location, "://") {
+			src = src.WithRetry(index.RetryPolicy{})
+		}
+		multi.Sources = append(multi.Sources, src)
+	}
+
+	if len(multi.Sources) == 1 {
+		return source{
+			container: multi.Sources[0].Container,
+			operator:  multi.Sources[0].Operator,
+			schemas:   multi.Sources[0].Schemas,
+		}
+	}
+	return source{
+		container: multi.ContainerFetcher(),
+		operator:  multi.OperatorFetcher(),
+		schemas:   multi.SchemaFetcher(),
+	}
+}
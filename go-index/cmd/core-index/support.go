@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+func runSupport(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("support", flag.ContinueOnError)
+	location := flags.String("source", index.DefaultBaseURL, "comma separated base URLs or local directories holding the index files, tried in order")
+	kind := flags.String("index", string(index.IndexOperator), "index to evaluate, operator or container")
+	var policy index.SupportPolicy
+	flags.IntVar(&policy.Minors, "minors", 0, "number of supported minor versions")
+	flags.IntVar(&policy.DeprecationMinors, "deprecation-minors", 0, "number of deprecated minor versions after the supported ones")
+	flags.IntVar(&policy.Months, "months", 0, "months of support after the release date")
+	flags.IntVar(&policy.DeprecationMonths, "deprecation-months", 0, "months of deprecation after the end of support")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: core-index support [flags] [version...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	src := openSource(*location)
+	var (
+		releases index.Releases
+		err      error
+	)
+	switch index.IndexKind(*kind) {
+	case index.IndexOperator:
+		releases, err = (&index.Operator{Fetcher: src.operator}).Releases(ctx)
+	case index.IndexContainer:
+		releases, err = (&index.Container{Fetcher: src.container}).Releases(ctx)
+	default:
+		return fmt.Errorf("unknown index %q", *kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var out []index.SupportInfo
+	if flags.NArg() == 0 {
+		if out, err = policy.Evaluate(releases, now); err != nil {
+			return err
+		}
+	}
+	for _, version := range flags.Args() {
+		info, err := policy.Support(releases, version, now)
+		if err != nil {
+			return fmt.Errorf("%s: %w", version, err)
+		}
+		out = append(out, info)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -2,16 +2,18 @@ package index
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
+	"io/fs"
 	"net/http"
 	"sort"
+	"time"
 
 	semver "github.com/hashicorp/go-version"
 )
 
 const (
+	ContainerIndexFile = "container.index.json"
+
 	containerIndexURL = "https://raw.githubusercontent.com/calyptia/core-images-index/main/container.index.json"
 )
 
@@ -27,6 +29,9 @@ type (
 	ContainerIndex interface {
 		All(ctx context.Context) ([]string, error)
 		Last(ctx context.Context) (string, error)
+		// Latest returns the highest release followed by channel, such as
+		// ChannelStable, see Channels.
+		Latest(ctx context.Context, channel string) (string, error)
 		Match(ctx context.Context, version string) (string, error)
 	}
 
@@ -37,34 +42,63 @@ type (
 
 	ContainerIndexFetcher struct {
 		ContainerIndexFetch
+		// URL of the index, defaults to the one published on GitHub.
+		URL string
+		// Client used for the request, defaults to http.DefaultClient.
+		Client *http.Client
+		// Verifier checks the integrity of the index when set.
+		Verifier Verifier
+	}
+
+	// ContainerIndexFSFetcher reads the index from a filesystem such as a
+	// checkout of this repository or an embed.FS.
+	ContainerIndexFSFetcher struct {
+		FS fs.FS
+		// Name of the index file within FS, defaults to container.index.json.
+		Name string
+		// Verifier checks the integrity of the index when set.
+		Verifier Verifier
 	}
 )
 
 func (c *ContainerIndexFetcher) GetImages(ctx context.Context) (ContainerImages, error) {
 	var out ContainerImages
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, containerIndexURL, nil)
-	if err != nil {
-		return out, fmt.Errorf("cannot create a request to index %s: %w", containerIndexURL, err)
-	}
+	err := fetchJSON(ctx, c.Client, c.url(), c.Verifier, &out)
+	return out, err
+}
 
-	client := http.DefaultClient
-	res, err := client.Do(request)
-	if err != nil {
-		return out, fmt.Errorf("could not fetch index %s: %w", containerIndexURL, err)
+// GetReleases reads the index with the metadata of the v2 format.
+func (c *ContainerIndexFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	var out Releases
+	err := fetchJSON(ctx, c.Client, c.url(), c.Verifier, &out)
+	return out, err
+}
+
+func (c *ContainerIndexFetcher) url() string {
+	if c.URL == "" {
+		return containerIndexURL
 	}
+	return c.URL
+}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			return
-		}
-	}(res.Body)
+func (c *ContainerIndexFSFetcher) GetImages(ctx context.Context) (ContainerImages, error) {
+	var out ContainerImages
+	err := readJSON(ctx, c.FS, c.name(), c.Verifier, &out)
+	return out, err
+}
 
-	err = json.NewDecoder(res.Body).Decode(&out)
-	if err != nil {
-		return out, fmt.Errorf("could not decode index response: %w", err)
+// GetReleases reads the index with the metadata of the v2 format.
+func (c *ContainerIndexFSFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	var out Releases
+	err := readJSON(ctx, c.FS, c.name(), c.Verifier, &out)
+	return out, err
+}
+
+func (c *ContainerIndexFSFetcher) name() string {
+	if c.Name == "" {
+		return ContainerIndexFile
 	}
-	return out, nil
+	return c.Name
 }
 
 func (c *Container) All(ctx context.Context) ([]string, error) {
@@ -94,6 +128,8 @@ func (c *Container) All(ctx context.Context) ([]string, error) {
 	return out, nil
 }
 
+// Match returns the tag of the index equal to version. Yanked releases are
+// reported with an *ErrVersionYanked.
 func (c *Container) Match(ctx context.Context, version string) (string, error) {
 	orig, err := semver.NewVersion(version)
 	if err != nil {
@@ -111,6 +147,13 @@ func (c *Container) Match(ctx context.Context, version string) (string, error) {
 			return "", err
 		}
 		if release.Equal(orig) {
+			denylist, err := fetchDenylist(ctx, c.Fetcher)
+			if err != nil {
+				return "", err
+			}
+			if err := denylist.check(imageTag); err != nil {
+				return "", err
+			}
 			return imageTag, nil
 		}
 	}
@@ -118,14 +161,70 @@ func (c *Container) Match(ctx context.Context, version string) (string, error) {
 	return "", ErrNoMatchingImage
 }
 
+// Last returns the highest version of the index that is not yanked.
 func (c *Container) Last(ctx context.Context) (string, error) {
 	versions, err := c.All(ctx)
 	if err != nil {
 		return "", err
 	}
+	denylist, err := fetchDenylist(ctx, c.Fetcher)
+	if err != nil {
+		return "", err
+	}
+	versions = denylist.Filter(versions)
+	if len(versions) == 0 {
+		return "", ErrNoMatchingImage
+	}
 	return versions[len(versions)-1], nil
 }
 
+// Latest returns the highest release followed by channel, as defined by
+// the channels published next to the index merged over DefaultChannels.
+// Yanked releases are skipped.
+func (c *Container) Latest(ctx context.Context, channel string) (string, error) {
+	channels, err := fetchChannels(ctx, c.Fetcher)
+	if err != nil {
+		return "", err
+	}
+	releases, err := c.Releases(ctx)
+	if err != nil {
+		return "", err
+	}
+	denylist, err := fetchDenylist(ctx, c.Fetcher)
+	if err != nil {
+		return "", err
+	}
+	return channels.Latest(channel, denylist.withoutYanked(releases))
+}
+
+// Releases returns the entries of the index with their metadata. Only the
+// tags and channels are known when the index uses the legacy format.
+func (c *Container) Releases(ctx context.Context) (Releases, error) {
+	out, err := fetchReleases[ContainerImages](ctx, c.Fetcher)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get container index: %w", err)
+	}
+	return out, nil
+}
+
+// Release returns the metadata of the release matching version.
+func (c *Container) Release(ctx context.Context, version string) (Release, error) {
+	releases, err := c.Releases(ctx)
+	if err != nil {
+		return Release{}, err
+	}
+	return releases.Match(version)
+}
+
+// Support evaluates policy at now against the releases of the index.
+func (c *Container) Support(ctx context.Context, policy SupportPolicy, now time.Time) ([]SupportInfo, error) {
+	releases, err := c.Releases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Evaluate(releases, now)
+}
+
 func NewContainer() (*Container, error) {
 	return &Container{
 		Fetcher: &ContainerIndexFetcher{},
@@ -2,6 +2,32 @@ package index
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 )
 
-var ErrNoMatchingImage = fmt.Errorf("no matching image found")
+var (
+	ErrNoMatchingImage = fmt.Errorf("no matching image found")
+	ErrNotFound        = fmt.Errorf("index document not found")
+	// ErrUntaggedImage is reported along ErrNoMatchingImage for images
+	// referenced without a tag, such as the operator mappings of releases
+	// whose default image is not pinned.
+	ErrUntaggedImage = fmt.Errorf("image without a tag")
+)
+
+// StatusError is returned when an index is served with a status other than 200.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is the delay requested by the server, if any.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("could not fetch index %s: unexpected status %d", e.URL, e.StatusCode)
+}
+
+// Is makes a 404 match ErrNotFound.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,151 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fetchJSON retrieves url, verifies it when verifier is set, and decodes its
+// JSON body into out.
+func fetchJSON(ctx context.Context, client *http.Client, url string, verifier Verifier, out any) error {
+	b, err := fetchVerified(ctx, client, url, verifier)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(b, url, out)
+}
+
+// fetchVerified fetches url and checks it with verifier when set.
+func fetchVerified(ctx context.Context, client *http.Client, url string, verifier Verifier) ([]byte, error) {
+	b, err := fetchBytes(ctx, client, url)
+	if err != nil || verifier == nil {
+		return b, err
+	}
+
+	err = verifier.Verify(ctx, Document{
+		Name: path.Base(url),
+		Data: b,
+		ReadSibling: func(ctx context.Context, name string) ([]byte, error) {
+			return fetchBytes(ctx, client, url[:strings.LastIndex(url, "/")+1]+name)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func fetchBytes(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a request to index %s: %w", url, err)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch index %s: %w", url, err)
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			return
+		}
+	}(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{
+			URL:        url,
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		// Reported like transport errors so that a dropped connection is
+		// told apart from a malformed document.
+		return nil, fmt.Errorf("could not read index %s: %w", url, &neturl.Error{Op: request.Method, URL: url, Err: err})
+	}
+	return b, nil
+}
+
+// decodeJSON decodes through a json.Decoder so that truncated documents are
+// reported with io.ErrUnexpectedEOF.
+func decodeJSON(b []byte, name string, out any) error {
+	err := json.NewDecoder(bytes.NewReader(b)).Decode(out)
+	if err != nil {
+		return fmt.Errorf("could not decode index %s: %w", name, err)
+	}
+	return nil
+}
+
+// parseRetryAfter accepts both the delay-seconds and HTTP-date forms of the
+// Retry-After header.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// readJSON reads name from fsys, verifies it when verifier is set, and
+// decodes it into out.
+func readJSON(ctx context.Context, fsys fs.FS, name string, verifier Verifier, out any) error {
+	b, err := readVerified(ctx, fsys, name, verifier)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(b, name, out)
+}
+
+// readVerified reads name from fsys and checks it with verifier when set.
+func readVerified(ctx context.Context, fsys fs.FS, name string, verifier Verifier) ([]byte, error) {
+	b, err := readFile(fsys, name)
+	if err != nil || verifier == nil {
+		return b, err
+	}
+
+	err = verifier.Verify(ctx, Document{
+		Name: path.Base(name),
+		Data: b,
+		ReadSibling: func(_ context.Context, sibling string) ([]byte, error) {
+			return readFile(fsys, path.Join(path.Dir(name), sibling))
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func readFile(fsys fs.FS, name string) ([]byte, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not read index %s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read index %s: %w", name, err)
+	}
+	return b, nil
+}
@@ -6,6 +6,7 @@ type FilterOpts struct {
 	Region string
 	// i.e 0.2.9
 	Version string
-	// Determine if use the test index, default false.
+	// Determine if use the test index, default false. The test index is the
+	// TestName of the AMI fetchers.
 	TestIndex bool
 }
@@ -0,0 +1,222 @@
+// Package generate builds the index files of this repository from the
+// container registry and the GitHub releases of the operator, replacing
+// create-container-index.sh and create-operator-mappings.sh.
+package generate
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+const (
+	OperatorReleasesRepo = "chronosphereio/calyptia-core-operator-releases"
+	BackendRepo          = "chronosphereio/calyptia-backend"
+
+	manifestAsset = "manifest.yaml"
+	filePerm      = 0o644
+)
+
+const (
+	TargetContainer Target = "container"
+	TargetOperator  Target = "operator"
+	TargetMappings  Target = "mappings"
+)
+
+type (
+	Target string
+
+	// Registry lists the tags of a repository, it is implemented by *oci.Client.
+	Registry interface {
+		Tags(ctx context.Context, repository string) ([]string, error)
+	}
+
+	// Releases reads GitHub releases, it is implemented by *GitHub.
+	Releases interface {
+		Releases(ctx context.Context, repo string) ([]Release, error)
+		Release(ctx context.Context, repo, tag string) (Release, error)
+		LatestRelease(ctx context.Context, repo string) (Release, error)
+		Download(ctx context.Context, url string) ([]byte, error)
+	}
+
+	// Generator builds the index files. Every document is built before any is
+	// written, and each is written atomically, so a failure leaves the
+	// committed files untouched.
+	Generator struct {
+		Registry Registry
+		Releases Releases
+		// Dir is the root of the index repository.
+		Dir string
+		// The repositories default to the constants of the index and this package.
+		ContainerRepository  string
+		OperatorRepository   string
+		OperatorReleasesRepo string
+		BackendRepo          string
+		// Logf reports progress when set.
+		Logf func(format string, args ...any)
+	}
+
+	document struct {
+		name string
+		data []byte
+	}
+)
+
+// AllTargets lists every document the generator can build.
+var AllTargets = []Target{TargetContainer, TargetOperator, TargetMappings}
+
+// Generate builds and writes the requested documents, all of them by default.
+func (g *Generator) Generate(ctx context.Context, targets ...Target) error {
+	if len(targets) == 0 {
+		targets = AllTargets
+	}
+
+	var docs []document
+	for _, target := range targets {
+		doc, err := g.build(ctx, target)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	for _, doc := range docs {
+		name := filepath.Join(g.Dir, filepath.FromSlash(doc.name))
+		if err := index.WriteFileAtomic(name, doc.data, filePerm); err != nil {
+			return err
+		}
+		g.logf("wrote %s", name)
+	}
+	return nil
+}
+
+func (g *Generator) build(ctx context.Context, target Target) (document, error) {
+	var (
+		doc = document{}
+		err error
+	)
+	switch target {
+	case TargetContainer:
+		doc.name = index.ContainerIndexFile
+		var tags []string
+		if tags, err = g.ContainerIndex(ctx); err == nil {
+			doc.data, err = index.MarshalTags(tags)
+		}
+	case TargetOperator:
+		doc.name = index.OperatorIndexFile
+		var tags []string
+		if tags, err = g.OperatorIndex(ctx); err == nil {
+			doc.data, err = index.MarshalTags(tags)
+		}
+	case TargetMappings:
+		doc.name = index.OperatorMappingsFile
+		var mappings index.OperatorMappings
+		if mappings, err = g.OperatorMappings(ctx); err == nil {
+			doc.data, err = index.MarshalMappings(mappings)
+		}
+	default:
+		return doc, fmt.Errorf("unknown target %q", target)
+	}
+	if err != nil {
+		return doc, fmt.Errorf("cannot generate %s: %w", doc.name, err)
+	}
+	return doc, nil
+}
+
+// ContainerIndex lists the release tags of the Calyptia Core image in the
+// canonical order of NormalizeTags.
+func (g *Generator) ContainerIndex(ctx context.Context) ([]string, error) {
+	return g.tags(ctx, or(g.ContainerRepository, index.ContainerRepository), index.ContainerTagFilter)
+}
+
+// OperatorIndex lists the release tags of the operator image in the canonical
+// order of NormalizeTags.
+func (g *Generator) OperatorIndex(ctx context.Context) ([]string, error) {
+	return g.tags(ctx, or(g.OperatorRepository, index.OperatorRepository), index.OperatorTagFilter)
+}
+
+func (g *Generator) tags(ctx context.Context, repository string, filter *regexp.Regexp) ([]string, error) {
+	tags, err := g.Registry.Tags(ctx, repository)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tags of %s: %w", repository, err)
+	}
+
+	var out []string
+	for _, tag := range tags {
+		if filter.MatchString(tag) {
+			out = append(out, tag)
+		}
+	}
+	return index.NormalizeTags(out), nil
+}
+
+// OperatorMappings maps every operator release, and the latest one, to the
+// default Core Fluent Bit image of its pipeline CRD. Releases without a
+// manifest.yaml asset fall back to the notes of the backend release, and to
+// the image without a tag when the notes do not mention it.
+func (g *Generator) OperatorMappings(ctx context.Context) (index.OperatorMappings, error) {
+	repo := or(g.OperatorReleasesRepo, OperatorReleasesRepo)
+
+	releases, err := g.Releases.Releases(ctx, repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list releases of %s: %w", repo, err)
+	}
+
+	out := index.OperatorMappings{}
+	for _, release := range releases {
+		image, err := g.defaultImage(ctx, release)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get Core Fluent Bit version of %s: %w", release.TagName, err)
+		}
+		g.logf("%s: %s", release.TagName, image)
+		out[release.TagName] = image
+	}
+
+	latest, err := g.Releases.LatestRelease(ctx, repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get latest release of %s: %w", repo, err)
+	}
+	image, err := g.defaultImage(ctx, latest)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get Core Fluent Bit version of latest release %s: %w", latest.TagName, err)
+	}
+	out[index.LatestMapping] = image
+
+	return out, nil
+}
+
+func (g *Generator) defaultImage(ctx context.Context, release Release) (string, error) {
+	if url, ok := release.Asset(manifestAsset); ok {
+		manifest, err := g.Releases.Download(ctx, url)
+		if err != nil {
+			return "", err
+		}
+		return defaultImageFromManifest(manifest)
+	}
+
+	backend, err := g.Releases.Release(ctx, or(g.BackendRepo, BackendRepo), release.TagName)
+	if err != nil {
+		return "", fmt.Errorf("no %s asset and no backend release: %w", manifestAsset, err)
+	}
+	image, ok := defaultImageFromNotes(backend.Body)
+	if !ok {
+		g.logf("WARNING: no Core Fluent Bit image in the notes of %s, mapped to %s", release.TagName, image)
+	}
+	return image, nil
+}
+
+func (g *Generator) logf(format string, args ...any) {
+	if g.Logf != nil {
+		g.Logf(format, args...)
+	}
+}
+
+func or(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
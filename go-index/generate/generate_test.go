@@ -0,0 +1,251 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/oci"
+	"github.com/calyptia/core-images-index/go-index/oci/ocitest"
+)
+
+type fakeReleases struct {
+	releases  map[string][]Release
+	latest    map[string]Release
+	downloads map[string]string
+}
+
+func (r *fakeReleases) Releases(_ context.Context, repo string) ([]Release, error) {
+	return r.releases[repo], nil
+}
+
+func (r *fakeReleases) Release(_ context.Context, repo, tag string) (Release, error) {
+	for _, release := range r.releases[repo] {
+		if release.TagName == tag {
+			return release, nil
+		}
+	}
+	return Release{}, errors.New("release not found")
+}
+
+func (r *fakeReleases) LatestRelease(_ context.Context, repo string) (Release, error) {
+	return r.latest[repo], nil
+}
+
+func (r *fakeReleases) Download(_ context.Context, url string) ([]byte, error) {
+	body, ok := r.downloads[url]
+	if !ok {
+		return nil, errors.New("asset not found")
+	}
+	return []byte(body), nil
+}
+
+func manifestYAML(image string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: calyptia-core
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: pipelines.core.calyptia.com
+spec:
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          properties:
+            spec:
+              properties:
+                image:
+                  default: %s
+`, image)
+}
+
+func newGenerator(t *testing.T, releases *fakeReleases) *Generator {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "operator"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	repositories := map[string]ocitest.Repository{}
+	for repository, tags := range map[string][]string{
+		index.ContainerRepository: {"v1.1.0", "v1", "v1.0.0", "latest", "v0.9.0", "v1.1"},
+		index.OperatorRepository:  {"v2.0.0", "v1.10.0", "v1.9.0", "v2.1.0-rc1", "sha256-abc.sig"},
+	} {
+		images := map[string][]ocitest.Image{}
+		for _, tag := range tags {
+			images[tag] = []ocitest.Image{{Platform: oci.Platform{OS: "linux", Architecture: "amd64"}}}
+		}
+		repositories[repository] = ocitest.Repository{Tags: images}
+	}
+	r := ocitest.NewRegistry(t, ocitest.Config{Auth: true, Repositories: repositories})
+
+	return &Generator{
+		Registry: r.OCIClient(),
+		Releases: releases,
+		Dir:      dir,
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	releases := &fakeReleases{
+		releases: map[string][]Release{
+			OperatorReleasesRepo: {
+				{TagName: "v1.10.0", Assets: []Asset{{Name: manifestAsset, BrowserDownloadURL: "https://example.com/v1.10.0"}}},
+				{TagName: "v1.9.0"},
+			},
+			BackendRepo: {
+				{TagName: "v1.9.0", Body: "Uses ghcr.io/calyptia/core/calyptia-fluent-bit:23.1.1 by default."},
+			},
+		},
+		latest: map[string]Release{
+			OperatorReleasesRepo: {TagName: "v1.10.0", Assets: []Asset{{Name: manifestAsset, BrowserDownloadURL: "https://example.com/v1.10.0"}}},
+		},
+		downloads: map[string]string{
+			"https://example.com/v1.10.0": manifestYAML("ghcr.io/calyptia/core/calyptia-fluent-bit:23.2.1"),
+		},
+	}
+	g := newGenerator(t, releases)
+
+	if err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	var container, operator []string
+	readJSON(t, filepath.Join(g.Dir, index.ContainerIndexFile), &container)
+	readJSON(t, filepath.Join(g.Dir, index.OperatorIndexFile), &operator)
+
+	if want := []string{"v0.9.0", "v1.0.0", "v1.1.0", "v1", "v1.1"}; !reflect.DeepEqual(want, container) {
+		t.Errorf("want: %v != got: %v", want, container)
+	}
+	if want := []string{"v1.9.0", "v1.10.0", "v2.0.0"}; !reflect.DeepEqual(want, operator) {
+		t.Errorf("want: %v != got: %v", want, operator)
+	}
+
+	got, err := os.ReadFile(filepath.Join(g.Dir, index.OperatorMappingsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "v1.10.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:23.2.1",
+  "v1.9.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:23.1.1",
+  "latest": "ghcr.io/calyptia/core/calyptia-fluent-bit:23.2.1"
+}
+`
+	if want != string(got) {
+		t.Errorf("want: %s != got: %s", want, got)
+	}
+}
+
+func TestGenerator_GenerateUntagged(t *testing.T) {
+	releases := &fakeReleases{
+		releases: map[string][]Release{
+			OperatorReleasesRepo: {{TagName: "v1.9.0"}},
+			BackendRepo:          {{TagName: "v1.9.0", Body: "no image mentioned"}},
+		},
+		latest: map[string]Release{
+			OperatorReleasesRepo: {TagName: "v1.9.0"},
+		},
+	}
+	g := newGenerator(t, releases)
+
+	if err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(g.Dir, index.OperatorMappingsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like the committed mappings, as written by create-operator-mappings.sh.
+	want := `{
+  "v1.9.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:",
+  "latest": "ghcr.io/calyptia/core/calyptia-fluent-bit:"
+}
+`
+	if want != string(got) {
+		t.Errorf("want: %s != got: %s", want, got)
+	}
+	if _, err := os.Stat(filepath.Join(g.Dir, index.ContainerIndexFile)); err != nil {
+		t.Errorf("container index not written: %v", err)
+	}
+}
+
+func TestGenerator_GenerateFailure(t *testing.T) {
+	releases := &fakeReleases{
+		releases: map[string][]Release{
+			OperatorReleasesRepo: {{TagName: "v1.9.0"}},
+		},
+	}
+	g := newGenerator(t, releases)
+
+	mappings := filepath.Join(g.Dir, index.OperatorMappingsFile)
+	if err := os.WriteFile(mappings, []byte(`{"latest": "previous"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Generate(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	got, err := os.ReadFile(mappings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"latest": "previous"}`; want != string(got) {
+		t.Errorf("mappings were modified: %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(g.Dir, index.ContainerIndexFile)); !os.IsNotExist(err) {
+		t.Errorf("container index written despite failure: %v", err)
+	}
+}
+
+func TestGitHub_Releases(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=100&page=2>; rel="next"`, ts.URL, r.URL.Path))
+			_ = json.NewEncoder(w).Encode([]Release{{TagName: "v2.0.0"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]Release{{TagName: "v1.0.0"}})
+	}))
+	defer ts.Close()
+
+	gh := &GitHub{BaseURL: ts.URL, Token: "token", HTTPClient: ts.Client()}
+	releases, err := gh.Releases(context.Background(), OperatorReleasesRepo)
+	if err != nil {
+		t.Fatalf("releases: %v", err)
+	}
+	if want, got := 2, len(releases); want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
+
+func readJSON(t *testing.T, name string, out any) {
+	t.Helper()
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
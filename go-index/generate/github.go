@@ -0,0 +1,149 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultGitHubAPI = "https://api.github.com"
+
+	maxDownloadSize = 32 << 20
+)
+
+type (
+	Release struct {
+		TagName string  `json:"tag_name"`
+		Name    string  `json:"name"`
+		Body    string  `json:"body"`
+		Assets  []Asset `json:"assets"`
+	}
+
+	Asset struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}
+
+	// GitHub is a minimal client of the GitHub releases API, replacing the gh
+	// CLI used by the scripts.
+	GitHub struct {
+		// BaseURL of the API, defaults to DefaultGitHubAPI.
+		BaseURL string
+		// Token authenticates the requests, like GITHUB_TOKEN.
+		Token      string
+		HTTPClient *http.Client
+	}
+)
+
+// Asset returns the download URL of the asset called name.
+func (r Release) Asset(name string) (string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.BrowserDownloadURL, true
+		}
+	}
+	return "", false
+}
+
+func (g *GitHub) Releases(ctx context.Context, repo string) ([]Release, error) {
+	var out []Release
+	next := fmt.Sprintf("%s/repos/%s/releases?per_page=100", g.baseURL(), repo)
+	for next != "" {
+		var page []Release
+		header, err := g.get(ctx, next, &page)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+		next = nextPage(header.Get("Link"))
+	}
+	return out, nil
+}
+
+func (g *GitHub) Release(ctx context.Context, repo, tag string) (Release, error) {
+	var out Release
+	_, err := g.get(ctx, fmt.Sprintf("%s/repos/%s/releases/tags/%s", g.baseURL(), repo, url.PathEscape(tag)), &out)
+	return out, err
+}
+
+func (g *GitHub) LatestRelease(ctx context.Context, repo string) (Release, error) {
+	var out Release
+	_, err := g.get(ctx, fmt.Sprintf("%s/repos/%s/releases/latest", g.baseURL(), repo), &out)
+	return out, err
+}
+
+func (g *GitHub) Download(ctx context.Context, rawURL string) ([]byte, error) {
+	res, err := g.do(ctx, rawURL, "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxDownloadSize))
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %w", rawURL, err)
+	}
+	return b, nil
+}
+
+func (g *GitHub) get(ctx context.Context, rawURL string, out any) (http.Header, error) {
+	res, err := g.do(ctx, rawURL, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", rawURL, err)
+	}
+	return res.Header, nil
+}
+
+func (g *GitHub) do(ctx context.Context, rawURL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request %s: %w", rawURL, err)
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	client := g.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s: %w", rawURL, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("could not fetch %s: unexpected status %d", rawURL, res.StatusCode)
+	}
+	return res, nil
+}
+
+func (g *GitHub) baseURL() string {
+	if g.BaseURL == "" {
+		return DefaultGitHubAPI
+	}
+	return strings.TrimSuffix(g.BaseURL, "/")
+}
+
+// nextPage returns the rel="next" URL of a GitHub Link header.
+func nextPage(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
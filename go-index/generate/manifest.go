@@ -0,0 +1,83 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	pipelineCRD = "pipelines.core.calyptia.com"
+	// untaggedImage is mapped to the releases whose image is not known.
+	untaggedImage = "ghcr.io/calyptia/core/calyptia-fluent-bit:"
+)
+
+// coreFluentBitImage matches the Core Fluent Bit image referenced in the
+// notes of a backend release.
+var coreFluentBitImage = regexp.MustCompile(`ghcr\.io/calyptia/core/calyptia-fluent-bit:([\w][\w.-]*)`)
+
+type crd struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Versions []struct {
+			Schema struct {
+				OpenAPIV3Schema struct {
+					Properties struct {
+						Spec struct {
+							Properties struct {
+								Image struct {
+									Default string `yaml:"default"`
+								} `yaml:"image"`
+							} `yaml:"properties"`
+						} `yaml:"spec"`
+					} `yaml:"properties"`
+				} `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// defaultImageFromManifest returns the default image of the pipeline CRD found
+// in a multi document operator manifest.yaml.
+func defaultImageFromManifest(manifest []byte) (string, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		var doc crd
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not decode manifest: %w", err)
+		}
+		if doc.Kind != "CustomResourceDefinition" || doc.Metadata.Name != pipelineCRD || len(doc.Spec.Versions) == 0 {
+			continue
+		}
+
+		image := doc.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties.Spec.Properties.Image.Default
+		if image == "" {
+			return "", fmt.Errorf("%s has no default image", pipelineCRD)
+		}
+		return image, nil
+	}
+	return "", fmt.Errorf("%s not found in manifest", pipelineCRD)
+}
+
+// defaultImageFromNotes returns the Core Fluent Bit image mentioned in release
+// notes, for releases predating the manifest.yaml asset. Like
+// create-operator-mappings.sh, the image is returned without a tag when the
+// notes do not mention it, and ok is false.
+func defaultImageFromNotes(notes string) (image string, ok bool) {
+	match := coreFluentBitImage.FindString(notes)
+	if match == "" {
+		return untaggedImage, false
+	}
+	return match, true
+}
@@ -0,0 +1,226 @@
+package generate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+const (
+	// LuaSchemaPath and PluginsSchemaPath are the locations of the schemas
+	// inside the Core Fluent Bit image.
+	LuaSchemaPath     = "/schema.json"
+	PluginsSchemaPath = "/opt/calyptia-fluent-bit/etc/plugins.json"
+	// ProvenanceFile records how the schemas of a version were extracted.
+	ProvenanceFile = "provenance.json"
+
+	DefaultPlatform = "linux/amd64"
+
+	dirPerm = 0o755
+)
+
+type (
+	// ImageReader reads files from the layers of an image, it is implemented
+	// by *oci.Client.
+	ImageReader interface {
+		ImageManifest(ctx context.Context, repository, reference, platform string) (oci.Manifest, error)
+		ReadFiles(ctx context.Context, repository string, manifest oci.Manifest, paths ...string) (map[string][]byte, error)
+	}
+
+	// SchemaExtractor writes the schemas of Core Fluent Bit images below
+	// schemas/<tag>, replacing create-core-fluent-bit-schemas.sh. The Lua and
+	// plugins schemas are read straight from the image layers, without a
+	// container runtime.
+	SchemaExtractor struct {
+		Images ImageReader
+		// Dir is the root of the index repository.
+		Dir string
+		// Repository defaults to index.CoreFluentBitRepository.
+		Repository string
+		// Platform of the image to read, defaults to DefaultPlatform.
+		Platform string
+		// Force extracts versions whose schema is already committed.
+		Force bool
+		// CoreSchema produces core-fluent-bit.json. The image generates it when
+		// run with -J, it is not a file of the image, so it is only written when
+		// set, for instance by running the image with a container runtime.
+		CoreSchema func(ctx context.Context, image string) ([]byte, error)
+		// Now defaults to time.Now.
+		Now func() time.Time
+		// Logf reports progress when set.
+		Logf func(format string, args ...any)
+	}
+
+	// Provenance is written next to the extracted schemas.
+	Provenance struct {
+		Image    string `json:"image"`
+		Tag      string `json:"tag"`
+		Platform string `json:"platform"`
+		// Digest of the platform image the files were read from.
+		Digest      string    `json:"digest"`
+		ExtractedAt time.Time `json:"extracted_at"`
+		// Files lists the schema files written, in their compact variant.
+		Files []string `json:"files"`
+	}
+)
+
+// ExtractAll extracts the schemas of every tag, skipping the versions already
+// committed unless Force is set. A tag failing does not stop the others, the
+// errors of every failed tag are returned joined.
+func (e *SchemaExtractor) ExtractAll(ctx context.Context, tags []string) ([]Provenance, error) {
+	var (
+		out  []Provenance
+		errs []error
+	)
+	for _, tag := range tags {
+		if !e.Force && e.extracted(tag) {
+			e.logf("schema for %s already present; skipping", tag)
+			continue
+		}
+
+		provenance, err := e.Extract(ctx, tag)
+		if err != nil {
+			if ctx.Err() != nil {
+				return out, ctx.Err()
+			}
+			errs = append(errs, err)
+			continue
+		}
+		out = append(out, provenance)
+	}
+	return out, errors.Join(errs...)
+}
+
+// extracted reports whether the core schema of tag is committed. A tag
+// extracted without CoreSchema has a provenance but no core schema, so it is
+// extracted again once a container runtime is available.
+func (e *SchemaExtractor) extracted(tag string) bool {
+	_, err := os.Stat(e.path(tag, index.SchemaFile))
+	return err == nil
+}
+
+// Extract writes the schemas of tag, each with its -pretty variant, and their
+// provenance. A schema missing from the image is logged and skipped, like
+// older images without a Lua schema.
+func (e *SchemaExtractor) Extract(ctx context.Context, tag string) (Provenance, error) {
+	repository := or(e.Repository, index.CoreFluentBitRepository)
+	provenance := Provenance{
+		Image:    repository,
+		Tag:      tag,
+		Platform: or(e.Platform, DefaultPlatform),
+	}
+
+	manifest, err := e.Images.ImageManifest(ctx, repository, tag, provenance.Platform)
+	if err != nil {
+		return provenance, fmt.Errorf("cannot get image %s:%s: %w", repository, tag, err)
+	}
+	provenance.Digest = manifest.Digest
+
+	files, err := e.Images.ReadFiles(ctx, repository, manifest, LuaSchemaPath, PluginsSchemaPath)
+	if err != nil {
+		return provenance, fmt.Errorf("cannot read schemas of %s:%s: %w", repository, tag, err)
+	}
+
+	docs := map[string][]byte{}
+	for name, file := range map[string]string{index.LuaSchemaFile: LuaSchemaPath, index.PluginsSchemaFile: PluginsSchemaPath} {
+		data, ok := files[file]
+		if !ok {
+			e.logf("WARNING: unable to find %s in %s:%s", file, repository, tag)
+			continue
+		}
+		docs[name] = data
+	}
+
+	if e.CoreSchema == nil {
+		e.logf("WARNING: %s of %s:%s needs a container runtime and is not written", index.SchemaFile, repository, tag)
+	} else {
+		data, err := e.CoreSchema(ctx, repository+"@"+manifest.Digest)
+		if err != nil {
+			return provenance, fmt.Errorf("cannot get schema of %s:%s: %w", repository, tag, err)
+		}
+		docs[index.SchemaFile] = data
+	}
+
+	if err := e.write(tag, docs, &provenance); err != nil {
+		return provenance, err
+	}
+	return provenance, nil
+}
+
+func (e *SchemaExtractor) write(tag string, docs map[string][]byte, provenance *Provenance) error {
+	if err := os.MkdirAll(filepath.Dir(e.path(tag, ProvenanceFile)), dirPerm); err != nil {
+		return err
+	}
+
+	for _, name := range []string{index.SchemaFile, index.LuaSchemaFile, index.PluginsSchemaFile} {
+		data, ok := docs[name]
+		if !ok {
+			continue
+		}
+		pretty, err := prettyJSON(data)
+		if err != nil {
+			return fmt.Errorf("invalid %s for %s: %w", name, tag, err)
+		}
+		if err := index.WriteFileAtomic(e.path(tag, name), data, filePerm); err != nil {
+			return err
+		}
+		if err := index.WriteFileAtomic(e.path(tag, PrettyName(name)), pretty, filePerm); err != nil {
+			return err
+		}
+		provenance.Files = append(provenance.Files, name)
+	}
+	if len(provenance.Files) == 0 {
+		return fmt.Errorf("no schema found in %s:%s: %w", provenance.Image, tag, fs.ErrNotExist)
+	}
+
+	now := time.Now
+	if e.Now != nil {
+		now = e.Now
+	}
+	provenance.ExtractedAt = now().UTC()
+
+	data, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := index.WriteFileAtomic(e.path(tag, ProvenanceFile), append(data, '\n'), filePerm); err != nil {
+		return err
+	}
+	e.logf("extracted %s", tag)
+	return nil
+}
+
+func (e *SchemaExtractor) path(tag, name string) string {
+	return filepath.Join(e.Dir, index.SchemaDir, tag, name)
+}
+
+func (e *SchemaExtractor) logf(format string, args ...any) {
+	if e.Logf != nil {
+		e.Logf(format, args...)
+	}
+}
+
+// PrettyName returns the name of the -pretty variant of a schema file.
+func PrettyName(name string) string {
+	return strings.TrimSuffix(name, ".json") + "-pretty.json"
+}
+
+// prettyJSON indents data like jq, which produced the committed -pretty files.
+func prettyJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
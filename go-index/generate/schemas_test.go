@@ -0,0 +1,143 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+type fakeImages map[string]map[string][]byte
+
+func (f fakeImages) ImageManifest(_ context.Context, repository, reference, platform string) (oci.Manifest, error) {
+	if _, ok := f[reference]; !ok {
+		return oci.Manifest{}, oci.ErrNotFound
+	}
+	return oci.Manifest{Digest: "sha256:" + reference}, nil
+}
+
+func (f fakeImages) ReadFiles(_ context.Context, repository string, manifest oci.Manifest, paths ...string) (map[string][]byte, error) {
+	files := f[manifest.Digest[len("sha256:"):]]
+	out := map[string][]byte{}
+	for _, p := range paths {
+		if content, ok := files[p]; ok {
+			out[p] = content
+		}
+	}
+	return out, nil
+}
+
+func TestSchemaExtractor_ExtractAll(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	committed := filepath.Join(dir, index.SchemaDir, "25.1.1")
+	if err := os.MkdirAll(committed, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(committed, index.SchemaFile), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &SchemaExtractor{
+		Images: fakeImages{
+			"25.1.1": {},
+			"25.2.1": {
+				LuaSchemaPath:     []byte(`{"processingRules":{}}`),
+				PluginsSchemaPath: []byte(`{"plugins":[{"name":"x"}]}`),
+			},
+		},
+		Dir: dir,
+		CoreSchema: func(_ context.Context, image string) ([]byte, error) {
+			return []byte(`{"fluent-bit":{"version":"` + image + `"}}` + "\r\n"), nil
+		},
+		Now: func() time.Time { return now },
+	}
+
+	got, err := e.ExtractAll(context.Background(), []string{"25.1.1", "25.2.1"})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	want := []Provenance{{
+		Image:       index.CoreFluentBitRepository,
+		Tag:         "25.2.1",
+		Platform:    DefaultPlatform,
+		Digest:      "sha256:25.2.1",
+		ExtractedAt: now,
+		Files:       []string{index.SchemaFile, index.LuaSchemaFile, index.PluginsSchemaFile},
+	}}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want: %+v != got: %+v", want, got)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(dir, index.SchemaDir, "25.2.1", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if want, got := `{"plugins":[{"name":"x"}]}`, read(index.PluginsSchemaFile); want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+	if want, got := "{\n  \"plugins\": [\n    {\n      \"name\": \"x\"\n    }\n  ]\n}\n", read(PrettyName(index.PluginsSchemaFile)); want != got {
+		t.Errorf("want: %q != got: %q", want, got)
+	}
+	if want, got := "{\n  \"fluent-bit\": {\n    \"version\": \""+index.CoreFluentBitRepository+"@sha256:25.2.1\"\n  }\n}\n", read("core-fluent-bit-pretty.json"); want != got {
+		t.Errorf("want: %q != got: %q", want, got)
+	}
+
+	var provenance Provenance
+	if err := json.Unmarshal([]byte(read(ProvenanceFile)), &provenance); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want[0], provenance) {
+		t.Errorf("want: %+v != got: %+v", want[0], provenance)
+	}
+
+	// The tags failing do not stop the extraction of the others.
+	e.Force = true
+	e.CoreSchema = nil
+	got, err = e.ExtractAll(context.Background(), []string{"25.1.1", "25.0.0", "25.2.1"})
+	if !errors.Is(err, oci.ErrNotFound) || !strings.Contains(err.Error(), "25.1.1") {
+		t.Errorf("want: errors of 25.1.1 and 25.0.0 != got: %v", err)
+	}
+	if len(got) != 1 || got[0].Tag != "25.2.1" {
+		t.Errorf("want: 25.2.1 extracted != got: %+v", got)
+	}
+}
+
+func TestSchemaExtractor_withoutRuntime(t *testing.T) {
+	dir := t.TempDir()
+	e := &SchemaExtractor{
+		Images: fakeImages{"25.2.1": {PluginsSchemaPath: []byte(`{"plugins":[]}`)}},
+		Dir:    dir,
+	}
+
+	ctx := context.Background()
+	if _, err := e.ExtractAll(ctx, []string{"25.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, index.SchemaDir, "25.2.1", index.SchemaFile)); !os.IsNotExist(err) {
+		t.Fatalf("want: %s not written != got: %v", index.SchemaFile, err)
+	}
+
+	// The core schema is still missing, so the tag is extracted again.
+	e.CoreSchema = func(context.Context, string) ([]byte, error) { return []byte(`{}`), nil }
+	got, err := e.ExtractAll(ctx, []string{"25.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{index.SchemaFile, index.PluginsSchemaFile}; len(got) != 1 || !reflect.DeepEqual(want, got[0].Files) {
+		t.Fatalf("want: %v != got: %+v", want, got)
+	}
+}
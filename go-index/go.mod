@@ -2,4 +2,7 @@ module github.com/calyptia/core-images-index/go-index
 
 go 1.26.3
 
-require github.com/hashicorp/go-version v1.6.0
+require (
+	github.com/hashicorp/go-version v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,295 @@
+// Package indextest serves index documents over HTTP for tests, with the
+// layout of this repository, so that fetchers can be tested end-to-end
+// through their real HTTP path without the network. Faults such as latency,
+// error statuses and malformed JSON are injected per document.
+package indextest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+const (
+	// ETagMatch sends an ETag derived from the document and answers 304 Not
+	// Modified when it matches If-None-Match.
+	ETagMatch ETagMode = iota
+	// ETagNone sends no ETag.
+	ETagNone
+	// ETagIgnore sends an ETag but always answers with the document, like
+	// caches that do not revalidate.
+	ETagIgnore
+	// ETagChanging sends a new ETag on every response so that clients never
+	// revalidate.
+	ETagChanging
+)
+
+type (
+	ETagMode int
+
+	// Fault alters the responses of a document.
+	Fault struct {
+		// Latency delays the response, or until the request is canceled.
+		Latency time.Duration
+		// Status replaces the response with an empty one of this status.
+		Status int
+		// RetryAfter is sent with Status, such as "1" or an HTTP date.
+		RetryAfter string
+		// Malformed truncates the document in the middle.
+		Malformed bool
+		// Times is the number of responses altered, every one when zero.
+		Times int
+	}
+
+	// Request is a request served by the server.
+	Request struct {
+		Path        string
+		IfNoneMatch string
+		Status      int
+	}
+
+	// Server is an httptest.Server serving the documents set on it. It is
+	// safe for concurrent use.
+	Server struct {
+		*httptest.Server
+
+		mu       sync.Mutex
+		docs     map[string][]byte
+		faults   map[string]*Fault
+		etags    ETagMode
+		served   int
+		requests []Request
+	}
+)
+
+// NewServer starts a server without documents, closed with the test.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s := &Server{docs: map[string][]byte{}, faults: map[string]*Fault{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Source returns a URL source reading the server.
+func (s *Server) Source() index.Source {
+	return index.NewURLSource(s.URL, s.Client())
+}
+
+// SetFile serves data at name, a path such as operator.index.json.
+func (s *Server) SetFile(name string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[path.Clean(name)] = data
+}
+
+// SetJSON serves the JSON encoding of v at name.
+func (s *Server) SetJSON(tb testing.TB, name string, v any) {
+	tb.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		tb.Fatalf("cannot encode %s: %v", name, err)
+	}
+	s.SetFile(name, data)
+}
+
+// SetContainer serves the container index holding tags.
+func (s *Server) SetContainer(tb testing.TB, tags ...string) {
+	tb.Helper()
+	s.setTags(tb, index.ContainerIndexFile, tags)
+}
+
+// SetOperator serves the operator index holding tags.
+func (s *Server) SetOperator(tb testing.TB, tags ...string) {
+	tb.Helper()
+	s.setTags(tb, index.OperatorIndexFile, tags)
+}
+
+func (s *Server) setTags(tb testing.TB, name string, tags []string) {
+	tb.Helper()
+
+	data, err := index.MarshalTags(tags)
+	if err != nil {
+		tb.Fatalf("cannot encode %s: %v", name, err)
+	}
+	s.SetFile(name, data)
+}
+
+// SetMappings serves the operator mappings.
+func (s *Server) SetMappings(tb testing.TB, m index.OperatorMappings) {
+	tb.Helper()
+
+	data, err := index.MarshalMappings(m)
+	if err != nil {
+		tb.Fatalf("cannot encode mappings: %v", err)
+	}
+	s.SetFile(index.OperatorMappingsFile, data)
+}
+
+// SetSchema serves the core schema of version.
+func (s *Server) SetSchema(tb testing.TB, version string, schema index.Schema) {
+	tb.Helper()
+	s.SetJSON(tb, SchemaPath(version, index.SchemaFile), schema)
+}
+
+// SetLuaSchema serves the lua schema of version.
+func (s *Server) SetLuaSchema(tb testing.TB, version string, schema index.LuaSchema) {
+	tb.Helper()
+	s.SetJSON(tb, SchemaPath(version, index.LuaSchemaFile), schema)
+}
+
+// SetPluginsSchema serves the enterprise plugins schema of version.
+func (s *Server) SetPluginsSchema(tb testing.TB, version string, schema index.PluginsSchema) {
+	tb.Helper()
+	s.SetJSON(tb, SchemaPath(version, index.PluginsSchemaFile), schema)
+}
+
+// Remove stops serving name, which is then not found.
+func (s *Server) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.docs, path.Clean(name))
+}
+
+// Inject alters the responses of name with f, replacing any previous fault.
+func (s *Server) Inject(name string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path.Clean(name)] = &f
+}
+
+// Heal removes the fault of name.
+func (s *Server) Heal(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.faults, path.Clean(name))
+}
+
+// SetETagMode sets how ETags are sent and honored, ETagMatch by default.
+func (s *Server) SetETagMode(mode ETagMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etags = mode
+}
+
+// Requests returns the requests served so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// Count returns the number of requests served for name.
+func (s *Server) Count(name string) int {
+	name = path.Clean(name)
+	n := 0
+	for _, r := range s.Requests() {
+		if r.Path == name {
+			n++
+		}
+	}
+	return n
+}
+
+// SchemaPath returns the path of a schema file of version.
+func SchemaPath(version, file string) string {
+	return path.Join(index.SchemaDir, version, file)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	data, ok, fault, etag := s.lookup(name)
+
+	status := http.StatusOK
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, Request{Path: name, IfNoneMatch: r.Header.Get("If-None-Match"), Status: status})
+	}()
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			status = 0
+			return
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case fault.Status != 0:
+		status = fault.Status
+		if fault.RetryAfter != "" {
+			w.Header().Set("Retry-After", fault.RetryAfter)
+		}
+		w.WriteHeader(status)
+		return
+	case !ok:
+		status = http.StatusNotFound
+		http.NotFound(w, r)
+		return
+	case fault.Malformed:
+		data = data[:len(data)/2]
+	}
+
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		if s.etagMode() == ETagMatch && r.Header.Get("If-None-Match") == etag {
+			status = http.StatusNotModified
+			w.WriteHeader(status)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// lookup returns the document name, the fault to apply to this response and
+// the ETag to send.
+func (s *Server) lookup(name string) ([]byte, bool, Fault, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.docs[name]
+	var fault Fault
+	if f, found := s.faults[name]; found {
+		fault = *f
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				delete(s.faults, name)
+			}
+		}
+	}
+
+	s.served++
+	var etag string
+	switch s.etags {
+	case ETagMatch, ETagIgnore:
+		sum := sha256.Sum256(data)
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	case ETagChanging:
+		etag = `"` + strconv.Itoa(s.served) + `"`
+	}
+	return data, ok, fault, etag
+}
+
+func (s *Server) etagMode() ETagMode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.etags
+}
@@ -0,0 +1,164 @@
+package indextest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer(t)
+	s.SetContainer(t, "v24.7.1", "v24.6.0")
+	s.SetOperator(t, "v3.119.0", "v3.120.0-rc1")
+	s.SetMappings(t, index.OperatorMappings{"v3.119.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1"})
+	s.SetSchema(t, "24.7.1", index.Schema{Inputs: []index.SchemaPlugin{{Type: "input", Name: "dummy"}}})
+	s.SetLuaSchema(t, "24.7.1", index.LuaSchema{ProcessingRules: map[string]index.LuaProcessingRule{"block_keys": {}}})
+
+	ctx := context.Background()
+	src := s.Source()
+
+	container, err := (&index.Container{Fetcher: src.Container}).Last(ctx)
+	if err != nil || container != "v24.7.1" {
+		t.Fatalf("want: v24.7.1 != got: %s (%v)", container, err)
+	}
+
+	operator := &index.Operator{Fetcher: src.Operator}
+	latest, err := operator.Latest(ctx, index.ChannelStable)
+	if err != nil || latest != "v3.119.0" {
+		t.Fatalf("want: v3.119.0 != got: %s (%v)", latest, err)
+	}
+	image, err := operator.CoreFluentBitImage(ctx, latest)
+	if err != nil || image != "ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1" {
+		t.Fatalf("want: ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1 != got: %s (%v)", image, err)
+	}
+
+	catalog, err := index.Catalog(ctx, src.Schemas, "24.7.1")
+	if err != nil || len(catalog.Plugins) != 2 {
+		t.Fatalf("want: 2 plugins != got: %+v (%v)", catalog.Plugins, err)
+	}
+
+	if _, err := src.Schemas.GetSchema(ctx, "24.6.0"); !errors.Is(err, index.ErrNotFound) {
+		t.Fatalf("want: %v != got: %v", index.ErrNotFound, err)
+	}
+	if want, got := 1, s.Count(index.ContainerIndexFile); want != got {
+		t.Fatalf("want: %d != got: %d", want, got)
+	}
+}
+
+func TestServer_Inject(t *testing.T) {
+	s := NewServer(t)
+	s.SetOperator(t, "v3.119.0")
+	ctx := context.Background()
+
+	t.Run("status", func(t *testing.T) {
+		s.Inject(index.OperatorIndexFile, Fault{Status: http.StatusServiceUnavailable, RetryAfter: "1"})
+		defer s.Heal(index.OperatorIndexFile)
+
+		_, err := s.Source().Operator.GetImages(ctx)
+		var statusErr *index.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || statusErr.RetryAfter != time.Second {
+			t.Fatalf("want: 503 retry after 1s != got: %v", err)
+		}
+	})
+
+	t.Run("transient status retried", func(t *testing.T) {
+		s.Inject(index.OperatorIndexFile, Fault{Status: http.StatusBadGateway, Times: 2})
+		before := s.Count(index.OperatorIndexFile)
+
+		fetcher := &index.OperatorIndexRetryFetcher{
+			Fetcher: s.Source().Operator,
+			Policy:  index.RetryPolicy{InitialBackoff: time.Millisecond},
+		}
+		images, err := fetcher.GetImages(ctx)
+		if err != nil || !reflect.DeepEqual(images, index.OperatorImages{"v3.119.0"}) {
+			t.Fatalf("want: [v3.119.0] != got: %v (%v)", images, err)
+		}
+		if want, got := 3, s.Count(index.OperatorIndexFile)-before; want != got {
+			t.Fatalf("want: %d != got: %d", want, got)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		s.Inject(index.OperatorIndexFile, Fault{Malformed: true, Times: 1})
+
+		_, err := s.Source().Operator.GetImages(ctx)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("want: %v != got: %v", io.ErrUnexpectedEOF, err)
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		s.Inject(index.OperatorIndexFile, Fault{Latency: time.Minute})
+		defer s.Heal(index.OperatorIndexFile)
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := s.Source().Operator.GetImages(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want: %v != got: %v", context.DeadlineExceeded, err)
+		}
+	})
+}
+
+func TestServer_ETag(t *testing.T) {
+	s := NewServer(t)
+	s.SetOperator(t, "v3.119.0")
+
+	get := func(etag string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, s.URL+"/"+index.OperatorIndexFile, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res, err := s.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		return res.StatusCode, res.Header.Get("ETag")
+	}
+
+	status, etag := get("")
+	if status != http.StatusOK || etag == "" {
+		t.Fatalf("want: 200 with an ETag != got: %d %q", status, etag)
+	}
+	if status, _ := get(etag); status != http.StatusNotModified {
+		t.Fatalf("want: %d != got: %d", http.StatusNotModified, status)
+	}
+
+	s.SetOperator(t, "v3.119.0", "v3.120.0")
+	if status, _ := get(etag); status != http.StatusOK {
+		t.Fatalf("want: %d != got: %d", http.StatusOK, status)
+	}
+
+	s.SetETagMode(ETagIgnore)
+	_, etag = get("")
+	if status, _ := get(etag); status != http.StatusOK {
+		t.Fatalf("want: %d != got: %d", http.StatusOK, status)
+	}
+
+	s.SetETagMode(ETagChanging)
+	_, first := get("")
+	if _, second := get(first); first == second {
+		t.Fatalf("want: a new ETag != got: %s", second)
+	}
+
+	s.SetETagMode(ETagNone)
+	if _, etag := get(""); etag != "" {
+		t.Fatalf("want: no ETag != got: %s", etag)
+	}
+
+	requests := s.Requests()
+	if last := requests[len(requests)-1]; last.Path != index.OperatorIndexFile || last.Status != http.StatusOK {
+		t.Fatalf("want: %s 200 != got: %+v", index.OperatorIndexFile, last)
+	}
+}
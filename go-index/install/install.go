@@ -0,0 +1,236 @@
+// Package install plans the installation of Calyptia Core with the rules of
+// install-core.sh: which release of the operator index to install, the
+// package to download for the host and the Core Fluent Bit image it deploys.
+package install
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	semver "github.com/hashicorp/go-version"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+const (
+	// DefaultBaseURL holds a directory of packages per release.
+	DefaultBaseURL    = "https://core-packages.calyptia.com/core"
+	DefaultRPMRelease = "-1"
+
+	FlavorOperator Flavor = "calyptia-core-operator"
+	// FlavorLegacy is the classic Calyptia Core package.
+	FlavorLegacy Flavor = "calyptia-core"
+
+	// OSDebian covers the systems installing deb packages with dpkg.
+	OSDebian OSFamily = "debian"
+	// OSRHEL covers the systems installing rpm packages.
+	OSRHEL OSFamily = "rhel"
+	// OSAlpine covers the systems installing apk packages.
+	OSAlpine OSFamily = "alpine"
+
+	FormatDeb = "deb"
+	FormatRPM = "rpm"
+	FormatAPK = "apk"
+
+	// ImageKnown is the status of a Core Fluent Bit image read from the
+	// operator mappings.
+	ImageKnown ImageStatus = "known"
+	// ImageUntagged is the status of a release mapped to an image without a
+	// tag, the deployed image is decided by the operator at runtime.
+	ImageUntagged ImageStatus = "untagged"
+	// ImageUnmapped is the status of a release missing from the mappings.
+	ImageUnmapped ImageStatus = "unmapped"
+	// ImageUnpublished is the status of every release when the source does
+	// not publish the operator mappings.
+	ImageUnpublished ImageStatus = "unpublished"
+)
+
+var (
+	ErrUnsupportedArch = errors.New("unsupported architecture")
+	ErrUnsupportedOS   = errors.New("unsupported OS family")
+)
+
+type (
+	// Flavor is the name prefix of the package.
+	Flavor   string
+	OSFamily string
+	// ImageStatus tells whether the Core Fluent Bit image of a release is
+	// known, see ImageKnown.
+	ImageStatus string
+
+	// Request describes the host and the release to install.
+	Request struct {
+		OS OSFamily
+		// Arch such as amd64 or arm64, x86_64 and aarch64 are accepted too.
+		Arch   string
+		Flavor Flavor
+		// Version is an exact version such as 3.119.0, a constraint such as
+		// ">= 3.100, < 4.0", or empty for the latest stable release.
+		Version string
+		// BaseURL of the packages, defaults to DefaultBaseURL. The version is
+		// appended to it.
+		BaseURL string
+		// RPMRelease is appended to the version in RPM file names, defaults
+		// to DefaultRPMRelease.
+		RPMRelease string
+	}
+
+	// Plan is the outcome of a Request.
+	Plan struct {
+		// Tag of the release in the operator index, such as v3.119.0.
+		Tag string `json:"tag"`
+		// Version of the package, such as 3.119.0.
+		Version string   `json:"version"`
+		OS      OSFamily `json:"os"`
+		// Arch is the architecture as named by the package format.
+		Arch     string `json:"arch"`
+		Flavor   Flavor `json:"flavor"`
+		Format   string `json:"format"`
+		Filename string `json:"filename"`
+		URL      string `json:"url"`
+		// CoreFluentBitImage is the image deployed by default by the
+		// release, empty unless CoreFluentBitImageStatus is ImageKnown.
+		CoreFluentBitImage       string      `json:"core_fluent_bit_image,omitempty"`
+		CoreFluentBitImageStatus ImageStatus `json:"core_fluent_bit_image_status"`
+	}
+
+	// Planner resolves requests through the operator index.
+	Planner struct {
+		Operator *index.Operator
+	}
+)
+
+// Plan resolves the release matching req and the package to install.
+func (p *Planner) Plan(ctx context.Context, req Request) (Plan, error) {
+	if req.Flavor == "" {
+		req.Flavor = FlavorOperator
+	}
+	if req.BaseURL == "" {
+		req.BaseURL = DefaultBaseURL
+	}
+	if req.RPMRelease == "" {
+		req.RPMRelease = DefaultRPMRelease
+	}
+
+	tag, err := p.resolve(ctx, req.Version)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{
+		Tag:     tag,
+		Version: strings.TrimPrefix(tag, "v"),
+		OS:      req.OS,
+		Flavor:  req.Flavor,
+	}
+	if plan.Format, err = req.OS.Format(); err != nil {
+		return Plan{}, err
+	}
+	if plan.Arch, err = PackageArch(req.OS, req.Arch); err != nil {
+		return Plan{}, err
+	}
+	plan.Filename = packageFile(plan, req.RPMRelease)
+	plan.URL = strings.TrimSuffix(req.BaseURL, "/") + "/" + plan.Version + "/" + plan.Filename
+
+	plan.CoreFluentBitImage, plan.CoreFluentBitImageStatus, err = p.image(ctx, tag)
+	if err != nil {
+		return Plan{}, err
+	}
+	return plan, nil
+}
+
+// image returns the default Core Fluent Bit image of the release tag and its
+// status. A fetcher without the operator mappings reports ImageUnpublished.
+func (p *Planner) image(ctx context.Context, tag string) (string, ImageStatus, error) {
+	image, err := p.Operator.CoreFluentBitImage(ctx, tag)
+	switch {
+	case err == nil:
+		return image, ImageKnown, nil
+	case errors.Is(err, index.ErrUntaggedImage):
+		return "", ImageUntagged, nil
+	case errors.Is(err, index.ErrNoMatchingImage):
+		return "", ImageUnmapped, nil
+	case errors.Is(err, index.ErrNotFound):
+		return "", ImageUnpublished, nil
+	default:
+		return "", "", err
+	}
+}
+
+// resolve returns the tag of the operator index matching version.
+func (p *Planner) resolve(ctx context.Context, version string) (string, error) {
+	if version == "" {
+		return p.Operator.Latest(ctx, index.ChannelStable)
+	}
+	if _, err := semver.NewVersion(version); err == nil {
+		return p.Operator.Match(ctx, version)
+	}
+	return p.Operator.MatchConstraint(ctx, version)
+}
+
+// Format returns the package format installed by the OS family.
+func (f OSFamily) Format() (string, error) {
+	switch f {
+	case OSDebian:
+		return FormatDeb, nil
+	case OSRHEL:
+		return FormatRPM, nil
+	case OSAlpine:
+		return FormatAPK, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnsupportedOS, f)
+	}
+}
+
+// NormalizeArch maps the architecture names of uname to amd64 and arm64.
+func NormalizeArch(arch string) (string, error) {
+	switch arch {
+	case "amd64", "x86_64":
+		return "amd64", nil
+	case "arm64", "aarch64":
+		return "arm64", nil
+	default:
+		return "", fmt.Errorf("%w %s", ErrUnsupportedArch, arch)
+	}
+}
+
+// PackageArch returns the name of arch in the packages of the OS family:
+// x86_64 and aarch64 for RPMs, amd64 and arm64 otherwise.
+func PackageArch(os OSFamily, arch string) (string, error) {
+	arch, err := NormalizeArch(arch)
+	if err != nil {
+		return "", err
+	}
+	if os == OSRHEL {
+		return map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[arch], nil
+	}
+	return arch, nil
+}
+
+// PackageFile returns the name of the package of flavor at version, such as
+// 3.119.0, for the OS family and arch.
+func PackageFile(os OSFamily, arch string, flavor Flavor, version, rpmRelease string) (string, error) {
+	format, err := os.Format()
+	if err != nil {
+		return "", err
+	}
+	packageArch, err := PackageArch(os, arch)
+	if err != nil {
+		return "", err
+	}
+	return packageFile(Plan{
+		Version: strings.TrimPrefix(version, "v"),
+		Arch:    packageArch,
+		Flavor:  flavor,
+		Format:  format,
+	}, rpmRelease), nil
+}
+
+func packageFile(plan Plan, rpmRelease string) string {
+	if plan.Format == FormatRPM {
+		return fmt.Sprintf("%s-%s%s.%s.rpm", plan.Flavor, plan.Version, rpmRelease, plan.Arch)
+	}
+	return fmt.Sprintf("%s_%s_%s.%s", plan.Flavor, plan.Version, plan.Arch, plan.Format)
+}
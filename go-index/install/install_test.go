@@ -0,0 +1,177 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+func TestPlanner_Plan(t *testing.T) {
+	fsys := fstest.MapFS{
+		index.OperatorIndexFile:  {Data: []byte(`["v3.110.0","v3.116.0","v3.119.0","v3.120.0-rc1","v4.0.0"]`)},
+		index.OperatorYankedFile: {Data: []byte(`[{"version":"v4.0.0","reason":"broken"}]`)},
+		index.OperatorMappingsFile: {Data: []byte(`{
+			"v3.119.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1",
+			"v3.116.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:"
+		}`)},
+	}
+	planner := &Planner{Operator: &index.Operator{Fetcher: &index.OperatorIndexFSFetcher{FS: fsys}}}
+
+	tt := []struct {
+		name       string
+		req        Request
+		want       Plan
+		wantErr    error
+		wantYanked bool
+	}{
+		{
+			name: "latest deb",
+			req:  Request{OS: OSDebian, Arch: "x86_64"},
+			want: Plan{
+				Tag:                      "v3.119.0",
+				Version:                  "3.119.0",
+				OS:                       OSDebian,
+				Arch:                     "amd64",
+				Flavor:                   FlavorOperator,
+				Format:                   FormatDeb,
+				Filename:                 "calyptia-core-operator_3.119.0_amd64.deb",
+				URL:                      "https://core-packages.calyptia.com/core/3.119.0/calyptia-core-operator_3.119.0_amd64.deb",
+				CoreFluentBitImage:       "ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1",
+				CoreFluentBitImageStatus: ImageKnown,
+			},
+		},
+		{
+			name: "constraint rpm without image",
+			req:  Request{OS: OSRHEL, Arch: "arm64", Flavor: FlavorLegacy, Version: "< 3.119", BaseURL: "https://mirror.example.com/core/"},
+			want: Plan{
+				Tag:                      "v3.116.0",
+				Version:                  "3.116.0",
+				OS:                       OSRHEL,
+				Arch:                     "aarch64",
+				Flavor:                   FlavorLegacy,
+				Format:                   FormatRPM,
+				Filename:                 "calyptia-core-3.116.0-1.aarch64.rpm",
+				URL:                      "https://mirror.example.com/core/3.116.0/calyptia-core-3.116.0-1.aarch64.rpm",
+				CoreFluentBitImageStatus: ImageUntagged,
+			},
+		},
+		{
+			name: "exact apk",
+			req:  Request{OS: OSAlpine, Arch: "aarch64", Version: "3.110.0"},
+			want: Plan{
+				Tag:                      "v3.110.0",
+				Version:                  "3.110.0",
+				OS:                       OSAlpine,
+				Arch:                     "arm64",
+				Flavor:                   FlavorOperator,
+				Format:                   FormatAPK,
+				Filename:                 "calyptia-core-operator_3.110.0_arm64.apk",
+				URL:                      "https://core-packages.calyptia.com/core/3.110.0/calyptia-core-operator_3.110.0_arm64.apk",
+				CoreFluentBitImageStatus: ImageUnmapped,
+			},
+		},
+		{
+			name:       "yanked",
+			req:        Request{OS: OSDebian, Arch: "amd64", Version: "4.0.0"},
+			wantYanked: true,
+		},
+		{
+			name:    "unknown version",
+			req:     Request{OS: OSDebian, Arch: "amd64", Version: "3.0.0"},
+			wantErr: index.ErrNoMatchingImage,
+		},
+		{
+			name:    "unsupported arch",
+			req:     Request{OS: OSDebian, Arch: "riscv64"},
+			wantErr: ErrUnsupportedArch,
+		},
+		{
+			name:    "unsupported os",
+			req:     Request{OS: "windows", Arch: "amd64"},
+			wantErr: ErrUnsupportedOS,
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := planner.Plan(ctx, tc.req)
+			if tc.wantYanked {
+				var yanked *index.ErrVersionYanked
+				if !errors.As(err, &yanked) {
+					t.Errorf("want: yanked != got: %v", err)
+				}
+				return
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("want: %v != got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %+v != got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPlanner_Plan_mappings(t *testing.T) {
+	ctx := context.Background()
+	req := Request{OS: OSDebian, Arch: "amd64"}
+	fsys := fstest.MapFS{
+		index.OperatorIndexFile: {Data: []byte(`["v3.119.0"]`)},
+	}
+
+	planner := &Planner{Operator: &index.Operator{Fetcher: &index.OperatorIndexFSFetcher{FS: fsys}}}
+	got, err := planner.Plan(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CoreFluentBitImageStatus != ImageUnpublished || got.CoreFluentBitImage != "" {
+		t.Errorf("want: %v != got: %v %q", ImageUnpublished, got.CoreFluentBitImageStatus, got.CoreFluentBitImage)
+	}
+
+	mock := &index.OperatorIndexFetchMock{
+		GetImagesFunc: func(ctx context.Context) (index.OperatorImages, error) {
+			return index.OperatorImages{"v3.119.0"}, nil
+		},
+	}
+	planner = &Planner{Operator: &index.Operator{Fetcher: mock}}
+	got, err = planner.Plan(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CoreFluentBitImageStatus != ImageUnpublished {
+		t.Errorf("want: %v != got: %v", ImageUnpublished, got.CoreFluentBitImageStatus)
+	}
+}
+
+func TestPackageFile(t *testing.T) {
+	tt := []struct {
+		os   OSFamily
+		arch string
+		want string
+	}{
+		{os: OSDebian, arch: "arm64", want: "calyptia-core-operator_3.119.0_arm64.deb"},
+		{os: OSRHEL, arch: "amd64", want: "calyptia-core-operator-3.119.0-1.x86_64.rpm"},
+		{os: OSAlpine, arch: "x86_64", want: "calyptia-core-operator_3.119.0_amd64.apk"},
+	}
+
+	for _, tc := range tt {
+		got, err := PackageFile(tc.os, tc.arch, FlavorOperator, "v3.119.0", DefaultRPMRelease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Errorf("want: %v != got: %v", tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package installtest builds minimal deb, rpm and apk packages for tests,
+// holding only the metadata read by the install package, so that package
+// verification is tested without real packages.
+package installtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+const (
+	arMagic       = "!<arch>\n"
+	rpmLeadSize   = 96
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagArch    = 1022
+	rpmTagSig     = 1004
+	rpmTypeString = 6
+	rpmEntrySize  = 16
+)
+
+var rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+
+// Deb returns an ar archive with a debian-binary member and a gzipped
+// control.tar holding the control file.
+func Deb(tb testing.TB, name, version, arch string) []byte {
+	tb.Helper()
+
+	control := fmt.Sprintf("Package: %s\nVersion: %s\nArchitecture: %s\nDescription: Calyptia Core\n multi-line description\n", name, version, arch)
+	return DebControl(tb, "control.tar.gz", GzipTar(tb, map[string]string{"./control": control}))
+}
+
+// DebControl returns a deb whose control member, such as control.tar.xz,
+// holds data as is.
+func DebControl(tb testing.TB, member string, data []byte) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString(arMagic)
+	for _, m := range []struct {
+		name string
+		data []byte
+	}{
+		{"debian-binary", []byte("2.0\n")},
+		{member, data},
+		{"data.tar.gz", GzipTar(tb, map[string]string{"./usr/bin/calyptia": "binary"})},
+	} {
+		fmt.Fprintf(&buf, "%-16s%-12s%-6s%-6s%-8s%-10d`\n", m.name+"/", "0", "0", "0", "100644", len(m.data))
+		buf.Write(m.data)
+		if len(m.data)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// RPM returns a lead, an empty signature header with its padding and a main
+// header with the name, version and arch tags.
+func RPM(tb testing.TB, name, version, arch string) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, rpmLeadSize))
+	writeRPMHeader(&buf, map[uint32]string{rpmTagSig: "sig"})
+	buf.Write(make([]byte, 4)) // pads the 4 bytes store to 8.
+	writeRPMHeader(&buf, map[uint32]string{
+		rpmTagName:    name,
+		rpmTagVersion: version,
+		rpmTagArch:    arch,
+	})
+	return buf.Bytes()
+}
+
+func writeRPMHeader(buf *bytes.Buffer, tags map[uint32]string) {
+	var entries, store bytes.Buffer
+	for _, tag := range []uint32{rpmTagName, rpmTagVersion, rpmTagArch, rpmTagSig} {
+		value, ok := tags[tag]
+		if !ok {
+			continue
+		}
+		for _, v := range []uint32{tag, rpmTypeString, uint32(store.Len()), 1} {
+			_ = binary.Write(&entries, binary.BigEndian, v)
+		}
+		store.WriteString(value)
+		store.WriteByte(0)
+	}
+	buf.Write(rpmHeaderMagic)
+	buf.Write(make([]byte, 4))
+	_ = binary.Write(buf, binary.BigEndian, uint32(entries.Len()/rpmEntrySize))
+	_ = binary.Write(buf, binary.BigEndian, uint32(store.Len()))
+	buf.Write(entries.Bytes())
+	buf.Write(store.Bytes())
+}
+
+// APK returns the signature, control and data segments of an apk, each a
+// gzip stream.
+func APK(tb testing.TB, name, version, arch string) []byte {
+	tb.Helper()
+
+	pkginfo := fmt.Sprintf("# Generated by abuild\npkgname = %s\npkgver = %s\narch = %s\n", name, version, arch)
+	var buf bytes.Buffer
+	buf.Write(GzipTar(tb, map[string]string{".SIGN.RSA.calyptia.rsa.pub": "signature"}))
+	buf.Write(GzipTar(tb, map[string]string{".PKGINFO": pkginfo}))
+	buf.Write(GzipTar(tb, map[string]string{"usr/bin/calyptia": "binary"}))
+	return buf.Bytes()
+}
+
+// GzipTar returns a gzipped tar archive of files, keyed by name.
+func GzipTar(tb testing.TB, files map[string]string) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// SHA256Hex returns the hex encoded SHA-256 of data, as listed by sha256sum.
+func SHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,283 @@
+package install
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	arMagic       = "!<arch>\n"
+	arHeaderSize  = 60
+	rpmLeadSize   = 96
+	rpmEntrySize  = 16
+	rpmHeaderSize = 16
+	// maxRPMHeader bounds the header store read in memory.
+	maxRPMHeader = 32 << 20
+
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagArch    = 1022
+	rpmTypeString = 6
+)
+
+var (
+	// ErrUnsupportedCompression is returned for debs whose control member is
+	// compressed with xz or zstd, which cannot be read without a dependency.
+	ErrUnsupportedCompression = errors.New("unsupported compression")
+
+	rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+)
+
+// PackageInfo is the metadata of a package file.
+type PackageInfo struct {
+	Name string `json:"name"`
+	// Version is the upstream version, such as 3.119.0, without the epoch
+	// nor the package revision.
+	Version string `json:"version"`
+	// Arch as named by the package format, such as x86_64 or amd64.
+	Arch   string `json:"arch"`
+	Format string `json:"format"`
+}
+
+// ReadPackageInfo reads the metadata of a deb, rpm or apk package.
+func ReadPackageInfo(r io.Reader, format string) (PackageInfo, error) {
+	var (
+		info PackageInfo
+		err  error
+	)
+	switch format {
+	case FormatDeb:
+		info, err = readDebInfo(r)
+	case FormatRPM:
+		info, err = readRPMInfo(r)
+	case FormatAPK:
+		info, err = readAPKInfo(r)
+	default:
+		return PackageInfo{}, fmt.Errorf("unknown package format %q", format)
+	}
+	if err != nil {
+		return PackageInfo{}, fmt.Errorf("invalid %s package: %w", format, err)
+	}
+	info.Format = format
+	return info, nil
+}
+
+// readDebInfo reads the control file of a deb, an ar archive holding a
+// control.tar, control.tar.gz or, reported with ErrUnsupportedCompression,
+// control.tar.xz or control.tar.zst member.
+func readDebInfo(r io.Reader) (PackageInfo, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != arMagic {
+		return PackageInfo{}, errors.New("not an ar archive")
+	}
+
+	header := make([]byte, arHeaderSize)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			return PackageInfo{}, errors.New("control member not found")
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(string(header[:16])), "/")
+		var size int64
+		if _, err := fmt.Sscan(strings.TrimSpace(string(header[48:58])), &size); err != nil || size < 0 {
+			return PackageInfo{}, fmt.Errorf("invalid size of member %s", name)
+		}
+		member := io.LimitReader(br, size)
+
+		if strings.HasPrefix(name, "control.tar") {
+			return readDebControl(member, name)
+		}
+		// Members are aligned on 2 bytes.
+		if _, err := io.CopyN(io.Discard, br, size+size%2); err != nil {
+			return PackageInfo{}, err
+		}
+	}
+}
+
+func readDebControl(member io.Reader, name string) (PackageInfo, error) {
+	switch path.Ext(name) {
+	case ".tar":
+	case ".gz":
+		zr, err := gzip.NewReader(member)
+		if err != nil {
+			return PackageInfo{}, err
+		}
+		defer zr.Close()
+		member = zr
+	default:
+		return PackageInfo{}, fmt.Errorf("%w of %s", ErrUnsupportedCompression, name)
+	}
+
+	control, err := tarFile(tar.NewReader(member), "control")
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	fields := parseFields(control, ":")
+	info := PackageInfo{
+		Name:    fields["Package"],
+		Version: upstreamVersion(fields["Version"]),
+		Arch:    fields["Architecture"],
+	}
+	return info, nil
+}
+
+// readRPMInfo reads the main header of an rpm, following the lead and the
+// signature header.
+func readRPMInfo(r io.Reader) (PackageInfo, error) {
+	br := bufio.NewReader(r)
+	if _, err := io.CopyN(io.Discard, br, rpmLeadSize); err != nil {
+		return PackageInfo{}, errors.New("truncated lead")
+	}
+
+	// The signature header is padded to a multiple of 8 bytes.
+	_, storeSize, err := readRPMHeader(br, nil)
+	if err != nil {
+		return PackageInfo{}, fmt.Errorf("signature: %w", err)
+	}
+	if pad := (8 - storeSize%8) % 8; pad > 0 {
+		if _, err := io.CopyN(io.Discard, br, int64(pad)); err != nil {
+			return PackageInfo{}, err
+		}
+	}
+
+	tags, _, err := readRPMHeader(br, []uint32{rpmTagName, rpmTagVersion, rpmTagArch})
+	if err != nil {
+		return PackageInfo{}, fmt.Errorf("header: %w", err)
+	}
+	return PackageInfo{
+		Name:    tags[rpmTagName],
+		Version: tags[rpmTagVersion],
+		Arch:    tags[rpmTagArch],
+	}, nil
+}
+
+// readRPMHeader reads a header structure and returns the string values of
+// wanted, with the size of its store.
+func readRPMHeader(r io.Reader, wanted []uint32) (map[uint32]string, uint32, error) {
+	intro := make([]byte, rpmHeaderSize)
+	if _, err := io.ReadFull(r, intro); err != nil {
+		return nil, 0, err
+	}
+	if !bytes.Equal(intro[:4], rpmHeaderMagic) {
+		return nil, 0, errors.New("bad header magic")
+	}
+	count := binary.BigEndian.Uint32(intro[8:12])
+	storeSize := binary.BigEndian.Uint32(intro[12:16])
+	if int64(count)*rpmEntrySize+int64(storeSize) > maxRPMHeader {
+		return nil, 0, errors.New("header too large")
+	}
+
+	entries := make([]byte, int(count)*rpmEntrySize)
+	if _, err := io.ReadFull(r, entries); err != nil {
+		return nil, 0, err
+	}
+	store := make([]byte, storeSize)
+	if _, err := io.ReadFull(r, store); err != nil {
+		return nil, 0, err
+	}
+
+	out := map[uint32]string{}
+	for i := 0; i < len(entries); i += rpmEntrySize {
+		tag := binary.BigEndian.Uint32(entries[i:])
+		typ := binary.BigEndian.Uint32(entries[i+4:])
+		offset := binary.BigEndian.Uint32(entries[i+8:])
+		if typ != rpmTypeString || offset >= storeSize || !slices.Contains(wanted, tag) {
+			continue
+		}
+		value := store[offset:]
+		if end := bytes.IndexByte(value, 0); end >= 0 {
+			value = value[:end]
+		}
+		out[tag] = string(value)
+	}
+	return out, storeSize, nil
+}
+
+// readAPKInfo reads the .PKGINFO of an apk, a concatenation of gzip streams
+// for the signature, the control and the data tar segments.
+func readAPKInfo(r io.Reader) (PackageInfo, error) {
+	// The buffered reader is a ByteReader, so gzip does not read past the
+	// end of each stream.
+	br := bufio.NewReader(r)
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	defer zr.Close()
+
+	for {
+		zr.Multistream(false)
+		pkginfo, err := tarFile(tar.NewReader(zr), ".PKGINFO")
+		if err == nil {
+			fields := parseFields(pkginfo, "=")
+			return PackageInfo{
+				Name:    fields["pkgname"],
+				Version: upstreamVersion(fields["pkgver"]),
+				Arch:    fields["arch"],
+			}, nil
+		}
+
+		// Skip the rest of the segment and move to the next stream.
+		if _, err := io.Copy(io.Discard, zr); err != nil {
+			return PackageInfo{}, err
+		}
+		if err := zr.Reset(br); err != nil {
+			return PackageInfo{}, errors.New(".PKGINFO not found")
+		}
+	}
+}
+
+// tarFile returns the content of the file name, with or without a leading
+// ./, of a tar archive.
+func tarFile(tr *tar.Reader, name string) ([]byte, error) {
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		if strings.TrimPrefix(hdr.Name, "./") == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// parseFields parses the key and value lines of control files, such as
+// "Package: name" or "pkgname = name". Continuation lines are ignored.
+func parseFields(data []byte, sep string) map[string]string {
+	out := map[string]string{}
+	for line := range strings.SplitSeq(string(data), "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, dup := out[key]; !dup {
+			out[key] = strings.TrimSpace(value)
+		}
+	}
+	return out
+}
+
+// upstreamVersion drops the epoch and the package revision of a deb or apk
+// version, such as 1:3.119.0-1 or 3.119.0-r0.
+func upstreamVersion(version string) string {
+	if _, v, ok := strings.Cut(version, ":"); ok {
+		version = v
+	}
+	if i := strings.LastIndex(version, "-"); i >= 0 {
+		version = version[:i]
+	}
+	return version
+}
@@ -0,0 +1,103 @@
+package install
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calyptia/core-images-index/go-index/install/installtest"
+)
+
+func TestReadPackageInfo(t *testing.T) {
+	tt := []struct {
+		name    string
+		format  string
+		data    []byte
+		want    PackageInfo
+		wantErr string
+	}{
+		{
+			name:   "deb",
+			format: FormatDeb,
+			data:   installtest.Deb(t, "calyptia-core-operator", "3.119.0-1", "amd64"),
+			want:   PackageInfo{Name: "calyptia-core-operator", Version: "3.119.0", Arch: "amd64", Format: FormatDeb},
+		},
+		{
+			name:   "rpm",
+			format: FormatRPM,
+			data:   installtest.RPM(t, "calyptia-core-operator", "3.119.0", "aarch64"),
+			want:   PackageInfo{Name: "calyptia-core-operator", Version: "3.119.0", Arch: "aarch64", Format: FormatRPM},
+		},
+		{
+			name:   "apk",
+			format: FormatAPK,
+			data:   installtest.APK(t, "calyptia-core", "3.119.0-r0", "x86_64"),
+			want:   PackageInfo{Name: "calyptia-core", Version: "3.119.0", Arch: "x86_64", Format: FormatAPK},
+		},
+		{
+			name:    "not a deb",
+			format:  FormatDeb,
+			data:    []byte("nope"),
+			wantErr: "invalid deb package: not an ar archive",
+		},
+		{
+			name:    "deb with zstd control",
+			format:  FormatDeb,
+			data:    installtest.DebControl(t, "control.tar.zst", []byte("zstd")),
+			wantErr: "invalid deb package: unsupported compression of control.tar.zst",
+		},
+		{
+			name:    "truncated rpm",
+			format:  FormatRPM,
+			data:    installtest.RPM(t, "calyptia-core", "3.119.0", "x86_64")[:150],
+			wantErr: "invalid rpm package: header",
+		},
+		{
+			name:    "apk without pkginfo",
+			format:  FormatAPK,
+			data:    installtest.GzipTar(t, map[string]string{"usr/bin/calyptia": "binary"}),
+			wantErr: "invalid apk package: .PKGINFO not found",
+		},
+		{
+			name:    "unknown format",
+			format:  "msi",
+			wantErr: `unknown package format "msi"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadPackageInfo(bytes.NewReader(tc.data), tc.format)
+			if tc.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+					t.Fatalf("want error: %q != got: %v", tc.wantErr, err)
+				}
+				if strings.Contains(tc.wantErr, "compression") && !errors.Is(err, ErrUnsupportedCompression) {
+					t.Fatalf("want: %v != got: %v", ErrUnsupportedCompression, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("want: %+v != got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUpstreamVersion(t *testing.T) {
+	tt := map[string]string{
+		"3.119.0":     "3.119.0",
+		"3.119.0-1":   "3.119.0",
+		"1:3.119.0-2": "3.119.0",
+		"3.119.0-r0":  "3.119.0",
+	}
+	for version, want := range tt {
+		if got := upstreamVersion(version); got != want {
+			t.Fatalf("%s: want: %s != got: %s", version, want, got)
+		}
+	}
+}
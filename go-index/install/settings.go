@@ -0,0 +1,417 @@
+package install
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/clusternet"
+)
+
+const (
+	// SettingsFile is the path of the settings below the installation
+	// directory, read by the package install and uninstall scripts.
+	SettingsFile = ".install/settings.conf"
+	// EnvPrefix prefixes the variables overriding the default settings.
+	EnvPrefix = "INSTALL_CALYPTIA_"
+
+	KeyNoPreflightChecks    = "NO_PREFLIGHT_CHECKS"
+	KeyIgnoreErrors         = "IGNORE_ERRORS"
+	KeyProvisionedUser      = "PROVISIONED_USER"
+	KeyProvisionedGroup     = "PROVISIONED_GROUP"
+	KeyClusterCIDR          = "CLUSTER_CIDR"
+	KeyServiceCIDR          = "SERVICE_CIDR"
+	KeyClusterDNS           = "CLUSTER_DNS"
+	KeyServiceNodePortRange = "SERVICE_NODE_PORT_RANGE"
+	KeyClusterDomain        = "CLUSTER_DOMAIN"
+
+	DefaultClusterCIDR   = "10.42.0.0/16"
+	DefaultServiceCIDR   = "10.43.0.0/16"
+	DefaultClusterDNS    = "10.43.0.10"
+	DefaultNodePortRange = "30000-32767"
+	DefaultClusterDomain = "cluster.local"
+
+	settingsPerm    = 0o644
+	settingsDirPerm = 0o755
+	maxDomainLength = 253
+)
+
+var (
+	// settingKeys are the keys of the settings in the order written by
+	// install-core.sh.
+	settingKeys = []string{
+		KeyNoPreflightChecks,
+		KeyIgnoreErrors,
+		KeyProvisionedUser,
+		KeyProvisionedGroup,
+		KeyClusterCIDR,
+		KeyServiceCIDR,
+		KeyClusterDNS,
+		KeyServiceNodePortRange,
+		KeyClusterDomain,
+	}
+
+	// networkKeys maps the fields of the network diagnostics to the keys.
+	networkKeys = map[string]string{
+		clusternet.FieldClusterCIDR:   KeyClusterCIDR,
+		clusternet.FieldServiceCIDR:   KeyServiceCIDR,
+		clusternet.FieldClusterDNS:    KeyClusterDNS,
+		clusternet.FieldNodePortRange: KeyServiceNodePortRange,
+	}
+
+	settingLine = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	domainLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+)
+
+type (
+	// Settings are the installation settings of settings.conf. Booleans are
+	// written yes or no and, as in install-core.sh, any value other than no
+	// is true.
+	Settings struct {
+		NoPreflightChecks    bool
+		IgnoreErrors         bool
+		ProvisionedUser      string
+		ProvisionedGroup     string
+		ClusterCIDR          string
+		ServiceCIDR          string
+		ClusterDNS           string
+		ServiceNodePortRange string
+		ClusterDomain        string
+		// Extra holds the unknown keys, written back after the known ones.
+		Extra map[string]string
+	}
+
+	// SettingError is a setting with an invalid value.
+	SettingError struct {
+		Key     string
+		Value   string
+		Message string
+	}
+
+	// SettingChange is a setting whose value differs between two settings.
+	SettingChange struct {
+		Key string `json:"key"`
+		Old string `json:"old"`
+		New string `json:"new"`
+	}
+
+	// AccountLookup checks that users and groups exist on the host.
+	//go:generate moq -out account_lookup_mock.go . AccountLookup
+	AccountLookup interface {
+		LookupUser(name string) error
+		LookupGroup(name string) error
+	}
+
+	// OSAccountLookup looks the accounts of the local machine up.
+	OSAccountLookup struct{}
+)
+
+func (e *SettingError) Error() string {
+	return fmt.Sprintf("%s=%s: %s", e.Key, e.Value, e.Message)
+}
+
+func (OSAccountLookup) LookupUser(name string) error {
+	_, err := user.Lookup(name)
+	return err
+}
+
+func (OSAccountLookup) LookupGroup(name string) error {
+	_, err := user.LookupGroup(name)
+	return err
+}
+
+// DefaultSettings returns the settings written by install-core.sh without
+// any override. The user and group default to the invoking ones and are left
+// empty.
+func DefaultSettings() Settings {
+	return Settings{
+		NoPreflightChecks:    true,
+		ClusterCIDR:          DefaultClusterCIDR,
+		ServiceCIDR:          DefaultServiceCIDR,
+		ClusterDNS:           DefaultClusterDNS,
+		ServiceNodePortRange: DefaultNodePortRange,
+		ClusterDomain:        DefaultClusterDomain,
+	}
+}
+
+// SettingsPath returns the path of the settings of an installation in
+// coreDir, such as /opt/calyptia.
+func SettingsPath(coreDir string) string {
+	return path.Join(coreDir, SettingsFile)
+}
+
+// ParseSettings reads the KEY=value lines of a settings file. Blank lines and
+// comments are skipped, quoted values are unquoted.
+func ParseSettings(r io.Reader) (Settings, error) {
+	var s Settings
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := settingLine.FindStringSubmatch(line)
+		if m == nil {
+			return Settings{}, fmt.Errorf("line %d: expected KEY=value, got %q", n, line)
+		}
+		value := m[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		s.Set(m[1], value)
+	}
+	return s, scanner.Err()
+}
+
+// LoadEnv overrides the settings with the INSTALL_CALYPTIA_ variables found
+// by lookup, such as os.LookupEnv, following the precedence of
+// install-core.sh: command line arguments, then variables, then defaults.
+// Empty variables keep the default, like ${VAR:-default} in the script.
+// NO_PREFLIGHT_CHECKS is not configurable.
+func (s *Settings) LoadEnv(lookup func(string) (string, bool)) {
+	for _, key := range settingKeys {
+		if key == KeyNoPreflightChecks {
+			continue
+		}
+		if value, ok := lookup(EnvPrefix + key); ok && value != "" {
+			s.Set(key, value)
+		}
+	}
+}
+
+// Get returns the value of key as written in the file, empty when unset.
+func (s Settings) Get(key string) string {
+	switch key {
+	case KeyNoPreflightChecks:
+		return yesNo(s.NoPreflightChecks)
+	case KeyIgnoreErrors:
+		return yesNo(s.IgnoreErrors)
+	case KeyProvisionedUser:
+		return s.ProvisionedUser
+	case KeyProvisionedGroup:
+		return s.ProvisionedGroup
+	case KeyClusterCIDR:
+		return s.ClusterCIDR
+	case KeyServiceCIDR:
+		return s.ServiceCIDR
+	case KeyClusterDNS:
+		return s.ClusterDNS
+	case KeyServiceNodePortRange:
+		return s.ServiceNodePortRange
+	case KeyClusterDomain:
+		return s.ClusterDomain
+	default:
+		return s.Extra[key]
+	}
+}
+
+// Set sets key to value as read from the file.
+func (s *Settings) Set(key, value string) {
+	switch key {
+	case KeyNoPreflightChecks:
+		s.NoPreflightChecks = value != "no"
+	case KeyIgnoreErrors:
+		s.IgnoreErrors = value != "no"
+	case KeyProvisionedUser:
+		s.ProvisionedUser = value
+	case KeyProvisionedGroup:
+		s.ProvisionedGroup = value
+	case KeyClusterCIDR:
+		s.ClusterCIDR = value
+	case KeyServiceCIDR:
+		s.ServiceCIDR = value
+	case KeyClusterDNS:
+		s.ClusterDNS = value
+	case KeyServiceNodePortRange:
+		s.ServiceNodePortRange = value
+	case KeyClusterDomain:
+		s.ClusterDomain = value
+	default:
+		if s.Extra == nil {
+			s.Extra = map[string]string{}
+		}
+		s.Extra[key] = value
+	}
+}
+
+// keys returns the known keys in file order followed by the extra keys in
+// lexical order.
+func (s Settings) keys() []string {
+	out := append([]string{}, settingKeys...)
+	return append(out, slices.Sorted(maps.Keys(s.Extra))...)
+}
+
+// MarshalSettings encodes s as KEY=value lines, in the order written by
+// install-core.sh.
+func MarshalSettings(s Settings) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, key := range s.keys() {
+		value := s.Get(key)
+		if strings.ContainsAny(value, "\n\r") {
+			return nil, fmt.Errorf("%s: value cannot span several lines", key)
+		}
+		fmt.Fprintf(&buf, "%s=%s\n", key, value)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteSettings atomically writes s to name, creating its directory, such as
+// .install, if needed. Like install-core.sh, an existing file is kept, failing
+// with fs.ErrExist, unless force is set, and the directory and file are owned
+// by the provisioned user and group when set.
+func WriteSettings(name string, s Settings, force bool) error {
+	data, err := MarshalSettings(s)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(name); err == nil && !force {
+		return fmt.Errorf("%s: %w", name, fs.ErrExist)
+	}
+	uid, gid, err := s.owner()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, settingsDirPerm); err != nil {
+		return err
+	}
+	if err := index.WriteFileAtomic(name, data, settingsPerm); err != nil {
+		return err
+	}
+	if uid < 0 {
+		return nil
+	}
+	for _, p := range []string{dir, name} {
+		if err := os.Chown(p, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// owner returns the ids of the provisioned user and group, -1 when no user is
+// set. The group defaults to the primary group of the user, like chown user:.
+func (s Settings) owner() (uid, gid int, err error) {
+	if s.ProvisionedUser == "" {
+		return -1, -1, nil
+	}
+	u, err := user.Lookup(s.ProvisionedUser)
+	if err != nil {
+		return -1, -1, err
+	}
+	groupID := u.Gid
+	if s.ProvisionedGroup != "" {
+		g, err := user.LookupGroup(s.ProvisionedGroup)
+		if err != nil {
+			return -1, -1, err
+		}
+		groupID = g.Gid
+	}
+	if uid, err = strconv.Atoi(u.Uid); err != nil {
+		return -1, -1, fmt.Errorf("user %s: %w", s.ProvisionedUser, err)
+	}
+	if gid, err = strconv.Atoi(groupID); err != nil {
+		return -1, -1, fmt.Errorf("group %s: %w", s.ProvisionedGroup, err)
+	}
+	return uid, gid, nil
+}
+
+// Validate checks the network settings and, when lookup is set, that the
+// user and group exist. Every invalid setting is reported as a
+// *SettingError.
+func (s Settings) Validate(lookup AccountLookup) error {
+	var errs []error
+	report := func(key, format string, args ...any) {
+		errs = append(errs, &SettingError{Key: key, Value: s.Get(key), Message: fmt.Sprintf(format, args...)})
+	}
+
+	plan := clusternet.Plan{
+		ClusterCIDR:   s.ClusterCIDR,
+		ServiceCIDR:   s.ServiceCIDR,
+		ClusterDNS:    s.ClusterDNS,
+		NodePortRange: s.ServiceNodePortRange,
+	}
+	if plan.NodePortRange == "" {
+		report(KeyServiceNodePortRange, "missing port range")
+	}
+	for _, d := range clusternet.Validate(plan, clusternet.HostNetwork{}) {
+		if d.Severity == clusternet.SeverityError {
+			report(networkKeys[d.Field], "%s", d.Message)
+		}
+	}
+
+	if err := validateDomain(s.ClusterDomain); err != nil {
+		report(KeyClusterDomain, "%v", err)
+	}
+
+	if lookup != nil {
+		if s.ProvisionedUser == "" {
+			report(KeyProvisionedUser, "missing user")
+		} else if err := lookup.LookupUser(s.ProvisionedUser); err != nil {
+			report(KeyProvisionedUser, "user not found: %v", err)
+		}
+		if s.ProvisionedGroup == "" {
+			report(KeyProvisionedGroup, "missing group")
+		} else if err := lookup.LookupGroup(s.ProvisionedGroup); err != nil {
+			report(KeyProvisionedGroup, "group not found: %v", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateDomain checks that domain is a DNS name, such as cluster.local.
+func validateDomain(domain string) error {
+	if domain == "" {
+		return errors.New("missing cluster domain")
+	}
+	if len(domain) > maxDomainLength {
+		return errors.New("domain name too long")
+	}
+	for label := range strings.SplitSeq(domain, ".") {
+		if !domainLabel.MatchString(label) {
+			return fmt.Errorf("invalid domain label %q", label)
+		}
+	}
+	return nil
+}
+
+// DiffSettings returns the settings whose value differs from old to updated,
+// in file order.
+func DiffSettings(old, updated Settings) []SettingChange {
+	keys := old.keys()
+	for _, key := range slices.Sorted(maps.Keys(updated.Extra)) {
+		if _, ok := old.Extra[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var out []SettingChange
+	for _, key := range keys {
+		if o, n := old.Get(key), updated.Get(key); o != n {
+			out = append(out, SettingChange{Key: key, Old: o, New: n})
+		}
+	}
+	return out
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,187 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+const installedSettings = `NO_PREFLIGHT_CHECKS=yes
+IGNORE_ERRORS=no
+PROVISIONED_USER=calyptia
+PROVISIONED_GROUP=calyptia
+CLUSTER_CIDR=10.42.0.0/16
+SERVICE_CIDR=10.43.0.0/16
+CLUSTER_DNS=10.43.0.10
+SERVICE_NODE_PORT_RANGE=30000-32767
+CLUSTER_DOMAIN=cluster.local
+`
+
+func TestParseSettings(t *testing.T) {
+	got, err := ParseSettings(strings.NewReader("# written by hand\n\n" + installedSettings + `CUSTOM="a b"` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultSettings()
+	want.ProvisionedUser, want.ProvisionedGroup = "calyptia", "calyptia"
+	want.Extra = map[string]string{"CUSTOM": "a b"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v != got: %+v", want, got)
+	}
+
+	if _, err := ParseSettings(strings.NewReader("CLUSTER_CIDR\n")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestWriteSettings(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	group, err := user.LookupGroupId(current.Gid)
+	if err != nil {
+		t.Skip(err)
+	}
+	s := DefaultSettings()
+	s.ProvisionedUser, s.ProvisionedGroup = current.Username, group.Name
+
+	name := SettingsPath(t.TempDir())
+	if err := WriteSettings(name, s, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer("PROVISIONED_USER=calyptia", "PROVISIONED_USER="+current.Username,
+		"PROVISIONED_GROUP=calyptia", "PROVISIONED_GROUP="+group.Name).Replace(installedSettings)
+	if want != string(got) {
+		t.Errorf("want: %v != got: %v", want, string(got))
+	}
+	for _, p := range []string{name, filepath.Dir(name)} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			if want, got := current.Uid+":"+current.Gid, fmt.Sprintf("%d:%d", st.Uid, st.Gid); want != got {
+				t.Errorf("want: %v != got: %v", want, got)
+			}
+		}
+	}
+
+	// The existing settings are kept unless forced.
+	s.ClusterDomain = "example.local"
+	if err := WriteSettings(name, s, false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("want: %v != got: %v", fs.ErrExist, err)
+	}
+	if err := WriteSettings(name, s, true); err != nil {
+		t.Fatal(err)
+	}
+
+	s.ClusterDomain = "cluster\nlocal"
+	if err := WriteSettings(name, s, true); err == nil {
+		t.Error("expected an error for a multi-line value")
+	}
+
+	s.ClusterDomain = DefaultClusterDomain
+	s.ProvisionedUser = "calyptia-unknown-user"
+	if err := WriteSettings(SettingsPath(t.TempDir()), s, false); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
+
+func TestSettings_LoadEnv(t *testing.T) {
+	env := map[string]string{
+		"INSTALL_CALYPTIA_IGNORE_ERRORS":       "yes",
+		"INSTALL_CALYPTIA_CLUSTER_CIDR":        "10.52.0.0/16",
+		"INSTALL_CALYPTIA_SERVICE_CIDR":        "",
+		"INSTALL_CALYPTIA_NO_PREFLIGHT_CHECKS": "no",
+		"CLUSTER_DNS":                          "10.43.0.53",
+	}
+	s := DefaultSettings()
+	s.LoadEnv(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+
+	want := DefaultSettings()
+	want.IgnoreErrors = true
+	want.ClusterCIDR = "10.52.0.0/16"
+	if !reflect.DeepEqual(want, s) {
+		t.Errorf("want: %+v != got: %+v", want, s)
+	}
+}
+
+func TestSettings_Validate(t *testing.T) {
+	lookup := &AccountLookupMock{
+		LookupUserFunc: func(name string) error {
+			if name != "calyptia" {
+				return errors.New("unknown user")
+			}
+			return nil
+		},
+		LookupGroupFunc: func(name string) error { return nil },
+	}
+
+	s := DefaultSettings()
+	s.ProvisionedUser, s.ProvisionedGroup = "calyptia", "calyptia"
+	if err := s.Validate(lookup); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	s.ProvisionedUser = "nobody"
+	s.ProvisionedGroup = ""
+	s.ServiceCIDR = "10.42.128.0/17"
+	s.ClusterDNS = "10.43.0.10"
+	s.ServiceNodePortRange = "32767-30000"
+	s.ClusterDomain = "Cluster.local"
+	err := s.Validate(lookup)
+
+	var got []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var settingErr *SettingError
+		if !errors.As(err, &settingErr) {
+			t.Fatalf("unexpected error %v", err)
+		}
+		got = append(got, settingErr.Key)
+	}
+	want := []string{KeyServiceCIDR, KeyClusterDNS, KeyServiceNodePortRange, KeyClusterDomain, KeyProvisionedUser, KeyProvisionedGroup}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v (%v)", want, got, err)
+	}
+
+	// Accounts are not checked without a lookup.
+	s = DefaultSettings()
+	if err := s.Validate(nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDiffSettings(t *testing.T) {
+	old := DefaultSettings()
+	old.Extra = map[string]string{"A": "1", "B": "2"}
+	updated := DefaultSettings()
+	updated.IgnoreErrors = true
+	updated.ClusterDNS = "10.43.0.53"
+	updated.Extra = map[string]string{"B": "2", "C": "3"}
+
+	got := DiffSettings(old, updated)
+	want := []SettingChange{
+		{Key: KeyIgnoreErrors, Old: "no", New: "yes"},
+		{Key: KeyClusterDNS, Old: "10.43.0.10", New: "10.43.0.53"},
+		{Key: "A", Old: "1", New: ""},
+		{Key: "C", Old: "", New: "3"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v != got: %+v", want, got)
+	}
+}
@@ -0,0 +1,219 @@
+package install
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+var (
+	// ErrPackageMismatch is returned when the metadata of a package differs
+	// from the requested one.
+	ErrPackageMismatch = errors.New("package mismatch")
+	// ErrChecksumMismatch is returned when a package differs from the
+	// checksum manifest.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+type (
+	// VerifyRequest describes the packages expected in a local package
+	// directory.
+	VerifyRequest struct {
+		// OS families whose packages are expected, all of them when empty.
+		OS []OSFamily
+		// Arch such as amd64 or arm64, x86_64 and aarch64 are accepted too.
+		Arch   string
+		Flavor Flavor
+		// Version of the packages, such as 3.119.0.
+		Version    string
+		RPMRelease string
+		// Checksums is the manifest within the directory, defaults to
+		// index.ChecksumsFile. A missing manifest is only reported when
+		// RequireChecksums is set.
+		Checksums        string
+		RequireChecksums bool
+	}
+
+	// PackageCheck is the verification of a package file.
+	PackageCheck struct {
+		OS       OSFamily     `json:"os"`
+		Filename string       `json:"filename"`
+		Found    bool         `json:"found"`
+		Info     *PackageInfo `json:"info,omitempty"`
+		SHA256   string       `json:"sha256,omitempty"`
+		Problems []string     `json:"problems,omitempty"`
+		// Unverified explains why the metadata of a package that is not
+		// invalid could not be read, such as ErrUnsupportedCompression.
+		Unverified string `json:"unverified,omitempty"`
+	}
+
+	// VerifyReport lists the checks of every expected package.
+	VerifyReport struct {
+		// Checksums is the manifest used, empty when there is none.
+		Checksums string         `json:"checksums,omitempty"`
+		Packages  []PackageCheck `json:"packages"`
+		Problems  []string       `json:"problems,omitempty"`
+	}
+)
+
+// OK reports whether every package is found and valid. Unverified packages
+// are not problems.
+func (r VerifyReport) OK() bool {
+	return r.Err() == nil
+}
+
+// Err joins the problems of the report, nil when there is none.
+func (r VerifyReport) Err() error {
+	var errs []error
+	for _, p := range r.Problems {
+		errs = append(errs, errors.New(p))
+	}
+	for _, pkg := range r.Packages {
+		for _, p := range pkg.Problems {
+			errs = append(errs, fmt.Errorf("%s: %s", pkg.Filename, p))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// VerifyPackages checks that the directory fsys holds the packages of req,
+// that their metadata match the requested name, version and arch and, when a
+// checksum manifest is present, that their checksums match. Problems are
+// reported in the VerifyReport, an error is returned for invalid requests.
+func VerifyPackages(fsys fs.FS, req VerifyRequest) (VerifyReport, error) {
+	if len(req.OS) == 0 {
+		req.OS = []OSFamily{OSDebian, OSRHEL, OSAlpine}
+	}
+	if req.Flavor == "" {
+		req.Flavor = FlavorOperator
+	}
+	if req.RPMRelease == "" {
+		req.RPMRelease = DefaultRPMRelease
+	}
+	if req.Checksums == "" {
+		req.Checksums = index.ChecksumsFile
+	}
+
+	report := VerifyReport{Packages: []PackageCheck{}}
+	sums, err := readChecksums(fsys, req.Checksums)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && req.RequireChecksums:
+		report.Problems = append(report.Problems, "missing checksum manifest "+req.Checksums)
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		report.Problems = append(report.Problems, err.Error())
+	default:
+		report.Checksums = req.Checksums
+	}
+
+	for _, os := range req.OS {
+		name, err := PackageFile(os, req.Arch, req.Flavor, req.Version, req.RPMRelease)
+		if err != nil {
+			return VerifyReport{}, err
+		}
+		check := verifyPackageFile(fsys, os, name, req)
+		if sums != nil && check.Found {
+			switch want, ok := sums[name]; {
+			case !ok:
+				check.Problems = append(check.Problems, "not listed in "+req.Checksums)
+			case want != check.SHA256:
+				check.Problems = append(check.Problems, fmt.Sprintf("%v: want sha256 %s, got %s", ErrChecksumMismatch, want, check.SHA256))
+			}
+		}
+		report.Packages = append(report.Packages, check)
+	}
+	return report, nil
+}
+
+func verifyPackageFile(fsys fs.FS, os OSFamily, name string, req VerifyRequest) PackageCheck {
+	check := PackageCheck{OS: os, Filename: name}
+	f, err := fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		check.Problems = []string{"missing package"}
+		return check
+	}
+	if err != nil {
+		check.Problems = []string{err.Error()}
+		return check
+	}
+	defer f.Close()
+	check.Found = true
+
+	// Hash the whole file while the metadata are read from its start.
+	h := sha256.New()
+	info, err := VerifyPackage(io.TeeReader(f, h), os, req.Flavor, req.Version, req.Arch)
+	switch {
+	case errors.Is(err, ErrUnsupportedCompression):
+		check.Unverified = err.Error()
+	case err != nil:
+		check.Problems = append(check.Problems, err.Error())
+	}
+	if info.Format != "" {
+		check.Info = &info
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		check.Problems = append(check.Problems, err.Error())
+		return check
+	}
+	check.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return check
+}
+
+// VerifyPackage reads the metadata of the package of the OS family from r and
+// checks they match flavor, version and arch. The metadata are returned even
+// on a mismatch.
+func VerifyPackage(r io.Reader, os OSFamily, flavor Flavor, version, arch string) (PackageInfo, error) {
+	format, err := os.Format()
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	wantArch, err := NormalizeArch(arch)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	info, err := ReadPackageInfo(r, format)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+
+	var mismatches []string
+	if info.Name != string(flavor) {
+		mismatches = append(mismatches, fmt.Sprintf("name %q, want %q", info.Name, flavor))
+	}
+	if want := strings.TrimPrefix(version, "v"); info.Version != want {
+		mismatches = append(mismatches, fmt.Sprintf("version %q, want %q", info.Version, want))
+	}
+	if got, err := NormalizeArch(info.Arch); err != nil || got != wantArch {
+		mismatches = append(mismatches, fmt.Sprintf("arch %q, want %q", info.Arch, wantArch))
+	}
+	if mismatches != nil {
+		return info, fmt.Errorf("%w: %s", ErrPackageMismatch, strings.Join(mismatches, ", "))
+	}
+	return info, nil
+}
+
+// readChecksums reads a manifest in the format of sha256sum, keyed by the
+// base name of the files.
+func readChecksums(fsys fs.FS, name string) (map[string]string, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := index.ParseChecksums(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	out := map[string]string{}
+	for file, digest := range sums {
+		out[path.Base(file)] = hex.EncodeToString(digest)
+	}
+	return out, nil
+}
@@ -0,0 +1,130 @@
+package install
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/install/installtest"
+)
+
+func TestVerifyPackages(t *testing.T) {
+	deb := installtest.Deb(t, "calyptia-core-operator", "3.119.0", "amd64")
+	rpm := installtest.RPM(t, "calyptia-core-operator", "3.119.0", "x86_64")
+	apk := installtest.APK(t, "calyptia-core-operator", "3.119.0-r0", "x86_64")
+	const (
+		debFile = "calyptia-core-operator_3.119.0_amd64.deb"
+		rpmFile = "calyptia-core-operator-3.119.0-1.x86_64.rpm"
+		apkFile = "calyptia-core-operator_3.119.0_amd64.apk"
+	)
+	sums := sha256Line(deb, "./"+debFile) + sha256Line(rpm, "*"+rpmFile) + sha256Line(apk, apkFile)
+	req := VerifyRequest{Arch: "x86_64", Version: "v3.119.0"}
+
+	tt := []struct {
+		name string
+		fsys fstest.MapFS
+		req  VerifyRequest
+		// want maps the file names to their problems.
+		want         map[string][]string
+		wantProblems []string
+	}{
+		{
+			name: "complete with checksums",
+			fsys: fstest.MapFS{debFile: {Data: deb}, rpmFile: {Data: rpm}, apkFile: {Data: apk}, index.ChecksumsFile: {Data: []byte(sums)}},
+			req:  req,
+			want: map[string][]string{debFile: nil, rpmFile: nil, apkFile: nil},
+		},
+		{
+			name: "without manifest",
+			fsys: fstest.MapFS{debFile: {Data: deb}},
+			req:  VerifyRequest{OS: []OSFamily{OSDebian}, Arch: "amd64", Version: "3.119.0"},
+			want: map[string][]string{debFile: nil},
+		},
+		{
+			name:         "required manifest",
+			fsys:         fstest.MapFS{debFile: {Data: deb}},
+			req:          VerifyRequest{OS: []OSFamily{OSDebian}, Arch: "amd64", Version: "3.119.0", RequireChecksums: true},
+			want:         map[string][]string{debFile: nil},
+			wantProblems: []string{"missing checksum manifest SHA256SUMS"},
+		},
+		{
+			name: "missing and mismatching",
+			fsys: fstest.MapFS{
+				debFile:             {Data: installtest.Deb(t, "calyptia-core", "3.118.0", "arm64")},
+				apkFile:             {Data: apk},
+				index.ChecksumsFile: {Data: []byte(sha256Line([]byte("other"), apkFile))},
+			},
+			req: req,
+			want: map[string][]string{
+				debFile: {
+					`package mismatch: name "calyptia-core", want "calyptia-core-operator", version "3.118.0", want "3.119.0", arch "arm64", want "amd64"`,
+					"not listed in SHA256SUMS",
+				},
+				rpmFile: {"missing package"},
+				apkFile: {"checksum mismatch: want sha256 " + installtest.SHA256Hex([]byte("other")) + ", got " + installtest.SHA256Hex(apk)},
+			},
+		},
+		{
+			name: "invalid package",
+			fsys: fstest.MapFS{rpmFile: {Data: []byte("nope")}},
+			req:  VerifyRequest{OS: []OSFamily{OSRHEL}, Arch: "amd64", Version: "3.119.0"},
+			want: map[string][]string{rpmFile: {"invalid rpm package: truncated lead"}},
+		},
+		{
+			name: "unsupported compression",
+			fsys: fstest.MapFS{debFile: {Data: installtest.DebControl(t, "control.tar.xz", []byte("xz"))}},
+			req:  VerifyRequest{OS: []OSFamily{OSDebian}, Arch: "amd64", Version: "3.119.0"},
+			want: map[string][]string{debFile: nil},
+		},
+		{
+			name:         "invalid manifest",
+			fsys:         fstest.MapFS{debFile: {Data: deb}, index.ChecksumsFile: {Data: []byte("abc " + debFile)}},
+			req:          VerifyRequest{OS: []OSFamily{OSDebian}, Arch: "amd64", Version: "3.119.0"},
+			want:         map[string][]string{debFile: nil},
+			wantProblems: []string{"SHA256SUMS: malformed checksum at line 1"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := VerifyPackages(tc.fsys, tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string][]string{}
+			for _, pkg := range report.Packages {
+				got[pkg.Filename] = pkg.Problems
+				if pkg.Found && pkg.SHA256 != installtest.SHA256Hex(tc.fsys[pkg.Filename].Data) {
+					t.Fatalf("%s: want: %s != got: %s", pkg.Filename, installtest.SHA256Hex(tc.fsys[pkg.Filename].Data), pkg.SHA256)
+				}
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Fatalf("want: %q != got: %q", tc.want, got)
+			}
+			if !reflect.DeepEqual(tc.wantProblems, report.Problems) {
+				t.Fatalf("want: %q != got: %q", tc.wantProblems, report.Problems)
+			}
+			wantOK := tc.wantProblems == nil
+			for _, problems := range tc.want {
+				wantOK = wantOK && problems == nil
+			}
+			if report.OK() != wantOK {
+				t.Fatalf("want: %v != got: %v (%v)", wantOK, report.OK(), report.Err())
+			}
+		})
+	}
+
+	t.Run("unsupported arch", func(t *testing.T) {
+		_, err := VerifyPackages(fstest.MapFS{}, VerifyRequest{Arch: "s390x", Version: "3.119.0"})
+		if !errors.Is(err, ErrUnsupportedArch) {
+			t.Fatalf("want: %v != got: %v", ErrUnsupportedArch, err)
+		}
+	})
+}
+
+func sha256Line(data []byte, name string) string {
+	return installtest.SHA256Hex(data) + "  " + name + "\n"
+}
@@ -0,0 +1,353 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+
+	// LintJSON reports documents that are not valid JSON of the expected shape.
+	LintJSON = "json"
+	// LintPretty reports -pretty files missing or differing from their twin.
+	LintPretty = "pretty"
+	// LintNaming reports tags and schema directories with non canonical names.
+	LintNaming = "naming"
+	// LintOrder reports index tags out of ascending version order.
+	LintOrder = "order"
+	// LintDuplicate reports tags, mappings and schema versions listed twice.
+	LintDuplicate = "duplicate"
+	// LintMissingFile reports schema directories missing a schema document.
+	LintMissingFile = "missing-file"
+	// LintSchemaCoverage reports Core Fluent Bit images without a schema.
+	LintSchemaCoverage = "schema-coverage"
+)
+
+var (
+	// releaseTag matches the tags of a full release, as opposed to aliases such as v1.2.
+	releaseTag     = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+	firstLuaSchema = semver.Must(semver.NewSemver("23.1.0"))
+)
+
+type (
+	LintSeverity string
+
+	// LintFinding is a problem found in the repository tree.
+	LintFinding struct {
+		Check    string       `json:"check"`
+		Severity LintSeverity `json:"severity"`
+		// Path of the offending file or directory, relative to the root.
+		Path    string `json:"path"`
+		Message string `json:"message"`
+	}
+
+	// LintReport lists the findings ordered by path.
+	LintReport struct {
+		Findings []LintFinding `json:"findings"`
+		Errors   int           `json:"errors"`
+		Warnings int           `json:"warnings"`
+	}
+
+	linter struct {
+		fsys     fs.FS
+		findings []LintFinding
+	}
+)
+
+// OK reports whether the tree has no error. Warnings are tolerated.
+func (r LintReport) OK() bool {
+	return r.Errors == 0
+}
+
+// Lint checks a tree with the layout of this repository: the indexes, the
+// operator mappings and every schema directory. Problems are reported as
+// findings, the error is only set when the tree cannot be read.
+//
+// Container tags are Calyptia Core releases and are not the versions of the
+// schemas, which are Core Fluent Bit releases. Schema coverage is therefore
+// checked through the operator mappings, which tie every operator release to
+// the Core Fluent Bit image it deploys.
+func Lint(fsys fs.FS) (LintReport, error) {
+	l := &linter{fsys: fsys}
+
+	l.index(ContainerIndexFile, ContainerTagFilter)
+	l.index(OperatorIndexFile, OperatorTagFilter)
+
+	schemas, err := l.schemas()
+	if err != nil {
+		return LintReport{}, err
+	}
+	l.mappings(schemas)
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].Path < l.findings[j].Path
+	})
+	report := LintReport{Findings: l.findings}
+	if report.Findings == nil {
+		report.Findings = []LintFinding{}
+	}
+	for _, f := range report.Findings {
+		if f.Severity == LintError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	return report, nil
+}
+
+func (l *linter) report(check string, severity LintSeverity, name, format string, args ...any) {
+	l.findings = append(l.findings, LintFinding{
+		Check:    check,
+		Severity: severity,
+		Path:     name,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// read returns the content of name, reporting it when it is missing.
+func (l *linter) read(name string) ([]byte, bool) {
+	data, err := fs.ReadFile(l.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		l.report(LintMissingFile, LintError, name, "missing %s", path.Base(name))
+		return nil, false
+	}
+	if err != nil {
+		l.report(LintMissingFile, LintError, name, "cannot read: %v", err)
+		return nil, false
+	}
+	return data, true
+}
+
+func (l *linter) index(name string, filter *regexp.Regexp) {
+	data, ok := l.read(name)
+	if !ok {
+		return
+	}
+
+	var releases Releases
+	if err := json.Unmarshal(data, &releases); err != nil {
+		l.report(LintJSON, LintError, name, "not an index: %v", err)
+		return
+	}
+	tags := releases.Tags()
+
+	seen := map[string]bool{}
+	var previous *semver.Version
+	for _, tag := range tags {
+		if seen[tag] {
+			l.report(LintDuplicate, LintError, name, "duplicate tag %s", tag)
+			continue
+		}
+		seen[tag] = true
+
+		if !filter.MatchString(tag) {
+			l.report(LintNaming, LintError, name, "tag %s does not match %s", tag, filter)
+			continue
+		}
+		if !releaseTag.MatchString(tag) {
+			continue
+		}
+
+		ver, err := semver.NewSemver(tag)
+		if err != nil {
+			l.report(LintNaming, LintError, name, "tag %s is not a version: %v", tag, err)
+			continue
+		}
+		if previous != nil && ver.LessThan(previous) {
+			l.report(LintOrder, LintError, name, "tag %s is listed after %s", tag, previous.Original())
+			continue
+		}
+		previous = ver
+	}
+}
+
+// schemas checks every schema directory and returns the versions found.
+func (l *linter) schemas() (map[string]bool, error) {
+	entries, err := fs.ReadDir(l.fsys, SchemaDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		l.report(LintMissingFile, LintError, SchemaDir, "schema directory not found")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", SchemaDir, err)
+	}
+
+	versions := map[string]bool{}
+	canonical := map[string]string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		dir := path.Join(SchemaDir, name)
+		versions[name] = true
+
+		ver, err := semver.NewSemver(name)
+		switch {
+		case err != nil:
+			l.report(LintNaming, LintError, dir, "%s is not a version", name)
+		case ver.String() != name:
+			l.report(LintNaming, LintError, dir, "%s should be named %s", name, ver.String())
+		}
+		if err == nil {
+			if other, ok := canonical[ver.String()]; ok {
+				l.report(LintDuplicate, LintError, dir, "%s is the same version as %s", name, other)
+			}
+			canonical[ver.String()] = name
+		}
+
+		l.schemaDir(dir, ver)
+	}
+	return versions, nil
+}
+
+func (l *linter) schemaDir(dir string, ver *semver.Version) {
+	// Images released before 23.1 have no Lua schema.
+	luaSeverity := LintError
+	if ver == nil || ver.LessThan(firstLuaSchema) {
+		luaSeverity = LintWarning
+	}
+
+	for _, file := range []struct {
+		name     string
+		severity LintSeverity
+	}{
+		{name: SchemaFile, severity: LintError},
+		{name: PluginsSchemaFile, severity: LintError},
+		{name: LuaSchemaFile, severity: luaSeverity},
+	} {
+		name := path.Join(dir, file.name)
+		compact, err := fs.ReadFile(l.fsys, name)
+		if err != nil {
+			l.report(LintMissingFile, file.severity, name, "missing %s", file.name)
+			continue
+		}
+		l.pretty(name, compact)
+	}
+}
+
+// pretty checks that the document name and its -pretty twin hold the same JSON value.
+func (l *linter) pretty(name string, compact []byte) {
+	want, err := decodeValue(compact)
+	if err != nil {
+		l.report(LintJSON, LintError, name, "invalid JSON: %v", err)
+		return
+	}
+
+	prettyName := strings.TrimSuffix(name, ".json") + "-pretty.json"
+	pretty, ok := l.read(prettyName)
+	if !ok {
+		return
+	}
+	got, err := decodeValue(pretty)
+	if err != nil {
+		l.report(LintJSON, LintError, prettyName, "invalid JSON: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(want, got) {
+		l.report(LintPretty, LintError, prettyName, "differs from %s", path.Base(name))
+	}
+}
+
+func (l *linter) mappings(schemas map[string]bool) {
+	data, ok := l.read(OperatorMappingsFile)
+	if !ok {
+		return
+	}
+	if _, err := decodeValue(data); err != nil {
+		l.report(LintJSON, LintError, OperatorMappingsFile, "invalid JSON: %v", err)
+		return
+	}
+
+	entries, err := stringEntries(data)
+	if err != nil {
+		l.report(LintJSON, LintError, OperatorMappingsFile, "not a JSON object of images: %v", err)
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		key, image := entry[0], entry[1]
+		if seen[key] {
+			l.report(LintDuplicate, LintError, OperatorMappingsFile, "duplicate release %s", key)
+			continue
+		}
+		seen[key] = true
+
+		if key != LatestMapping && !OperatorTagFilter.MatchString(key) {
+			l.report(LintNaming, LintError, OperatorMappingsFile, "release %s does not match %s", key, OperatorTagFilter)
+		}
+
+		tag := ImageTag(image)
+		switch {
+		case tag == "":
+			l.report(LintSchemaCoverage, LintError, OperatorMappingsFile, "release %s maps to image %q without a tag", key, image)
+		case schemas != nil && !schemas[tag]:
+			l.report(LintSchemaCoverage, LintError, OperatorMappingsFile, "release %s maps to %s which has no schema", key, tag)
+		}
+	}
+}
+
+// decodeValue decodes any JSON value, keeping numbers exact.
+func decodeValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the top level value")
+	}
+	return v, nil
+}
+
+// stringEntries returns the key and value pairs of a JSON object of strings
+// in document order, duplicates included, which json.Unmarshal silently merges.
+func stringEntries(data []byte) ([][2]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("expected an object")
+	}
+
+	var entries [][2]string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+
+		var value string
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, [2]string{key, value})
+	}
+	return entries, nil
+}
+
+// ImageTag returns the tag of an image reference, such as 24.7.1 for
+// ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1, empty when it has none.
+func ImageTag(image string) string {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
@@ -0,0 +1,63 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLint(t *testing.T) {
+	file := func(data string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(data)}
+	}
+	fsys := fstest.MapFS{
+		ContainerIndexFile:   file(`["v0.1.0","v0","v0.1","v0.2.1","v0.2.0","v0.2.1","latest"]`),
+		OperatorIndexFile:    file(`["v1.0.0","v1.1.0"]`),
+		OperatorMappingsFile: file(`{"v1.1.0":"ghcr.io/calyptia/core/calyptia-fluent-bit:23.1.1","v1.0.0":"ghcr.io/calyptia/core/calyptia-fluent-bit:","v1.0.0":"x:22.1.1","latest":"localhost:5000/core:23.1.1"}`),
+
+		"schemas/23.1.1/core-fluent-bit.json":                file(`{"a":1.0}`),
+		"schemas/23.1.1/core-fluent-bit-pretty.json":         file("{\n  \"a\": 1.0\n}\n"),
+		"schemas/23.1.1/core-fluent-bit-plugins.json":        file(`{"plugins":[]}`),
+		"schemas/23.1.1/core-fluent-bit-plugins-pretty.json": file(`{"plugins":[1]}`),
+		"schemas/23.1.1/core-fluent-bit-lua.json":            file(`{`),
+		"schemas/23.1.1/core-fluent-bit-lua-pretty.json":     file(`{}`),
+
+		"schemas/v22.07.1/core-fluent-bit.json":                file(`{}`),
+		"schemas/v22.07.1/core-fluent-bit-pretty.json":         file(`{}`),
+		"schemas/v22.07.1/core-fluent-bit-plugins.json":        file(`{}`),
+		"schemas/v22.07.1/core-fluent-bit-plugins-pretty.json": file(`{}`),
+		"schemas/22.7.1/core-fluent-bit.json":                  file(`{}`),
+		"schemas/22.7.1/core-fluent-bit-plugins.json":          file(`{}`),
+		"schemas/22.7.1/core-fluent-bit-plugins-pretty.json":   file(`{}`),
+	}
+
+	report, err := Lint(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LintFinding{
+		{Check: LintOrder, Severity: LintError, Path: ContainerIndexFile, Message: "tag v0.2.0 is listed after v0.2.1"},
+		{Check: LintDuplicate, Severity: LintError, Path: ContainerIndexFile, Message: "duplicate tag v0.2.1"},
+		{Check: LintNaming, Severity: LintError, Path: ContainerIndexFile, Message: "tag latest does not match " + ContainerTagFilter.String()},
+		{Check: LintSchemaCoverage, Severity: LintError, Path: OperatorMappingsFile, Message: `release v1.0.0 maps to image "ghcr.io/calyptia/core/calyptia-fluent-bit:" without a tag`},
+		{Check: LintDuplicate, Severity: LintError, Path: OperatorMappingsFile, Message: "duplicate release v1.0.0"},
+		{Check: LintMissingFile, Severity: LintWarning, Path: "schemas/22.7.1/core-fluent-bit-lua.json", Message: "missing core-fluent-bit-lua.json"},
+		{Check: LintMissingFile, Severity: LintError, Path: "schemas/22.7.1/core-fluent-bit-pretty.json", Message: "missing core-fluent-bit-pretty.json"},
+		{Check: LintJSON, Severity: LintError, Path: "schemas/23.1.1/core-fluent-bit-lua.json", Message: "invalid JSON: unexpected EOF"},
+		{Check: LintPretty, Severity: LintError, Path: "schemas/23.1.1/core-fluent-bit-plugins-pretty.json", Message: "differs from core-fluent-bit-plugins.json"},
+		{Check: LintNaming, Severity: LintError, Path: "schemas/v22.07.1", Message: "v22.07.1 should be named 22.7.1"},
+		{Check: LintDuplicate, Severity: LintError, Path: "schemas/v22.07.1", Message: "v22.07.1 is the same version as 22.7.1"},
+		{Check: LintMissingFile, Severity: LintWarning, Path: "schemas/v22.07.1/core-fluent-bit-lua.json", Message: "missing core-fluent-bit-lua.json"},
+	}
+	got := report.Findings
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v\n!= got: %+v", want, got)
+	}
+	if want, got := 10, report.Errors; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+	if report.OK() {
+		t.Error("want report with errors")
+	}
+}
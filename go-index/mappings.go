@@ -0,0 +1,120 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+const (
+	// OperatorMappingsFile maps operator releases to their default Core Fluent
+	// Bit image.
+	OperatorMappingsFile = "operator/core-fluent-bit-default-versions.json"
+	// LatestMapping is the key of the latest operator release in the mappings.
+	LatestMapping = "latest"
+)
+
+type (
+	// OperatorMappings maps operator release tags, and LatestMapping, to the
+	// default Core Fluent Bit image of the release.
+	OperatorMappings map[string]string
+
+	// MappingFetch is implemented by the fetchers able to read the operator
+	// mappings published with the operator index.
+	MappingFetch interface {
+		GetMappings(ctx context.Context) (OperatorMappings, error)
+	}
+)
+
+// MarshalJSON orders releases from the newest to the oldest and LatestMapping
+// last, like the file committed in this repository.
+func (m OperatorMappings) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if key != LatestMapping {
+			keys = append(keys, key)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, errA := semver.NewSemver(keys[i])
+		b, errB := semver.NewSemver(keys[j])
+		if errA != nil || errB != nil || a.Equal(b) {
+			return keys[i] > keys[j]
+		}
+		return a.GreaterThan(b)
+	})
+	if _, ok := m[LatestMapping]; ok {
+		keys = append(keys, LatestMapping)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalMappings encodes m as the indented JSON object committed in this
+// repository.
+func MarshalMappings(m OperatorMappings) ([]byte, error) {
+	return marshalIndent(m)
+}
+
+// Image returns the Core Fluent Bit image of the operator release equal to
+// version. Images without a tag are reported with ErrUntaggedImage and
+// ErrNoMatchingImage.
+func (m OperatorMappings) Image(version string) (string, error) {
+	want, err := semver.NewVersion(version)
+	if err != nil {
+		return "", err
+	}
+	for key, image := range m {
+		got, err := semver.NewVersion(key)
+		if err != nil || !got.Equal(want) {
+			continue
+		}
+		if ImageTag(image) == "" {
+			return "", fmt.Errorf("operator %s maps to image %q: %w: %w", key, image, ErrUntaggedImage, ErrNoMatchingImage)
+		}
+		return image, nil
+	}
+	return "", ErrNoMatchingImage
+}
+
+// GetMappings reads the operator mappings published at the root of the
+// repository holding the index.
+func (c *OperatorIndexFetcher) GetMappings(ctx context.Context) (OperatorMappings, error) {
+	var out OperatorMappings
+	url := c.url()
+	err := fetchJSON(ctx, c.Client, url[:strings.LastIndex(url, "/")+1]+OperatorMappingsFile, c.Verifier, &out)
+	return out, err
+}
+
+// GetMappings reads the operator mappings stored at the root of the
+// repository holding the index.
+func (c *OperatorIndexFSFetcher) GetMappings(ctx context.Context) (OperatorMappings, error) {
+	var out OperatorMappings
+	err := readJSON(ctx, c.FS, path.Join(path.Dir(c.name()), OperatorMappingsFile), c.Verifier, &out)
+	return out, err
+}
@@ -0,0 +1,71 @@
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const maxConfigSize = 4 << 20
+
+type verifyingReader struct {
+	io.ReadCloser
+	hash   hash.Hash
+	digest string
+}
+
+// Blob opens the blob described by desc. The content is checked against the
+// digest once fully read. The caller must close it.
+func (c *Client) Blob(ctx context.Context, repository string, desc Descriptor) (io.ReadCloser, error) {
+	url := fmt.Sprintf("%s/v2/%s/blobs/%s", c.baseURL(), repository, desc.Digest)
+	res, err := c.Do(ctx, http.MethodGet, url, repository, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(desc.Digest, "sha256:") {
+		return res.Body, nil
+	}
+	return &verifyingReader{ReadCloser: res.Body, hash: sha256.New(), digest: desc.Digest}, nil
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if got := "sha256:" + hex.EncodeToString(r.hash.Sum(nil)); got != r.digest {
+			return n, fmt.Errorf("blob digest mismatch: want %s, got %s", r.digest, got)
+		}
+	}
+	return n, err
+}
+
+// ConfigPlatform reads the platform of a single platform image from its
+// configuration blob.
+func (c *Client) ConfigPlatform(ctx context.Context, repository string, config Descriptor) (Platform, error) {
+	var out Platform
+
+	body, err := c.Blob(ctx, repository, config)
+	if err != nil {
+		return out, err
+	}
+	defer body.Close()
+
+	// The blob is read to its end so that its digest is checked.
+	data, err := io.ReadAll(io.LimitReader(body, maxConfigSize+1))
+	if err != nil {
+		return out, fmt.Errorf("could not read image config %s: %w", config.Digest, err)
+	}
+	if len(data) > maxConfigSize {
+		return out, fmt.Errorf("image config %s exceeds %d bytes", config.Digest, maxConfigSize)
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return out, fmt.Errorf("could not decode image config %s: %w", config.Digest, err)
+	}
+	return out, nil
+}
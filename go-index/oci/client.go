@@ -0,0 +1,298 @@
+// Package oci implements the subset of the OCI Distribution API used to build
+// and resolve the indexes: listing tags, fetching manifests and blobs, with
+// support for the bearer token challenge flow.
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultRegistry = "https://ghcr.io"
+
+	defaultPageSize = 1000
+	maxErrorBody    = 64 << 10
+)
+
+var ErrNotFound = fmt.Errorf("not found in registry")
+
+type (
+	// Client talks to a registry implementing the OCI Distribution API.
+	Client struct {
+		// BaseURL of the registry, defaults to DefaultRegistry.
+		BaseURL string
+		// HTTPClient defaults to http.DefaultClient.
+		HTTPClient *http.Client
+		// Username and Password are used for basic auth and to request bearer
+		// tokens.
+		Username string
+		Password string
+		// Token is sent as a bearer token before any challenge, like the
+		// base64 encoded GitHub token accepted by ghcr.io.
+		Token string
+		// PageSize requested when listing tags, defaults to 1000.
+		PageSize int
+
+		mu     sync.Mutex
+		tokens map[string]string
+	}
+
+	// Error is returned for unexpected registry responses.
+	Error struct {
+		StatusCode int
+		URL        string
+		Errors     []ErrorDetail `json:"errors"`
+	}
+
+	ErrorDetail struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	tagList struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+
+	tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+)
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("registry request %s failed with status %d", e.URL, e.StatusCode)
+	for _, detail := range e.Errors {
+		msg += fmt.Sprintf(": %s %s", detail.Code, detail.Message)
+	}
+	return msg
+}
+
+// Is makes a 404 match ErrNotFound.
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Tags lists every tag of repository, following the pagination Link headers.
+func (c *Client) Tags(ctx context.Context, repository string) ([]string, error) {
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=%d", c.baseURL(), repository, pageSize)
+	var out []string
+	for next != "" {
+		res, err := c.Do(ctx, http.MethodGet, next, repository, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page tagList
+		err = json.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not decode tags of %s: %w", repository, err)
+		}
+		out = append(out, page.Tags...)
+
+		next, err = nextLink(res.Request.URL, res.Header.Get("Link"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Do sends an authenticated request for repository, answering a bearer token
+// challenge once if needed. Responses other than 2xx are returned as *Error.
+func (c *Client) Do(ctx context.Context, method, rawURL, repository string, header http.Header) (*http.Response, error) {
+	scope := "repository:" + repository + ":pull"
+
+	res, err := c.send(ctx, method, rawURL, header, c.token(scope))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		challenge := res.Header.Get("WWW-Authenticate")
+		_ = res.Body.Close()
+
+		token, err := c.authenticate(ctx, challenge, scope)
+		if err != nil {
+			return nil, err
+		}
+		res, err = c.send(ctx, method, rawURL, header, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		out := &Error{StatusCode: res.StatusCode, URL: rawURL}
+		_ = json.NewDecoder(io.LimitReader(res.Body, maxErrorBody)).Decode(out)
+		return nil, out
+	}
+	return res, nil
+}
+
+func (c *Client) send(ctx context.Context, method, rawURL string, header http.Header, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create registry request %s: %w", rawURL, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach registry %s: %w", rawURL, err)
+	}
+	return res, nil
+}
+
+// authenticate obtains a token for scope from the realm of a bearer challenge.
+func (c *Client) authenticate(ctx context.Context, challenge, scope string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "bearer") {
+		return "", &Error{StatusCode: http.StatusUnauthorized, URL: params["realm"]}
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid bearer realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if s := params["scope"]; s != "" {
+		scope = s
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create token request: %w", err)
+	}
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not request registry token: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", &Error{StatusCode: res.StatusCode, URL: realm.String()}
+	}
+
+	var out tokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("could not decode registry token: %w", err)
+	}
+	token := out.Token
+	if token == "" {
+		token = out.AccessToken
+	}
+	if token == "" {
+		return "", errors.New("registry returned an empty token")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[string]string{}
+	}
+	c.tokens[scope] = token
+	return token, nil
+}
+
+func (c *Client) token(scope string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if token, ok := c.tokens[scope]; ok {
+		return token
+	}
+	return c.Token
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return DefaultRegistry
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:x:pull".
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
+
+// nextLink resolves the rel="next" target of a Link header against base.
+func nextLink(base *url.URL, header string) (string, error) {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if !ok || !strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+			continue
+		}
+
+		ref, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return "", fmt.Errorf("invalid pagination link %q: %w", header, err)
+		}
+		return base.ResolveReference(ref).String(), nil
+	}
+	return "", nil
+}
+
+// Host returns the registry host name used in image references, like ghcr.io.
+func (c *Client) Host() string {
+	u, err := url.Parse(c.baseURL())
+	if err != nil || u.Host == "" {
+		return strings.TrimPrefix(strings.TrimPrefix(c.baseURL(), "https://"), "http://")
+	}
+	return u.Host
+}
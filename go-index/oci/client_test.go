@@ -0,0 +1,21 @@
+package oci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:a/b:pull"`)
+	if want, got := "Bearer", scheme; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+	want := map[string]string{
+		"realm":   "https://ghcr.io/token",
+		"service": "ghcr.io",
+		"scope":   "repository:a/b:pull",
+	}
+	if !reflect.DeepEqual(want, params) {
+		t.Errorf("want: %v != got: %v", want, params)
+	}
+}
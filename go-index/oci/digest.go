@@ -0,0 +1,12 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Digest returns the sha256 digest of data in the "sha256:<hex>" form.
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
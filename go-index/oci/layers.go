@@ -0,0 +1,131 @@
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	maxFileSize    = 64 << 20
+)
+
+// ImageManifest returns the image manifest of reference for platform
+// (os/arch[/variant]), following image indexes. Single platform images are
+// returned as is.
+func (c *Client) ImageManifest(ctx context.Context, repository, reference, platform string) (Manifest, error) {
+	manifest, err := c.Manifest(ctx, repository, reference)
+	if err != nil || !manifest.IsIndex() {
+		return manifest, err
+	}
+
+	for _, desc := range manifest.Manifests {
+		if desc.Platform != nil && desc.Platform.String() == platform {
+			return c.Manifest(ctx, repository, desc.Digest)
+		}
+	}
+	return Manifest{}, fmt.Errorf("%s:%s has no %s image: %w", repository, reference, platform, ErrNotFound)
+}
+
+// ReadFiles returns the content of the requested absolute paths in the image
+// filesystem built by the layers of manifest, without running the image.
+// Later layers override earlier ones and whiteouts delete files. Paths absent
+// from the image are missing from the result, while paths that are links or
+// larger than 64MB are an error.
+func (c *Client) ReadFiles(ctx context.Context, repository string, manifest Manifest, paths ...string) (map[string][]byte, error) {
+	wanted := map[string]struct{}{}
+	for _, p := range paths {
+		wanted[cleanPath(p)] = struct{}{}
+	}
+
+	out := map[string][]byte{}
+	for _, layer := range manifest.Layers {
+		if err := c.readLayer(ctx, repository, layer, wanted, out); err != nil {
+			return nil, fmt.Errorf("cannot read layer %s: %w", layer.Digest, err)
+		}
+	}
+
+	result := make(map[string][]byte, len(out))
+	for p, content := range out {
+		result["/"+p] = content
+	}
+	return result, nil
+}
+
+func (c *Client) readLayer(ctx context.Context, repository string, layer Descriptor, wanted map[string]struct{}, out map[string][]byte) error {
+	blob, err := c.Blob(ctx, repository, layer)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	var r io.Reader = blob
+	switch {
+	case strings.HasSuffix(layer.MediaType, "gzip"):
+		gz, err := gzip.NewReader(blob)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case strings.HasSuffix(layer.MediaType, "zstd"):
+		return errors.New("zstd compressed layers are not supported")
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := cleanPath(hdr.Name)
+		dir, base := path.Split(name)
+		if strings.HasPrefix(base, whiteoutPrefix) {
+			// .wh..wh..opq hides the whole directory, .wh.<name> a single entry.
+			deleted := path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+			for p := range out {
+				if p == deleted || strings.HasPrefix(p, deleted+"/") || (base == ".wh..wh..opq" && strings.HasPrefix(p, dir)) {
+					delete(out, p)
+				}
+			}
+			continue
+		}
+
+		if _, ok := wanted[name]; !ok {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("/%s is a link to %s, links are not followed", name, hdr.Linkname)
+		default:
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, maxFileSize+1))
+		if err != nil {
+			return err
+		}
+		if len(content) > maxFileSize {
+			return fmt.Errorf("/%s exceeds %d bytes", name, maxFileSize)
+		}
+		out[name] = content
+	}
+
+	// Drain the blob so that its digest is verified.
+	_, err = io.Copy(io.Discard, blob)
+	return err
+}
+
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
@@ -0,0 +1,166 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func layer(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestClient_ReadFiles(t *testing.T) {
+	const gzipLayer = "application/vnd.oci.image.layer.v1.tar+gzip"
+	base := layer(t, map[string]string{
+		"./schema.json":     `{"v":1}`,
+		"etc/removed.json":  `{}`,
+		"opt/app/keep.json": `{"keep":true}`,
+	})
+	top := layer(t, map[string]string{
+		"schema.json":          `{"v":2}`,
+		"etc/.wh.removed.json": "",
+	})
+	manifest, _ := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIManifest,
+		Layers: []Descriptor{
+			{MediaType: gzipLayer, Digest: Digest(base)},
+			{MediaType: gzipLayer, Digest: Digest(top)},
+		},
+	})
+	list, _ := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIIndex,
+		Manifests: []Descriptor{
+			{Digest: "sha256:arm64", Platform: &Platform{OS: "linux", Architecture: "arm64"}},
+			{Digest: Digest(manifest), Platform: &Platform{OS: "linux", Architecture: "amd64"}},
+		},
+	})
+
+	documents := map[string][]byte{
+		"/v2/app/manifests/v1":                  list,
+		"/v2/app/manifests/" + Digest(manifest): manifest,
+		"/v2/app/blobs/" + Digest(base):         base,
+		"/v2/app/blobs/" + Digest(top):          top,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.Contains(r.URL.Path, "/manifests/") {
+			w.Header().Set("Docker-Content-Digest", Digest(doc))
+		}
+		_, _ = w.Write(doc)
+	}))
+	defer ts.Close()
+
+	client := &Client{BaseURL: ts.URL, HTTPClient: ts.Client()}
+	ctx := context.Background()
+
+	image, err := client.ImageManifest(ctx, "app", "v1", "linux/amd64")
+	if err != nil {
+		t.Fatalf("image manifest: %v", err)
+	}
+	if want, got := Digest(manifest), image.Digest; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+
+	files, err := client.ReadFiles(ctx, "app", image, "/schema.json", "/etc/removed.json", "/opt/app/keep.json", "/missing")
+	if err != nil {
+		t.Fatalf("read files: %v", err)
+	}
+	want := map[string][]byte{
+		"/schema.json":       []byte(`{"v":2}`),
+		"/opt/app/keep.json": []byte(`{"keep":true}`),
+	}
+	if !reflect.DeepEqual(want, files) {
+		t.Errorf("want: %q != got: %q", want, files)
+	}
+
+	if _, err := client.ImageManifest(ctx, "app", "v1", "linux/s390x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want: %v != got: %v", ErrNotFound, err)
+	}
+
+	documents["/v2/app/blobs/"+Digest(top)] = base
+	if _, err := client.ReadFiles(ctx, "app", image, "/schema.json"); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("want digest mismatch, got: %v", err)
+	}
+}
+
+func TestClient_ReadFiles_unsupported(t *testing.T) {
+	const gzipLayer = "application/vnd.oci.image.layer.v1.tar+gzip"
+	tt := []struct {
+		name string
+		hdr  tar.Header
+		want string
+	}{
+		{name: "symlink", hdr: tar.Header{Name: "schema.json", Typeflag: tar.TypeSymlink, Linkname: "opt/schema.json"}, want: "link"},
+		{name: "hardlink", hdr: tar.Header{Name: "schema.json", Typeflag: tar.TypeLink, Linkname: "opt/schema.json"}, want: "link"},
+		{name: "too large", hdr: tar.Header{Name: "schema.json", Typeflag: tar.TypeReg, Size: maxFileSize + 1}, want: "exceeds"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			tc.hdr.Mode = 0o644
+			if err := tw.WriteHeader(&tc.hdr); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write(make([]byte, tc.hdr.Size)); err != nil {
+				t.Fatal(err)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+			blob := buf.Bytes()
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(blob)
+			}))
+			defer ts.Close()
+
+			client := &Client{BaseURL: ts.URL, HTTPClient: ts.Client()}
+			manifest := Manifest{Layers: []Descriptor{{MediaType: gzipLayer, Digest: Digest(blob)}}}
+			_, err := client.ReadFiles(context.Background(), "app", manifest, "/schema.json")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("want: %s != got: %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	maxManifestSize = 4 << 20
+)
+
+// manifestAccept lists every manifest type understood by the client.
+var manifestAccept = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}, ", ")
+
+type (
+	// Manifest holds the fields shared by image manifests and image indexes
+	// (manifest lists) in both OCI and Docker formats.
+	Manifest struct {
+		SchemaVersion int          `json:"schemaVersion"`
+		MediaType     string       `json:"mediaType,omitempty"`
+		Config        *Descriptor  `json:"config,omitempty"`
+		Layers        []Descriptor `json:"layers,omitempty"`
+		Manifests     []Descriptor `json:"manifests,omitempty"`
+		// Digest is the digest of the manifest as served by the registry.
+		Digest string `json:"-"`
+	}
+
+	Descriptor struct {
+		MediaType string    `json:"mediaType"`
+		Digest    string    `json:"digest"`
+		Size      int64     `json:"size"`
+		Platform  *Platform `json:"platform,omitempty"`
+	}
+
+	Platform struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant,omitempty"`
+	}
+)
+
+// IsIndex reports whether the manifest lists per platform manifests.
+func (m Manifest) IsIndex() bool {
+	return m.MediaType == MediaTypeOCIIndex || m.MediaType == MediaTypeDockerList ||
+		(m.MediaType == "" && len(m.Manifests) > 0)
+}
+
+func (p Platform) String() string {
+	out := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		out += "/" + p.Variant
+	}
+	return out
+}
+
+// Manifest fetches the manifest or image index that reference, a tag or a
+// digest, points to in repository.
+func (c *Client) Manifest(ctx context.Context, repository, reference string) (Manifest, error) {
+	var out Manifest
+
+	url := fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL(), repository, reference)
+	res, err := c.Do(ctx, http.MethodGet, url, repository, http.Header{"Accept": {manifestAccept}})
+	if err != nil {
+		return out, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxManifestSize+1))
+	if err != nil {
+		return out, fmt.Errorf("could not read manifest %s:%s: %w", repository, reference, err)
+	}
+	if len(body) > maxManifestSize {
+		return out, fmt.Errorf("manifest %s:%s exceeds %d bytes", repository, reference, maxManifestSize)
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return out, fmt.Errorf("could not decode manifest %s:%s: %w", repository, reference, err)
+	}
+	if out.MediaType == "" {
+		out.MediaType = res.Header.Get("Content-Type")
+	}
+
+	// The digest is the one of the body read, the header only being checked
+	// against it.
+	out.Digest = Digest(body)
+	if strings.HasPrefix(reference, "sha256:") && out.Digest != reference {
+		return out, fmt.Errorf("manifest %s@%s digest mismatch: got %s", repository, reference, out.Digest)
+	}
+	if header := res.Header.Get("Docker-Content-Digest"); header != "" && header != out.Digest {
+		return out, fmt.Errorf("manifest %s:%s digest mismatch: registry sent %s, got %s", repository, reference, header, out.Digest)
+	}
+	return out, nil
+}
@@ -0,0 +1,461 @@
+// Package ocitest runs an in-memory registry implementing the subset of the
+// OCI Distribution API used by the oci package, seeded from Go values, so that
+// tag listing, digest resolution and schema extraction are tested without
+// the network.
+package ocitest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+const (
+	MediaTypeOCILayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
+	MediaTypeOCIConfig   = "application/vnd.oci.image.config.v1+json"
+	MediaTypeDockerLayer = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	MediaTypeDockerImage = "application/vnd.docker.container.image.v1+json"
+
+	service         = "ocitest"
+	tokenPrefix     = "ocitest-token:"
+	defaultPageSize = 100
+)
+
+type (
+	// Config sets up a registry.
+	Config struct {
+		// Repositories seed the registry, by name such as calyptia/core.
+		Repositories map[string]Repository
+		// Auth requires the bearer token challenge flow on every request.
+		Auth bool
+		// Username and Password, when set, are required by the token
+		// endpoint with basic auth.
+		Username string
+		Password string
+		// MaxPageSize caps the tags returned per page, defaults to 100.
+		MaxPageSize int
+	}
+
+	// Repository maps tags to images. A tag with a single image is pushed as
+	// an image manifest, one with several as an image index.
+	Repository struct {
+		Tags map[string][]Image
+	}
+
+	// Image is a single platform image.
+	Image struct {
+		Platform oci.Platform
+		// Config is the configuration blob, defaults to the JSON encoding of
+		// Platform.
+		Config []byte
+		// Layers are gzipped tar blobs, such as returned by Layer.
+		Layers [][]byte
+		// Docker uses the Docker media types instead of the OCI ones.
+		Docker bool
+	}
+
+	// Registry is an httptest.Server serving the pushed images. It is safe
+	// for concurrent use.
+	Registry struct {
+		*httptest.Server
+
+		cfg           Config
+		tokenRequests atomic.Int32
+
+		mu    sync.Mutex
+		repos map[string]*repository
+	}
+
+	repository struct {
+		tags      map[string]string
+		manifests map[string]manifest
+		blobs     map[string][]byte
+	}
+
+	manifest struct {
+		mediaType string
+		data      []byte
+		// digest, when set, is served instead of the digest of data.
+		digest string
+	}
+
+	errorResponse struct {
+		Errors []oci.ErrorDetail `json:"errors"`
+	}
+)
+
+// NewRegistry starts a registry seeded with cfg, closed with the test.
+func NewRegistry(tb testing.TB, cfg Config) *Registry {
+	tb.Helper()
+
+	r := &Registry{cfg: cfg, repos: map[string]*repository{}}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Repositories)) {
+		for tag, images := range cfg.Repositories[name].Tags {
+			if _, err := r.Push(name, tag, images...); err != nil {
+				tb.Fatalf("cannot seed %s:%s: %v", name, tag, err)
+			}
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /token", r.token)
+	mux.HandleFunc("/v2/", r.serve)
+	r.Server = httptest.NewServer(mux)
+	tb.Cleanup(r.Close)
+	return r
+}
+
+// OCIClient returns a client of the registry, authenticated with the
+// credentials of the configuration.
+func (r *Registry) OCIClient() *oci.Client {
+	return &oci.Client{
+		BaseURL:    r.URL,
+		HTTPClient: r.Server.Client(),
+		Username:   r.cfg.Username,
+		Password:   r.cfg.Password,
+	}
+}
+
+// TokenRequests returns the number of tokens issued.
+func (r *Registry) TokenRequests() int {
+	return int(r.tokenRequests.Load())
+}
+
+// Push stores images under repository and tags them, as an image manifest for
+// a single image or as an image index otherwise. It returns the descriptor of
+// the tagged manifest.
+func (r *Registry) Push(repository, tag string, images ...Image) (oci.Descriptor, error) {
+	if len(images) == 0 {
+		return oci.Descriptor{}, errors.New("no image to push")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	repo := r.repo(repository)
+
+	descs := make([]oci.Descriptor, 0, len(images))
+	for _, img := range images {
+		desc, err := repo.pushImage(img)
+		if err != nil {
+			return oci.Descriptor{}, err
+		}
+		descs = append(descs, desc)
+	}
+
+	desc := descs[0]
+	if len(images) > 1 {
+		mediaType := oci.MediaTypeOCIIndex
+		if images[0].Docker {
+			mediaType = oci.MediaTypeDockerList
+		}
+		var err error
+		desc, err = repo.pushManifest(oci.Manifest{SchemaVersion: 2, MediaType: mediaType, Manifests: descs})
+		if err != nil {
+			return oci.Descriptor{}, err
+		}
+	}
+	repo.tags[tag] = desc.Digest
+	return desc, nil
+}
+
+// Delete removes a tag, or a manifest by digest, from repository.
+func (r *Registry) Delete(repository, reference string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	repo := r.repo(repository)
+	delete(repo.tags, reference)
+	delete(repo.manifests, reference)
+}
+
+// ServeDigest makes the registry answer with digest as the
+// Docker-Content-Digest of the manifest that reference points to in
+// repository, as a faulty registry would.
+func (r *Registry) ServeDigest(repository, reference, digest string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	repo := r.repo(repository)
+	if tagged, ok := repo.tags[reference]; ok {
+		reference = tagged
+	}
+	if m, ok := repo.manifests[reference]; ok {
+		m.digest = digest
+		repo.manifests[reference] = m
+	}
+}
+
+// ReplaceBlob makes the registry serve data as the blob digest of
+// repository, which no longer matches its digest.
+func (r *Registry) ReplaceBlob(repository, digest string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.repo(repository).blobs[digest] = data
+}
+
+func (r *Registry) repo(name string) *repository {
+	repo, ok := r.repos[name]
+	if !ok {
+		repo = &repository{tags: map[string]string{}, manifests: map[string]manifest{}, blobs: map[string][]byte{}}
+		r.repos[name] = repo
+	}
+	return repo
+}
+
+func (repo *repository) pushImage(img Image) (oci.Descriptor, error) {
+	configType, layerType, manifestType := MediaTypeOCIConfig, MediaTypeOCILayer, oci.MediaTypeOCIManifest
+	if img.Docker {
+		configType, layerType, manifestType = MediaTypeDockerImage, MediaTypeDockerLayer, oci.MediaTypeDockerManifest
+	}
+
+	config := img.Config
+	if config == nil {
+		var err error
+		if config, err = json.Marshal(img.Platform); err != nil {
+			return oci.Descriptor{}, err
+		}
+	}
+	m := oci.Manifest{SchemaVersion: 2, MediaType: manifestType, Config: ptr(repo.pushBlob(configType, config))}
+	for _, layer := range img.Layers {
+		m.Layers = append(m.Layers, repo.pushBlob(layerType, layer))
+	}
+
+	desc, err := repo.pushManifest(m)
+	if err != nil {
+		return oci.Descriptor{}, err
+	}
+	desc.Platform = &img.Platform
+	return desc, nil
+}
+
+func (repo *repository) pushBlob(mediaType string, data []byte) oci.Descriptor {
+	digest := oci.Digest(data)
+	repo.blobs[digest] = data
+	return oci.Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(data))}
+}
+
+func (repo *repository) pushManifest(m oci.Manifest) (oci.Descriptor, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return oci.Descriptor{}, err
+	}
+	digest := oci.Digest(data)
+	repo.manifests[digest] = manifest{mediaType: m.MediaType, data: data}
+	return oci.Descriptor{MediaType: m.MediaType, Digest: digest, Size: int64(len(data))}, nil
+}
+
+// token issues a token granting the requested scope, checking the credentials when
+// the registry has some.
+func (r *Registry) token(w http.ResponseWriter, req *http.Request) {
+	if r.cfg.Username != "" {
+		user, password, ok := req.BasicAuth()
+		if !ok || user != r.cfg.Username || password != r.cfg.Password {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid credentials")
+			return
+		}
+	}
+	if req.URL.Query().Get("service") != service || !strings.HasPrefix(req.URL.Query().Get("scope"), "repository:") {
+		writeError(w, http.StatusBadRequest, "DENIED", "invalid service or scope")
+		return
+	}
+
+	r.tokenRequests.Add(1)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": tokenPrefix + req.URL.Query().Get("scope")})
+}
+
+func (r *Registry) serve(w http.ResponseWriter, req *http.Request) {
+	rest := strings.TrimPrefix(req.URL.Path, "/v2/")
+	name, kind, reference := route(rest)
+
+	if r.cfg.Auth && !authorized(req.Header.Get("Authorization"), name) {
+		challenge := fmt.Sprintf(`Bearer realm="%s/token",service="%s"`, r.URL, service)
+		if name != "" {
+			challenge += fmt.Sprintf(`,scope="repository:%s:pull"`, name)
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+		return
+	}
+
+	switch {
+	case rest == "":
+		w.WriteHeader(http.StatusOK)
+	case req.Method != http.MethodGet && req.Method != http.MethodHead:
+		writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "the registry is read only")
+	case kind == "tags" && reference == "list":
+		r.serveTags(w, req, name)
+	case kind == "manifests":
+		r.serveManifest(w, req, name, reference)
+	case kind == "blobs":
+		r.serveBlob(w, req, name, reference)
+	default:
+		writeError(w, http.StatusNotFound, "NAME_UNKNOWN", "unknown endpoint")
+	}
+}
+
+// authorized reports whether header holds a token issued for pulling name,
+// any token for the base endpoint.
+func authorized(header, name string) bool {
+	scope, ok := strings.CutPrefix(header, "Bearer "+tokenPrefix)
+	return ok && (name == "" || scope == "repository:"+name+":pull")
+}
+
+// route splits a path such as calyptia/core/manifests/v1 in the repository
+// name, the endpoint and its reference.
+func route(rest string) (name, kind, reference string) {
+	for _, k := range []string{"tags", "manifests", "blobs"} {
+		if i := strings.LastIndex(rest, "/"+k+"/"); i > 0 {
+			return rest[:i], k, rest[i+len(k)+2:]
+		}
+	}
+	return "", "", ""
+}
+
+func (r *Registry) serveTags(w http.ResponseWriter, req *http.Request, name string) {
+	r.mu.Lock()
+	repo, ok := r.repos[name]
+	var tags []string
+	if ok {
+		tags = slices.Sorted(maps.Keys(repo.tags))
+	}
+	r.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to registry")
+		return
+	}
+
+	// Tags are listed in lexical order, resuming after last.
+	query := req.URL.Query()
+	if last := query.Get("last"); last != "" {
+		i, _ := slices.BinarySearch(tags, last)
+		for i < len(tags) && tags[i] <= last {
+			i++
+		}
+		tags = tags[i:]
+	}
+	n := r.cfg.MaxPageSize
+	if n <= 0 {
+		n = defaultPageSize
+	}
+	if requested, err := strconv.Atoi(query.Get("n")); err == nil && requested > 0 {
+		n = min(n, requested)
+	}
+	if len(tags) > n {
+		tags = tags[:n]
+		next := url.Values{"n": {strconv.Itoa(n)}, "last": {tags[n-1]}}
+		w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?%s>; rel="next"`, name, next.Encode()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"name": name, "tags": tags})
+}
+
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, name, reference string) {
+	r.mu.Lock()
+	var (
+		m  manifest
+		ok bool
+	)
+	if repo, found := r.repos[name]; found {
+		digest := reference
+		if tagged, isTag := repo.tags[reference]; isTag {
+			digest = tagged
+		}
+		m, ok = repo.manifests[digest]
+	}
+	r.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+		return
+	}
+
+	if accept := req.Header.Get("Accept"); accept != "" && !strings.Contains(accept, m.mediaType) && !strings.Contains(accept, "*/*") {
+		writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest not available in the accepted media types")
+		return
+	}
+
+	w.Header().Set("Content-Type", m.mediaType)
+	digest := m.digest
+	if digest == "" {
+		digest = oci.Digest(m.data)
+	}
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", strconv.Itoa(len(m.data)))
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(m.data)
+	}
+}
+
+func (r *Registry) serveBlob(w http.ResponseWriter, req *http.Request, name, digest string) {
+	r.mu.Lock()
+	var (
+		data []byte
+		ok   bool
+	)
+	if repo, found := r.repos[name]; found {
+		data, ok = repo.blobs[digest]
+	}
+	r.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown to registry")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Errors: []oci.ErrorDetail{{Code: code, Message: message}}})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// Layer returns a gzipped tar layer holding files, by path such as
+// /fluent-bit/schema.json.
+func Layer(tb testing.TB, files map[string]string) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		content := files[name]
+		hdr := &tar.Header{Name: strings.TrimPrefix(name, "/"), Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
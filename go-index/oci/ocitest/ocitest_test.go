@@ -0,0 +1,219 @@
+package ocitest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	index "github.com/calyptia/core-images-index/go-index"
+	"github.com/calyptia/core-images-index/go-index/generate"
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+var (
+	amd64 = oci.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 = oci.Platform{OS: "linux", Architecture: "arm64"}
+)
+
+func TestRegistry_Tags(t *testing.T) {
+	image := []Image{{Platform: amd64}}
+	r := NewRegistry(t, Config{
+		Auth:        true,
+		Username:    "user",
+		Password:    "secret",
+		MaxPageSize: 2,
+		Repositories: map[string]Repository{
+			index.OperatorRepository: {Tags: map[string][]Image{
+				"v3.119.0": image, "v3.118.0": image, "v3.120.0-rc1": image, "latest": image, "v3.117.0": image,
+			}},
+		},
+	})
+	client := r.OCIClient()
+	ctx := context.Background()
+
+	fetcher := &index.RegistryFetcher{Client: client, Repository: index.OperatorRepository, Filter: index.OperatorTagFilter}
+	got, err := fetcher.Operator().GetImages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := index.OperatorImages{"v3.117.0", "v3.118.0", "v3.119.0"}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want: %v != got: %v", want, got)
+	}
+	// Every page reuses the token issued for the first one.
+	if want, got := 1, r.TokenRequests(); want != got {
+		t.Fatalf("want: %d != got: %d", want, got)
+	}
+
+	_, err = client.Tags(ctx, "calyptia/unknown")
+	if !errors.Is(err, oci.ErrNotFound) {
+		t.Fatalf("want: %v != got: %v", oci.ErrNotFound, err)
+	}
+
+	_, err = (&oci.Client{BaseURL: r.URL, Username: "user", Password: "wrong"}).Tags(ctx, index.OperatorRepository)
+	var regErr *oci.Error
+	if !errors.As(err, &regErr) || regErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("want: 401 != got: %v", err)
+	}
+}
+
+func TestRegistry_ImageManifest(t *testing.T) {
+	r := NewRegistry(t, Config{Auth: true})
+	amd64Layer := Layer(t, map[string]string{generate.LuaSchemaPath: "amd64", "/etc/hostname": "core"})
+	desc, err := r.Push(index.CoreFluentBitRepository, "24.7.1",
+		Image{Platform: amd64, Layers: [][]byte{amd64Layer, Layer(t, map[string]string{"/etc/hostname": "fluent-bit"})}},
+		Image{Platform: arm64, Layers: [][]byte{Layer(t, map[string]string{generate.LuaSchemaPath: "arm64"})}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.MediaType != oci.MediaTypeOCIIndex {
+		t.Fatalf("want: %s != got: %s", oci.MediaTypeOCIIndex, desc.MediaType)
+	}
+
+	client := r.OCIClient()
+	ctx := context.Background()
+
+	list, err := client.Manifest(ctx, index.CoreFluentBitRepository, "24.7.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !list.IsIndex() || list.Digest != desc.Digest || len(list.Manifests) != 2 {
+		t.Fatalf("want: index %s of 2 images != got: %+v", desc.Digest, list)
+	}
+
+	manifest, err := client.ImageManifest(ctx, index.CoreFluentBitRepository, "24.7.1", "linux/amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Digest != list.Manifests[0].Digest || len(manifest.Layers) != 2 {
+		t.Fatalf("want: image %s of 2 layers != got: %+v", list.Manifests[0].Digest, manifest)
+	}
+
+	platform, err := client.ConfigPlatform(ctx, index.CoreFluentBitRepository, *manifest.Config)
+	if err != nil || platform != amd64 {
+		t.Fatalf("want: %v != got: %v (%v)", amd64, platform, err)
+	}
+
+	files, err := client.ReadFiles(ctx, index.CoreFluentBitRepository, manifest, generate.LuaSchemaPath, "/etc/hostname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]byte{generate.LuaSchemaPath: []byte("amd64"), "/etc/hostname": []byte("fluent-bit")}
+	if !reflect.DeepEqual(want, files) {
+		t.Fatalf("want: %s != got: %s", want, files)
+	}
+
+	_, err = client.ImageManifest(ctx, index.CoreFluentBitRepository, "24.7.1", "linux/s390x")
+	if !errors.Is(err, oci.ErrNotFound) {
+		t.Fatalf("want: %v != got: %v", oci.ErrNotFound, err)
+	}
+
+	r.Delete(index.CoreFluentBitRepository, "24.7.1")
+	if _, err := client.Manifest(ctx, index.CoreFluentBitRepository, "24.7.1"); !errors.Is(err, oci.ErrNotFound) {
+		t.Fatalf("want: %v != got: %v", oci.ErrNotFound, err)
+	}
+	// Manifests stay reachable by digest.
+	if _, err := client.Manifest(ctx, index.CoreFluentBitRepository, desc.Digest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistry_tampered(t *testing.T) {
+	r := NewRegistry(t, Config{})
+	desc, err := r.Push(index.CoreFluentBitRepository, "24.7.1", Image{Platform: amd64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := r.OCIClient()
+	ctx := context.Background()
+
+	manifest, err := client.Manifest(ctx, index.CoreFluentBitRepository, "24.7.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ReplaceBlob(index.CoreFluentBitRepository, manifest.Config.Digest, []byte(`{"os":"linux","architecture":"arm64"}`))
+	if _, err := client.ConfigPlatform(ctx, index.CoreFluentBitRepository, *manifest.Config); err == nil {
+		t.Fatal("want: config digest mismatch != got: nil")
+	}
+	r.ReplaceBlob(index.CoreFluentBitRepository, manifest.Config.Digest, make([]byte, 5<<20))
+	if _, err := client.ConfigPlatform(ctx, index.CoreFluentBitRepository, *manifest.Config); err == nil {
+		t.Fatal("want: oversized config != got: nil")
+	}
+
+	r.ServeDigest(index.CoreFluentBitRepository, "24.7.1", oci.Digest([]byte("other")))
+	if _, err := client.Manifest(ctx, index.CoreFluentBitRepository, "24.7.1"); err == nil {
+		t.Fatal("want: manifest digest mismatch != got: nil")
+	}
+	if _, err := client.Manifest(ctx, index.CoreFluentBitRepository, desc.Digest); err == nil {
+		t.Fatal("want: manifest digest mismatch != got: nil")
+	}
+
+	// An index listing platforms of 3MB each is over the 4MB limit.
+	variant := strings.Repeat("v", 3<<20)
+	_, err = r.Push(index.CoreFluentBitRepository, "24.7.2",
+		Image{Platform: oci.Platform{OS: "linux", Architecture: "amd64", Variant: variant}},
+		Image{Platform: oci.Platform{OS: "linux", Architecture: "arm64", Variant: variant}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Manifest(ctx, index.CoreFluentBitRepository, "24.7.2"); err == nil {
+		t.Fatal("want: oversized manifest != got: nil")
+	}
+}
+
+func TestRegistry_docker(t *testing.T) {
+	r := NewRegistry(t, Config{Repositories: map[string]Repository{
+		index.ContainerRepository: {Tags: map[string][]Image{
+			"v24.7.1": {{Platform: amd64, Docker: true}, {Platform: arm64, Docker: true}},
+			"v24.7.0": {{Platform: amd64, Docker: true}},
+		}},
+	}})
+	client := r.OCIClient()
+	ctx := context.Background()
+
+	list, err := client.Manifest(ctx, index.ContainerRepository, "v24.7.1")
+	if err != nil || list.MediaType != oci.MediaTypeDockerList {
+		t.Fatalf("want: %s != got: %s (%v)", oci.MediaTypeDockerList, list.MediaType, err)
+	}
+	manifest, err := client.ImageManifest(ctx, index.ContainerRepository, "v24.7.0", "linux/amd64")
+	if err != nil || manifest.MediaType != oci.MediaTypeDockerManifest {
+		t.Fatalf("want: %s != got: %s (%v)", oci.MediaTypeDockerManifest, manifest.MediaType, err)
+	}
+	if want, got := 0, r.TokenRequests(); want != got {
+		t.Fatalf("want: %d != got: %d", want, got)
+	}
+}
+
+func TestRegistry_SchemaExtractor(t *testing.T) {
+	r := NewRegistry(t, Config{Auth: true, Repositories: map[string]Repository{
+		index.CoreFluentBitRepository: {Tags: map[string][]Image{
+			"24.7.1": {{Platform: amd64, Layers: [][]byte{Layer(t, map[string]string{
+				generate.LuaSchemaPath:     `{"processingRules":{}}`,
+				generate.PluginsSchemaPath: `{"plugins":[]}`,
+			})}}},
+		}},
+	}})
+	dir := t.TempDir()
+
+	e := &generate.SchemaExtractor{Images: r.OCIClient(), Dir: dir}
+	provenance, err := e.Extract(context.Background(), "24.7.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{index.LuaSchemaFile, index.PluginsSchemaFile}
+	if !reflect.DeepEqual(want, provenance.Files) || provenance.Digest == "" {
+		t.Fatalf("want: %v with a digest != got: %+v", want, provenance)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, index.SchemaDir, "24.7.1", index.PluginsSchemaFile))
+	if err != nil || string(data) != `{"plugins":[]}` {
+		t.Fatalf("want: %s != got: %s (%v)", `{"plugins":[]}`, data, err)
+	}
+}
@@ -0,0 +1,40 @@
+package oci_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/calyptia/core-images-index/go-index/oci"
+	"github.com/calyptia/core-images-index/go-index/oci/ocitest"
+)
+
+func TestClient_Tags(t *testing.T) {
+	tags := []string{"latest", "v1.0.0", "v1.0.1", "v1.1.0", "v2.0.0"}
+	images := map[string][]ocitest.Image{}
+	for _, tag := range tags {
+		images[tag] = []ocitest.Image{{Platform: oci.Platform{OS: "linux", Architecture: "amd64"}}}
+	}
+	r := ocitest.NewRegistry(t, ocitest.Config{Auth: true, Repositories: map[string]ocitest.Repository{
+		"calyptia/core-operator": {Tags: images},
+	}})
+
+	client := r.OCIClient()
+	client.PageSize = 2
+	got, err := client.Tags(context.Background(), "calyptia/core-operator")
+	if err != nil {
+		t.Fatalf("tags: %v", err)
+	}
+	if !reflect.DeepEqual(tags, got) {
+		t.Errorf("want: %v != got: %v", tags, got)
+	}
+	if want, got := 1, r.TokenRequests(); want != got {
+		t.Errorf("token requests want: %v != got: %v", want, got)
+	}
+
+	_, err = client.Tags(context.Background(), "calyptia/unknown")
+	if !errors.Is(err, oci.ErrNotFound) {
+		t.Errorf("error: %v != %v", err, oci.ErrNotFound)
+	}
+}
@@ -2,16 +2,18 @@ package index
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
+	"io/fs"
 	"net/http"
 	"sort"
+	"time"
 
 	semver "github.com/hashicorp/go-version"
 )
 
 const (
+	OperatorIndexFile = "operator.index.json"
+
 	operatorIndexURL = "https://raw.githubusercontent.com/calyptia/core-images-index/main/operator.index.json"
 )
 
@@ -27,6 +29,9 @@ type (
 	OperatorIndex interface {
 		All(ctx context.Context) ([]string, error)
 		Last(ctx context.Context) (string, error)
+		// Latest returns the highest release followed by channel, such as
+		// ChannelStable, see Channels.
+		Latest(ctx context.Context, channel string) (string, error)
 		Match(ctx context.Context, version string) (string, error)
 	}
 
@@ -37,34 +42,63 @@ type (
 
 	OperatorIndexFetcher struct {
 		OperatorIndexFetch
+		// URL of the index, defaults to the one published on GitHub.
+		URL string
+		// Client used for the request, defaults to http.DefaultClient.
+		Client *http.Client
+		// Verifier checks the integrity of the index when set.
+		Verifier Verifier
+	}
+
+	// OperatorIndexFSFetcher reads the index from a filesystem such as a
+	// checkout of this repository or an embed.FS.
+	OperatorIndexFSFetcher struct {
+		FS fs.FS
+		// Name of the index file within FS, defaults to operator.index.json.
+		Name string
+		// Verifier checks the integrity of the index when set.
+		Verifier Verifier
 	}
 )
 
 func (c *OperatorIndexFetcher) GetImages(ctx context.Context) (OperatorImages, error) {
 	var out OperatorImages
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, operatorIndexURL, nil)
-	if err != nil {
-		return out, fmt.Errorf("cannot create a request to index %s: %w", operatorIndexURL, err)
-	}
+	err := fetchJSON(ctx, c.Client, c.url(), c.Verifier, &out)
+	return out, err
+}
 
-	client := http.DefaultClient
-	res, err := client.Do(request)
-	if err != nil {
-		return out, fmt.Errorf("could not fetch index %s: %w", operatorIndexURL, err)
+// GetReleases reads the index with the metadata of the v2 format.
+func (c *OperatorIndexFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	var out Releases
+	err := fetchJSON(ctx, c.Client, c.url(), c.Verifier, &out)
+	return out, err
+}
+
+func (c *OperatorIndexFetcher) url() string {
+	if c.URL == "" {
+		return operatorIndexURL
 	}
+	return c.URL
+}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			return
-		}
-	}(res.Body)
+func (c *OperatorIndexFSFetcher) GetImages(ctx context.Context) (OperatorImages, error) {
+	var out OperatorImages
+	err := readJSON(ctx, c.FS, c.name(), c.Verifier, &out)
+	return out, err
+}
 
-	err = json.NewDecoder(res.Body).Decode(&out)
-	if err != nil {
-		return out, fmt.Errorf("could not decode index response: %w", err)
+// GetReleases reads the index with the metadata of the v2 format.
+func (c *OperatorIndexFSFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	var out Releases
+	err := readJSON(ctx, c.FS, c.name(), c.Verifier, &out)
+	return out, err
+}
+
+func (c *OperatorIndexFSFetcher) name() string {
+	if c.Name == "" {
+		return OperatorIndexFile
 	}
-	return out, nil
+	return c.Name
 }
 
 func (c *Operator) All(ctx context.Context) ([]string, error) {
@@ -94,6 +128,8 @@ func (c *Operator) All(ctx context.Context) ([]string, error) {
 	return out, nil
 }
 
+// Match returns the tag of the index equal to version. Yanked releases are
+// reported with an *ErrVersionYanked.
 func (c *Operator) Match(ctx context.Context, version string) (string, error) {
 	orig, err := semver.NewVersion(version)
 	if err != nil {
@@ -111,6 +147,13 @@ func (c *Operator) Match(ctx context.Context, version string) (string, error) {
 			return "", err
 		}
 		if release.Equal(orig) {
+			denylist, err := fetchDenylist(ctx, c.Fetcher)
+			if err != nil {
+				return "", err
+			}
+			if err := denylist.check(imageTag); err != nil {
+				return "", err
+			}
 			return imageTag, nil
 		}
 	}
@@ -118,14 +161,114 @@ func (c *Operator) Match(ctx context.Context, version string) (string, error) {
 	return "", ErrNoMatchingImage
 }
 
+// MatchConstraint returns the highest release of the index satisfying
+// constraint, such as ">= 3.100, < 4.0". Aliases, pre-releases and yanked
+// releases are skipped.
+func (c *Operator) MatchConstraint(ctx context.Context, constraint string) (string, error) {
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	versions, err := c.All(ctx)
+	if err != nil {
+		return "", err
+	}
+	denylist, err := fetchDenylist(ctx, c.Fetcher)
+	if err != nil {
+		return "", err
+	}
+
+	versions = denylist.Filter(versions)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !releaseTag.MatchString(versions[i]) {
+			continue
+		}
+		if constraints.Check(semver.Must(semver.NewSemver(versions[i]))) {
+			return versions[i], nil
+		}
+	}
+	return "", fmt.Errorf("%w for %s", ErrNoMatchingImage, constraint)
+}
+
+// CoreFluentBitImage returns the default Core Fluent Bit image of the
+// operator release equal to version, read from the operator mappings. It
+// reports ErrNotFound when the fetcher does not publish the mappings.
+func (c *Operator) CoreFluentBitImage(ctx context.Context, version string) (string, error) {
+	f, ok := c.Fetcher.(MappingFetch)
+	if !ok {
+		return "", ErrNotFound
+	}
+	mappings, err := f.GetMappings(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot get operator mappings: %w", err)
+	}
+	return mappings.Image(version)
+}
+
+// Last returns the highest version of the index that is not yanked.
 func (c *Operator) Last(ctx context.Context) (string, error) {
 	versions, err := c.All(ctx)
 	if err != nil {
 		return "", err
 	}
+	denylist, err := fetchDenylist(ctx, c.Fetcher)
+	if err != nil {
+		return "", err
+	}
+	versions = denylist.Filter(versions)
+	if len(versions) == 0 {
+		return "", ErrNoMatchingImage
+	}
 	return versions[len(versions)-1], nil
 }
 
+// Latest returns the highest release followed by channel, as defined by
+// the channels published next to the index merged over DefaultChannels.
+// Yanked releases are skipped.
+func (c *Operator) Latest(ctx context.Context, channel string) (string, error) {
+	channels, err := fetchChannels(ctx, c.Fetcher)
+	if err != nil {
+		return "", err
+	}
+	releases, err := c.Releases(ctx)
+	if err != nil {
+		return "", err
+	}
+	denylist, err := fetchDenylist(ctx, c.Fetcher)
+	if err != nil {
+		return "", err
+	}
+	return channels.Latest(channel, denylist.withoutYanked(releases))
+}
+
+// Releases returns the entries of the index with their metadata. Only the
+// tags and channels are known when the index uses the legacy format.
+func (c *Operator) Releases(ctx context.Context) (Releases, error) {
+	out, err := fetchReleases[OperatorImages](ctx, c.Fetcher)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get operator index: %w", err)
+	}
+	return out, nil
+}
+
+// Release returns the metadata of the release matching version.
+func (c *Operator) Release(ctx context.Context, version string) (Release, error) {
+	releases, err := c.Releases(ctx)
+	if err != nil {
+		return Release{}, err
+	}
+	return releases.Match(version)
+}
+
+// Support evaluates policy at now against the releases of the index.
+func (c *Operator) Support(ctx context.Context, policy SupportPolicy, now time.Time) ([]SupportInfo, error) {
+	releases, err := c.Releases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Evaluate(releases, now)
+}
+
 func NewOperator() (*Operator, error) {
 	return &Operator{
 		Fetcher: &OperatorIndexFetcher{},
@@ -24,6 +24,9 @@ var _ OperatorIndex = &OperatorIndexMock{}
 //			LastFunc: func(ctx context.Context) (string, error) {
 //				panic("mock out the Last method")
 //			},
+//			LatestFunc: func(ctx context.Context, channel string) (string, error) {
+//				panic("mock out the Latest method")
+//			},
 //			MatchFunc: func(ctx context.Context, version string) (string, error) {
 //				panic("mock out the Match method")
 //			},
@@ -40,6 +43,9 @@ type OperatorIndexMock struct {
 	// LastFunc mocks the Last method.
 	LastFunc func(ctx context.Context) (string, error)
 
+	// LatestFunc mocks the Latest method.
+	LatestFunc func(ctx context.Context, channel string) (string, error)
+
 	// MatchFunc mocks the Match method.
 	MatchFunc func(ctx context.Context, version string) (string, error)
 
@@ -55,6 +61,13 @@ type OperatorIndexMock struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
+		// Latest holds details about calls to the Latest method.
+		Latest []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Channel is the channel argument value.
+			Channel string
+		}
 		// Match holds details about calls to the Match method.
 		Match []struct {
 			// Ctx is the ctx argument value.
@@ -63,9 +76,10 @@ type OperatorIndexMock struct {
 			Version string
 		}
 	}
-	lockAll   sync.RWMutex
-	lockLast  sync.RWMutex
-	lockMatch sync.RWMutex
+	lockAll    sync.RWMutex
+	lockLast   sync.RWMutex
+	lockLatest sync.RWMutex
+	lockMatch  sync.RWMutex
 }
 
 // All calls AllFunc.
@@ -132,6 +146,42 @@ func (mock *OperatorIndexMock) LastCalls() []struct {
 	return calls
 }
 
+// Latest calls LatestFunc.
+func (mock *OperatorIndexMock) Latest(ctx context.Context, channel string) (string, error) {
+	if mock.LatestFunc == nil {
+		panic("OperatorIndexMock.LatestFunc: method is nil but OperatorIndex.Latest was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Channel string
+	}{
+		Ctx:     ctx,
+		Channel: channel,
+	}
+	mock.lockLatest.Lock()
+	mock.calls.Latest = append(mock.calls.Latest, callInfo)
+	mock.lockLatest.Unlock()
+	return mock.LatestFunc(ctx, channel)
+}
+
+// LatestCalls gets all the calls that were made to Latest.
+// Check the length with:
+//
+//	len(mockedOperatorIndex.LatestCalls())
+func (mock *OperatorIndexMock) LatestCalls() []struct {
+	Ctx     context.Context
+	Channel string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Channel string
+	}
+	mock.lockLatest.RLock()
+	calls = mock.calls.Latest
+	mock.lockLatest.RUnlock()
+	return calls
+}
+
 // Match calls MatchFunc.
 func (mock *OperatorIndexMock) Match(ctx context.Context, version string) (string, error) {
 	if mock.MatchFunc == nil {
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"reflect"
 	"testing"
+	"testing/fstest"
 )
 
 func TestOperator_Match(t *testing.T) {
@@ -206,3 +207,71 @@ func TestOperator_Last(t *testing.T) {
 		})
 	}
 }
+
+func TestOperator_MatchConstraint(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		OperatorIndexFile:  &fstest.MapFile{Data: []byte(`["v1.0.0","v1.1.0","v1.2.0","v1.3.0-rc1","v2.0.0","v1"]`)},
+		OperatorYankedFile: &fstest.MapFile{Data: []byte(`[{"version":"v1.2.0"}]`)},
+	}
+	operator := &Operator{Fetcher: &OperatorIndexFSFetcher{FS: fsys}}
+
+	for constraint, want := range map[string]string{
+		">= 1.0, < 2.0": "v1.1.0",
+		"~> 1.0.0":      "v1.0.0",
+		">= 1.0":        "v2.0.0",
+	} {
+		got, err := operator.MatchConstraint(ctx, constraint)
+		if err != nil {
+			t.Fatalf("%s: %v", constraint, err)
+		}
+		if want != got {
+			t.Errorf("want: %v != got: %v", want, got)
+		}
+	}
+
+	if _, err := operator.MatchConstraint(ctx, "> 2.0"); !errors.Is(err, ErrNoMatchingImage) {
+		t.Errorf("want: %v != got: %v", ErrNoMatchingImage, err)
+	}
+	if _, err := operator.MatchConstraint(ctx, "not a constraint"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestOperator_CoreFluentBitImage(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		OperatorIndexFile: &fstest.MapFile{Data: []byte(`["v1.0.0","v1.1.0"]`)},
+		OperatorMappingsFile: &fstest.MapFile{Data: []byte(`{
+			"v1.1.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:",
+			"v1.0.0": "ghcr.io/calyptia/core/calyptia-fluent-bit:23.1.0",
+			"latest": "ghcr.io/calyptia/core/calyptia-fluent-bit:"
+		}`)},
+	}
+	operator := &Operator{Fetcher: &OperatorIndexFSFetcher{FS: fsys}}
+
+	got, err := operator.CoreFluentBitImage(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ghcr.io/calyptia/core/calyptia-fluent-bit:23.1.0"; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+
+	for _, version := range []string{"v1.1.0", "v3.0.0"} {
+		if _, err := operator.CoreFluentBitImage(ctx, version); !errors.Is(err, ErrNoMatchingImage) {
+			t.Errorf("%s: want: %v != got: %v", version, ErrNoMatchingImage, err)
+		}
+	}
+	if _, err := operator.CoreFluentBitImage(ctx, "v1.1.0"); !errors.Is(err, ErrUntaggedImage) {
+		t.Errorf("want: %v != got: %v", ErrUntaggedImage, err)
+	}
+	if _, err := operator.CoreFluentBitImage(ctx, "v3.0.0"); errors.Is(err, ErrUntaggedImage) {
+		t.Errorf("want: not %v != got: %v", ErrUntaggedImage, err)
+	}
+
+	mock := &Operator{Fetcher: &OperatorIndexFetchMock{}}
+	if _, err := mock.CoreFluentBitImage(ctx, "v1.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want: %v != got: %v", ErrNotFound, err)
+	}
+}
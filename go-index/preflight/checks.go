@@ -0,0 +1,262 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/calyptia/core-images-index/go-index/clusternet"
+	"github.com/calyptia/core-images-index/go-index/install"
+)
+
+const (
+	selinuxConfig = "/etc/selinux/config"
+	fipsEnabled   = "/proc/sys/crypto/fips_enabled"
+)
+
+var selinuxEnforcing = regexp.MustCompile(`(?m)^\s*SELINUX=enforcing`)
+
+func checkSystem(ctx context.Context, host Host, cfg Config) []Result {
+	var out []Result
+	switch host.OS {
+	case "linux":
+		out = append(out, pass("Linux OS detected"))
+	case "darwin":
+		out = append(out, fatal("macOS system detected, please use Docker Desktop with the Calyptia Core extension"))
+	case "windows":
+		out = append(out, fatal("Windows OS detected, not supported by this installation method"))
+	default:
+		out = append(out, ignorable("Unknown OS %s detected, confirm it is supported", host.OS))
+	}
+
+	if host.installed("curl") {
+		out = append(out, pass("Found curl"))
+	} else {
+		out = append(out, fatal("No curl command present, please install"))
+	}
+	if info, _ := host.stat("/bin/systemctl"); info != nil || host.installed("systemctl") {
+		out = append(out, pass("Found systemctl"))
+	} else {
+		out = append(out, fatal("Can not find systemctl, unsupported platform"))
+	}
+
+	return append(out, checkUsers(ctx, host, cfg)...)
+}
+
+func checkUsers(ctx context.Context, host Host, cfg Config) []Result {
+	var out []Result
+	if uid, err := host.output(ctx, "id", "-u"); err == nil && uid == "0" {
+		out = append(out, warn("Running as root is not generally recommended"))
+	}
+	if cfg.User == "" || cfg.User == "root" {
+		out = append(out, warn("Not provisioning any additional user, suggestion is to provide a dedicated user"))
+	}
+
+	if cfg.User != "" {
+		if _, err := host.output(ctx, "id", cfg.User); err != nil {
+			out = append(out, ignorable("%s user not found, please create in advance", cfg.User))
+		} else {
+			out = append(out, pass("%s user found", cfg.User))
+		}
+	}
+	if cfg.Group != "" {
+		if _, err := host.output(ctx, "getent", "group", cfg.Group); err != nil {
+			out = append(out, ignorable("%s group not found, please create in advance", cfg.Group))
+		} else {
+			out = append(out, pass("%s group found", cfg.Group))
+		}
+	}
+
+	info, err := host.stat(cfg.CoreDir)
+	switch {
+	case err != nil:
+		out = append(out, ignorable("Cannot check the Calyptia Core directory %s: %v", cfg.CoreDir, err))
+	case info != nil && info.IsDir():
+		out = append(out, ignorable("Found existing Calyptia Core directory: %s", cfg.CoreDir))
+	}
+	return out
+}
+
+func checkSELinux(ctx context.Context, host Host, _ Config) []Result {
+	if !host.installed("getenforce") {
+		config, err := host.readFile(selinuxConfig)
+		if err == nil && selinuxEnforcing.Match(config) {
+			return []Result{ignorable("SELinux enabled in enforcing mode")}
+		}
+		return []Result{pass("SELinux disabled")}
+	}
+
+	mode, err := host.output(ctx, "getenforce")
+	if err != nil {
+		return []Result{ignorable("Cannot get the SELinux mode: %v", err)}
+	}
+	switch strings.ToLower(mode) {
+	case "disabled":
+		return []Result{pass("SELinux disabled")}
+	case "enforcing":
+		return []Result{ignorable("SELinux enabled in enforcing mode")}
+	default:
+		return []Result{warn("SELinux enabled but not in enforcing mode")}
+	}
+}
+
+func checkCrypto(ctx context.Context, host Host, _ Config) []Result {
+	if !host.installed("update-crypto-policies") {
+		return []Result{skip("No system wide crypto policy")}
+	}
+
+	policy, err := host.output(ctx, "update-crypto-policies", "--show")
+	if err != nil {
+		return []Result{ignorable("Cannot get the crypto policy: %v", err)}
+	}
+	if strings.HasPrefix(policy, "DEFAULT") || strings.HasPrefix(policy, "LEGACY") {
+		return []Result{pass("Crypto policy set to %s", policy)}
+	}
+	return []Result{ignorable("Crypto policy set to %s, may fail to download components", policy)}
+}
+
+func checkFIPS(_ context.Context, host Host, _ Config) []Result {
+	enabled, err := host.readFile(fipsEnabled)
+	if err == nil && bytes.Contains(enabled, []byte("1")) {
+		return []Result{ignorable("FIPS mode enabled")}
+	}
+	return []Result{pass("FIPS mode not enabled")}
+}
+
+func checkFirewall(ctx context.Context, host Host, _ Config) []Result {
+	const enabled = "Firewall is enabled, please ensure outbound rules are correctly configured from docs"
+
+	if host.installed("ufw") {
+		status, err := host.output(ctx, "ufw", "status")
+		switch {
+		case err != nil:
+			// ufw needs root, the script runs it with sudo.
+			return []Result{warn("Cannot get the firewall status, please ensure outbound rules are correctly configured from docs: %v", err)}
+		case strings.Contains(strings.ToLower(status), "inactive"):
+			return []Result{pass("Firewall disabled")}
+		}
+		return []Result{ignorable(enabled)}
+	}
+
+	for _, unit := range []string{"firewalld", "netfilter-persistent"} {
+		for _, verb := range []string{"is-enabled", "is-active"} {
+			if _, err := host.output(ctx, "systemctl", verb, unit); err == nil {
+				return []Result{ignorable("%s: %s", enabled, unit)}
+			}
+		}
+	}
+	return []Result{pass("Firewall not detected")}
+}
+
+func checkCIDR(ctx context.Context, host Host, cfg Config) []Result {
+	var out []Result
+	switch {
+	case !host.installed("nslookup"):
+		out = append(out, warn("Unable to find nslookup to check resolution of 8.8.8.8 for Core DNS - only relevant in some situations (see k3s docs)"))
+	default:
+		if _, err := host.output(ctx, "nslookup", "8.8.8.8"); err != nil {
+			out = append(out, warn("Unable to use 8.8.8.8 as a DNS server for Core DNS - only relevant in some situations (see k3s docs)"))
+		}
+	}
+
+	network, err := host.network()
+	if err != nil {
+		out = append(out, warn("Cannot read the networks of the host: %v", err))
+	}
+	plan := clusternet.Plan{
+		ClusterCIDR:   cfg.ClusterCIDR,
+		ServiceCIDR:   cfg.ServiceCIDR,
+		ClusterDNS:    cfg.ClusterDNS,
+		NodePortRange: cfg.NodePortRange,
+	}
+	diagnostics := clusternet.Validate(plan, network)
+	for _, d := range diagnostics {
+		if d.Severity == clusternet.SeverityError {
+			out = append(out, ignorable("%s: %s", d.Field, d.Message))
+		} else {
+			out = append(out, warn("%s: %s", d.Field, d.Message))
+		}
+	}
+
+	if len(diagnostics) == 0 {
+		out = append(out, pass("Cluster CIDR %s, service CIDR %s and cluster DNS %s are valid", cfg.ClusterCIDR, cfg.ServiceCIDR, cfg.ClusterDNS))
+	}
+	return out
+}
+
+func checkLocalPackages(_ context.Context, host Host, cfg Config) []Result {
+	if cfg.LocalPackage == "" {
+		return []Result{skip("No local package, the package of %s will be downloaded", cfg.ReleaseVersion)}
+	}
+
+	info, err := host.stat(cfg.LocalPackage)
+	switch {
+	case err != nil:
+		return []Result{fatal("Cannot read local package %s: %v", cfg.LocalPackage, err)}
+	case info == nil:
+		return []Result{fatal("Missing local package: %s", cfg.LocalPackage)}
+	case !info.IsDir():
+		return []Result{pass("Local package file found: %s", cfg.LocalPackage)}
+	}
+
+	out := []Result{pass("Local package directory found: %s", cfg.LocalPackage)}
+	family, err := hostOSFamily(host)
+	if err != nil {
+		return append(out, fatal("%v", err))
+	}
+	dir, err := fs.Sub(host.FS, hostPath(cfg.LocalPackage))
+	if err != nil {
+		return append(out, fatal("Cannot read local package directory %s: %v", cfg.LocalPackage, err))
+	}
+	report, err := install.VerifyPackages(dir, install.VerifyRequest{
+		OS:         []install.OSFamily{family},
+		Arch:       cfg.Arch,
+		Flavor:     install.Flavor(cfg.PackageNamePrefix),
+		Version:    cfg.ReleaseVersion,
+		RPMRelease: cfg.RPMRelease,
+	})
+	if err != nil {
+		return append(out, fatal("%v", err))
+	}
+
+	// Like install-core.sh, a missing package is only a warning, while an
+	// invalid one would fail the installation.
+	for _, problem := range report.Problems {
+		out = append(out, fatal("Invalid local packages: %s", problem))
+	}
+	for _, pkg := range report.Packages {
+		expected := path.Join(cfg.LocalPackage, pkg.Filename)
+		switch {
+		case !pkg.Found:
+			out = append(out, warn("Unable to find local package file: %s", expected))
+		case pkg.Problems != nil:
+			out = append(out, fatal("Invalid local package file %s: %s", expected, strings.Join(pkg.Problems, ", ")))
+		case pkg.Unverified != "":
+			// The script installs the package without reading it.
+			out = append(out, warn("Cannot verify local package file %s: %s", expected, pkg.Unverified))
+		case report.Checksums != "":
+			out = append(out, pass("Local package file verified with %s: %s", report.Checksums, expected))
+		default:
+			out = append(out, pass("Local package file verified: %s", expected))
+		}
+	}
+	return out
+}
+
+// hostOSFamily returns the OS family of the package manager of the host.
+func hostOSFamily(host Host) (install.OSFamily, error) {
+	switch {
+	case host.installed("dpkg"):
+		return install.OSDebian, nil
+	case host.installed("rpm"):
+		return install.OSRHEL, nil
+	case host.installed("apk"):
+		return install.OSAlpine, nil
+	default:
+		return "", errors.New("unknown OS, no dpkg, rpm or apk tool")
+	}
+}
@@ -0,0 +1,279 @@
+package preflight
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/calyptia/core-images-index/go-index/install/installtest"
+)
+
+func TestChecks(t *testing.T) {
+	tt := []struct {
+		name    string
+		check   func(context.Context, Host, Config) []Result
+		files   fstest.MapFS
+		outputs map[string]string
+		os      string
+		cfg     func(*Config)
+		want    []Result
+	}{
+		{
+			name:  "system on macOS without tools",
+			check: checkSystem,
+			os:    "darwin",
+			files: fstest.MapFS{"opt/calyptia": {Mode: fs.ModeDir | 0o755}},
+			outputs: map[string]string{
+				"id -u":            "0",
+				"id root":          "uid=0(root)",
+				"getent group ops": "error",
+			},
+			cfg: func(c *Config) { c.User, c.Group = "root", "ops" },
+			want: []Result{
+				fatal("macOS system detected, please use Docker Desktop with the Calyptia Core extension"),
+				fatal("No curl command present, please install"),
+				fatal("Can not find systemctl, unsupported platform"),
+				warn("Running as root is not generally recommended"),
+				warn("Not provisioning any additional user, suggestion is to provide a dedicated user"),
+				pass("root user found"),
+				ignorable("ops group not found, please create in advance"),
+				ignorable("Found existing Calyptia Core directory: /opt/calyptia"),
+			},
+		},
+		{
+			name:    "selinux enforcing",
+			check:   checkSELinux,
+			outputs: map[string]string{"getenforce": "Enforcing\n"},
+			want:    []Result{ignorable("SELinux enabled in enforcing mode")},
+		},
+		{
+			name:    "selinux permissive",
+			check:   checkSELinux,
+			outputs: map[string]string{"getenforce": "Permissive"},
+			want:    []Result{warn("SELinux enabled but not in enforcing mode")},
+		},
+		{
+			name:  "selinux config without getenforce",
+			check: checkSELinux,
+			files: fstest.MapFS{"etc/selinux/config": {Data: []byte("# SELINUX=disabled\n  SELINUX=enforcing\n")}},
+			want:  []Result{ignorable("SELinux enabled in enforcing mode")},
+		},
+		{
+			name:  "selinux absent",
+			check: checkSELinux,
+			want:  []Result{pass("SELinux disabled")},
+		},
+		{
+			name:    "crypto legacy",
+			check:   checkCrypto,
+			outputs: map[string]string{"update-crypto-policies --show": "LEGACY:AD-SUPPORT\n"},
+			want:    []Result{pass("Crypto policy set to LEGACY:AD-SUPPORT")},
+		},
+		{
+			name:    "crypto future",
+			check:   checkCrypto,
+			outputs: map[string]string{"update-crypto-policies --show": "FUTURE"},
+			want:    []Result{ignorable("Crypto policy set to FUTURE, may fail to download components")},
+		},
+		{
+			name:  "crypto without policies",
+			check: checkCrypto,
+			want:  []Result{skip("No system wide crypto policy")},
+		},
+		{
+			name:  "fips enabled",
+			check: checkFIPS,
+			files: fstest.MapFS{"proc/sys/crypto/fips_enabled": {Data: []byte("1\n")}},
+			want:  []Result{ignorable("FIPS mode enabled")},
+		},
+		{
+			name:  "fips disabled",
+			check: checkFIPS,
+			files: fstest.MapFS{"proc/sys/crypto/fips_enabled": {Data: []byte("0\n")}},
+			want:  []Result{pass("FIPS mode not enabled")},
+		},
+		{
+			name:    "ufw inactive",
+			check:   checkFirewall,
+			outputs: map[string]string{"ufw status": "Status: inactive"},
+			want:    []Result{pass("Firewall disabled")},
+		},
+		{
+			name:    "ufw active",
+			check:   checkFirewall,
+			outputs: map[string]string{"ufw status": "Status: active"},
+			want:    []Result{ignorable("Firewall is enabled, please ensure outbound rules are correctly configured from docs")},
+		},
+		{
+			name:    "ufw without root",
+			check:   checkFirewall,
+			outputs: map[string]string{"ufw status": "error"},
+			want:    []Result{warn("Cannot get the firewall status, please ensure outbound rules are correctly configured from docs: exit status 1")},
+		},
+		{
+			name:    "firewalld active",
+			check:   checkFirewall,
+			outputs: map[string]string{"systemctl is-enabled firewalld": "error", "systemctl is-active firewalld": "active"},
+			want:    []Result{ignorable("Firewall is enabled, please ensure outbound rules are correctly configured from docs: firewalld")},
+		},
+		{
+			name:  "no firewall",
+			check: checkFirewall,
+			want:  []Result{pass("Firewall not detected")},
+		},
+		{
+			name:    "cidr valid",
+			check:   checkCIDR,
+			files:   fstest.MapFS{"etc/resolv.conf": {Data: []byte("search local\nnameserver 10.4.0.2\nnameserver fe80::1%eth0\n")}},
+			outputs: map[string]string{"nslookup 8.8.8.8": ""},
+			want:    []Result{pass("Cluster CIDR 10.42.0.0/16, service CIDR 10.43.0.0/16 and cluster DNS 10.43.0.10 are valid")},
+		},
+		{
+			// The prefix 10.4 of 10.42.0.0/16 is found by install-core.sh in
+			// 10.4.0.2 although the ranges do not overlap.
+			name:  "cidr conflicts",
+			check: checkCIDR,
+			files: fstest.MapFS{
+				"etc/resolv.conf": {Data: []byte("nameserver 10.42.1.1\nnameserver 10.4.0.2\n")},
+				"proc/net/route": {Data: []byte("Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+					"eth0\t00000000\t0100040A\t0003\t0\t0\t0\t00000000\t0\t0\t0\n" +
+					"eth0\t0000040A\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n" +
+					"tun0\t00002B0A\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n")},
+				"proc/net/ipv6_route": {Data: []byte("fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0\n")},
+			},
+			cfg: func(c *Config) { c.ClusterDNS = "10.42.0.10" },
+			want: []Result{
+				warn("Unable to find nslookup to check resolution of 8.8.8.8 for Core DNS - only relevant in some situations (see k3s docs)"),
+				ignorable("cluster-cidr: cluster CIDR 10.42.0.0/16 contains the nameserver 10.42.1.1, which pods could not reach"),
+				ignorable("service-cidr: service CIDR 10.43.0.0/16 overlaps the route to 10.43.0.0/16"),
+				ignorable("cluster-dns: cluster DNS 10.42.0.10 is not in the service CIDR 10.43.0.0/16"),
+			},
+		},
+		{
+			name:    "cidr invalid",
+			check:   checkCIDR,
+			outputs: map[string]string{"nslookup 8.8.8.8": "error"},
+			cfg:     func(c *Config) { c.ClusterCIDR, c.ClusterDNS, c.NodePortRange = "10.42", "dns", "80-90" },
+			want: []Result{
+				warn("Unable to use 8.8.8.8 as a DNS server for Core DNS - only relevant in some situations (see k3s docs)"),
+				ignorable(`cluster-cidr: invalid CIDR "10.42": netip.ParsePrefix("10.42"): no '/'`),
+				ignorable(`cluster-dns: invalid cluster DNS address "dns": ParseAddr("dns"): unable to parse IP`),
+				ignorable(`service-node-port-range: port range "80-90" includes well known ports below 1024`),
+			},
+		},
+		{
+			name:  "no local package",
+			check: checkLocalPackages,
+			want:  []Result{skip("No local package, the package of 3.119.0 will be downloaded")},
+		},
+		{
+			name:  "local package file",
+			check: checkLocalPackages,
+			files: fstest.MapFS{"tmp/core.deb": {}},
+			cfg:   func(c *Config) { c.LocalPackage = "/tmp/core.deb" },
+			want:  []Result{pass("Local package file found: /tmp/core.deb")},
+		},
+		{
+			name:  "missing local package",
+			check: checkLocalPackages,
+			cfg:   func(c *Config) { c.LocalPackage = "/tmp/core.deb" },
+			want:  []Result{fatal("Missing local package: /tmp/core.deb")},
+		},
+		{
+			name:    "local package directory with apk",
+			check:   checkLocalPackages,
+			files:   fstest.MapFS{"pkgs/calyptia-core-operator_3.119.0_arm64.apk": {Data: installtest.APK(t, "calyptia-core-operator", "3.119.0-r0", "aarch64")}},
+			outputs: map[string]string{"apk": ""},
+			cfg:     func(c *Config) { c.LocalPackage, c.Arch = "/pkgs", "aarch64" },
+			want: []Result{
+				pass("Local package directory found: /pkgs"),
+				pass("Local package file verified: /pkgs/calyptia-core-operator_3.119.0_arm64.apk"),
+			},
+		},
+		{
+			name:  "local package directory with checksums",
+			check: checkLocalPackages,
+			files: fstest.MapFS{
+				"pkgs/calyptia-core-operator_3.119.0_amd64.apk": {Data: installtest.APK(t, "calyptia-core-operator", "3.119.0-r0", "x86_64")},
+				"pkgs/SHA256SUMS": {Data: []byte(strings.Repeat("0", 64) + "  calyptia-core-operator_3.119.0_amd64.apk\n")},
+			},
+			outputs: map[string]string{"apk": ""},
+			cfg:     func(c *Config) { c.LocalPackage, c.Arch = "/pkgs", "amd64" },
+			want: []Result{
+				pass("Local package directory found: /pkgs"),
+				fatal("Invalid local package file /pkgs/calyptia-core-operator_3.119.0_amd64.apk: checksum mismatch: want sha256 %s, got %s",
+					strings.Repeat("0", 64), installtest.SHA256Hex(installtest.APK(t, "calyptia-core-operator", "3.119.0-r0", "x86_64"))),
+			},
+		},
+		{
+			name:  "local package directory with xz deb",
+			check: checkLocalPackages,
+			files: fstest.MapFS{
+				"pkgs/calyptia-core-operator_3.119.0_amd64.deb": {Data: installtest.DebControl(t, "control.tar.xz", []byte("xz"))},
+			},
+			outputs: map[string]string{"dpkg": ""},
+			cfg:     func(c *Config) { c.LocalPackage, c.Arch = "/pkgs", "amd64" },
+			want: []Result{
+				pass("Local package directory found: /pkgs"),
+				warn("Cannot verify local package file /pkgs/calyptia-core-operator_3.119.0_amd64.deb: invalid deb package: unsupported compression of control.tar.xz"),
+			},
+		},
+		{
+			name:    "local package directory with invalid rpm",
+			check:   checkLocalPackages,
+			files:   fstest.MapFS{"pkgs/calyptia-core-operator-3.119.0-1.aarch64.rpm": {}},
+			outputs: map[string]string{"rpm": ""},
+			cfg:     func(c *Config) { c.LocalPackage, c.Arch = "/pkgs", "aarch64" },
+			want: []Result{
+				pass("Local package directory found: /pkgs"),
+				fatal("Invalid local package file /pkgs/calyptia-core-operator-3.119.0-1.aarch64.rpm: invalid rpm package: truncated lead"),
+			},
+		},
+		{
+			name:    "local package directory without deb",
+			check:   checkLocalPackages,
+			files:   fstest.MapFS{"pkgs/other.deb": {}},
+			outputs: map[string]string{"dpkg": ""},
+			cfg:     func(c *Config) { c.LocalPackage, c.Arch = "/pkgs", "amd64" },
+			want: []Result{
+				pass("Local package directory found: /pkgs"),
+				warn("Unable to find local package file: /pkgs/calyptia-core-operator_3.119.0_amd64.deb"),
+			},
+		},
+		{
+			name:  "local package directory without package manager",
+			check: checkLocalPackages,
+			files: fstest.MapFS{"pkgs/other.deb": {}},
+			cfg:   func(c *Config) { c.LocalPackage, c.Arch = "/pkgs", "amd64" },
+			want: []Result{
+				pass("Local package directory found: /pkgs"),
+				fatal("unknown OS, no dpkg, rpm or apk tool"),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			files := tc.files
+			if files == nil {
+				files = fstest.MapFS{}
+			}
+			host := fakeHost(files, tc.outputs)
+			if tc.os != "" {
+				host.OS = tc.os
+			}
+			cfg := DefaultConfig()
+			if tc.cfg != nil {
+				tc.cfg(&cfg)
+			}
+
+			got := tc.check(context.Background(), host, cfg)
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %+v != got: %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+)
+
+type (
+	// Host is the machine the checks inspect.
+	Host struct {
+		// FS is the root filesystem, paths are looked up without the leading
+		// slash, such as etc/resolv.conf.
+		FS     fs.FS
+		Runner Runner
+		// Interfaces returns the addresses of the network interfaces,
+		// loopback excluded. None are checked when nil.
+		Interfaces func() ([]netip.Prefix, error)
+		// OS as reported by runtime.GOOS.
+		OS string
+	}
+
+	//go:generate moq -out runner_mock.go . Runner
+	Runner interface {
+		// LookPath reports where the command is installed, like command -v.
+		LookPath(name string) (string, error)
+		// Output runs the command and returns its standard output. A non zero
+		// exit status is an error.
+		Output(ctx context.Context, name string, args ...string) ([]byte, error)
+	}
+
+	// ExecRunner runs the commands of the local machine.
+	ExecRunner struct{}
+)
+
+// LocalHost returns the machine running this process.
+func LocalHost() Host {
+	return Host{
+		FS:         os.DirFS("/"),
+		Runner:     ExecRunner{},
+		Interfaces: localInterfaces,
+		OS:         runtime.GOOS,
+	}
+}
+
+func (ExecRunner) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+func (ExecRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// installed reports whether the command is found on the host.
+func (h Host) installed(name string) bool {
+	_, err := h.Runner.LookPath(name)
+	return err == nil
+}
+
+// output runs the command and returns its trimmed standard output.
+func (h Host) output(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := h.Runner.Output(ctx, name, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// readFile reads the absolute path name from the host filesystem.
+func (h Host) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(h.FS, hostPath(name))
+}
+
+// stat returns the file info of the absolute path name, nil when it does not
+// exist.
+func (h Host) stat(name string) (fs.FileInfo, error) {
+	info, err := fs.Stat(h.FS, hostPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return info, err
+}
+
+func hostPath(name string) string {
+	p := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
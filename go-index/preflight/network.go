@@ -0,0 +1,158 @@
+package preflight
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"math/bits"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/calyptia/core-images-index/go-index/clusternet"
+)
+
+const (
+	resolvConf = "/etc/resolv.conf"
+	ipv4Routes = "/proc/net/route"
+	ipv6Routes = "/proc/net/ipv6_route"
+)
+
+// network returns the networks of the host the cluster CIDRs must not
+// overlap. Files missing from the host are skipped.
+func (h Host) network() (clusternet.HostNetwork, error) {
+	var (
+		out  clusternet.HostNetwork
+		errs []error
+	)
+	if h.Interfaces != nil {
+		ifaces, err := h.Interfaces()
+		out.Interfaces = ifaces
+		errs = append(errs, err)
+	}
+
+	routes, err := h.readRoutes(ipv4Routes, parseIPv4Route)
+	out.Routes = append(out.Routes, routes...)
+	errs = append(errs, err)
+	routes, err = h.readRoutes(ipv6Routes, parseIPv6Route)
+	out.Routes = append(out.Routes, routes...)
+	errs = append(errs, err)
+
+	out.Nameservers, err = h.readNameservers()
+	errs = append(errs, err)
+	return out, errors.Join(errs...)
+}
+
+// readNameservers returns the nameservers listed in resolv.conf.
+func (h Host) readNameservers() ([]netip.Addr, error) {
+	var out []netip.Addr
+	err := h.scanLines(resolvConf, func(fields []string) {
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			return
+		}
+		// Drop the zone of link-local IPv6 nameservers such as fe80::1%eth0.
+		if addr, err := netip.ParseAddr(fields[1]); err == nil {
+			out = append(out, addr.WithZone(""))
+		}
+	})
+	return out, err
+}
+
+// readRoutes returns the destinations of the routing table name, loopback
+// routes excluded.
+func (h Host) readRoutes(name string, parse func(fields []string) (netip.Prefix, bool)) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	err := h.scanLines(name, func(fields []string) {
+		if route, ok := parse(fields); ok && !route.Addr().IsLoopback() {
+			out = append(out, route)
+		}
+	})
+	return out, err
+}
+
+// scanLines calls fn with the fields of every line of the file name, if it
+// exists.
+func (h Host) scanLines(name string, fn func(fields []string)) error {
+	data, err := h.readFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fn(strings.Fields(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// parseIPv4Route parses a line of /proc/net/route, whose destination and mask
+// are hexadecimal in host byte order, little endian on supported platforms.
+func parseIPv4Route(fields []string) (netip.Prefix, bool) {
+	const destination, mask = 1, 7
+	if len(fields) <= mask || fields[0] == "Iface" {
+		return netip.Prefix{}, false
+	}
+	dst, err := strconv.ParseUint(fields[destination], 16, 32)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	m, err := strconv.ParseUint(fields[mask], 16, 32)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+
+	var addr [4]byte
+	binary.LittleEndian.PutUint32(addr[:], uint32(dst))
+	return netip.PrefixFrom(netip.AddrFrom4(addr), bits.OnesCount32(uint32(m))), true
+}
+
+// parseIPv6Route parses a line of /proc/net/ipv6_route, starting with the
+// destination in hexadecimal and its prefix length.
+func parseIPv6Route(fields []string) (netip.Prefix, bool) {
+	if len(fields) < 2 {
+		return netip.Prefix{}, false
+	}
+	dst, err := hex.DecodeString(fields[0])
+	if err != nil || len(dst) != net.IPv6len {
+		return netip.Prefix{}, false
+	}
+	length, err := strconv.ParseUint(fields[1], 16, 8)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(netip.AddrFrom16([16]byte(dst)), int(length)), true
+}
+
+// localInterfaces returns the addresses of the interfaces of the machine,
+// loopback excluded.
+func localInterfaces() ([]netip.Prefix, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []netip.Prefix
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(ipnet.IP)
+		if !ok || addr.IsLoopback() {
+			continue
+		}
+		ones, size := ipnet.Mask.Size()
+		if addr.Is4In6() && size == 8*net.IPv6len {
+			ones -= 8 * (net.IPv6len - net.IPv4len)
+		}
+		out = append(out, netip.PrefixFrom(addr.Unmap(), ones))
+	}
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package preflight
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/calyptia/core-images-index/go-index/clusternet"
+)
+
+func TestHost_network(t *testing.T) {
+	host := fakeHost(fstest.MapFS{
+		"etc/resolv.conf": {Data: []byte("# generated\nnameserver 192.168.1.1\nnameserver fe80::1%eth0\noptions edns0\n")},
+		"proc/net/route": {Data: []byte("Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+			"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+			"eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n" +
+			"lo\t0000007F\t00000000\t0001\t0\t0\t0\t000000FF\t0\t0\t0\n")},
+		"proc/net/ipv6_route": {Data: []byte("fd000000000000000000000000000000 30 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0\n" +
+			"00000000000000000000000000000001 80 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000001 00000000 00000001       lo\n")},
+	}, nil)
+	host.Interfaces = func() ([]netip.Prefix, error) {
+		return []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")}, nil
+	}
+
+	got, err := host.network()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := clusternet.HostNetwork{
+		Interfaces: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+		Routes: []netip.Prefix{
+			netip.MustParsePrefix("0.0.0.0/0"),
+			netip.MustParsePrefix("192.168.1.0/24"),
+			netip.MustParsePrefix("fd00::/48"),
+		},
+		Nameservers: []netip.Addr{netip.MustParseAddr("192.168.1.1"), netip.MustParseAddr("fe80::1")},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
@@ -0,0 +1,170 @@
+// Package preflight verifies that a host is ready for a Calyptia Core
+// installation, with the checks of install-core.sh. Checks read the host
+// through an injectable filesystem and command runner so they can be tested
+// against fake files and commands.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/calyptia/core-images-index/go-index/install"
+)
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	// StatusSkip is reported by checks that do not apply to the host.
+	StatusSkip Status = "skip"
+
+	// SeverityFatal failures abort the installation.
+	SeverityFatal Severity = "fatal"
+	// SeverityIgnorable failures abort the installation unless errors are
+	// ignored, as with install-core.sh --force.
+	SeverityIgnorable Severity = "ignorable"
+
+	DefaultReleaseVersion    = "3.119.0"
+	DefaultClusterCIDR       = install.DefaultClusterCIDR
+	DefaultServiceCIDR       = install.DefaultServiceCIDR
+	DefaultClusterDNS        = install.DefaultClusterDNS
+	DefaultNodePortRange     = install.DefaultNodePortRange
+	DefaultPackageNamePrefix = string(install.FlavorOperator)
+	DefaultCoreDir           = "/opt/calyptia"
+	DefaultRPMRelease        = install.DefaultRPMRelease
+)
+
+type (
+	Status   string
+	Severity string
+
+	// Config holds the installation settings the checks verify, with the
+	// meaning of the INSTALL_CALYPTIA_ variables of install-core.sh.
+	Config struct {
+		User           string
+		Group          string
+		ReleaseVersion string
+		ClusterCIDR    string
+		ServiceCIDR    string
+		ClusterDNS     string
+		NodePortRange  string
+		// LocalPackage is a package file, or a directory holding the packages
+		// of several systems, installed instead of a download.
+		LocalPackage      string
+		PackageNamePrefix string
+		// Arch such as amd64 or arm64, x86_64 and aarch64 are accepted too.
+		Arch string
+		// CoreDir is the installation directory, absolute.
+		CoreDir string
+		// RPMRelease is appended to the version in RPM file names.
+		RPMRelease string
+	}
+
+	// Check is a unit of verification. Run returns at least one result.
+	Check struct {
+		Name string
+		Run  func(ctx context.Context, host Host, cfg Config) []Result
+	}
+
+	// Result is an outcome of a check. Severity is only set on failures.
+	Result struct {
+		Check    string   `json:"check"`
+		Status   Status   `json:"status"`
+		Severity Severity `json:"severity,omitempty"`
+		Message  string   `json:"message"`
+	}
+
+	// Report lists the results of every check in order.
+	Report struct {
+		Results   []Result `json:"results"`
+		Fatal     int      `json:"fatal"`
+		Ignorable int      `json:"ignorable"`
+		Warnings  int      `json:"warnings"`
+	}
+)
+
+// DefaultChecks are the pre-installation checks of install-core.sh.
+var DefaultChecks = []Check{
+	{Name: "system", Run: checkSystem},
+	{Name: "selinux", Run: checkSELinux},
+	{Name: "crypto", Run: checkCrypto},
+	{Name: "fips", Run: checkFIPS},
+	{Name: "firewall", Run: checkFirewall},
+	{Name: "cidr", Run: checkCIDR},
+	{Name: "local-packages", Run: checkLocalPackages},
+}
+
+// DefaultConfig returns the defaults of install-core.sh. User and Group are
+// left empty as they default to the invoking user.
+func DefaultConfig() Config {
+	return Config{
+		ReleaseVersion:    DefaultReleaseVersion,
+		ClusterCIDR:       DefaultClusterCIDR,
+		ServiceCIDR:       DefaultServiceCIDR,
+		ClusterDNS:        DefaultClusterDNS,
+		NodePortRange:     DefaultNodePortRange,
+		PackageNamePrefix: DefaultPackageNamePrefix,
+		Arch:              runtime.GOARCH,
+		CoreDir:           DefaultCoreDir,
+		RPMRelease:        DefaultRPMRelease,
+	}
+}
+
+// Run runs checks, DefaultChecks when none is given, against host. Unlike
+// install-core.sh it does not stop at the first fatal failure.
+func Run(ctx context.Context, host Host, cfg Config, checks ...Check) Report {
+	if len(checks) == 0 {
+		checks = DefaultChecks
+	}
+
+	report := Report{Results: []Result{}}
+	for _, check := range checks {
+		if ctx.Err() != nil {
+			break
+		}
+		for _, r := range check.Run(ctx, host, cfg) {
+			r.Check = check.Name
+			report.add(r)
+		}
+	}
+	return report
+}
+
+func (r *Report) add(result Result) {
+	r.Results = append(r.Results, result)
+	switch {
+	case result.Status == StatusWarn:
+		r.Warnings++
+	case result.Status == StatusFail && result.Severity == SeverityIgnorable:
+		r.Ignorable++
+	case result.Status == StatusFail:
+		r.Fatal++
+	}
+}
+
+// OK reports whether the installation can proceed: no fatal failure, and no
+// ignorable one unless ignoreErrors is set.
+func (r Report) OK(ignoreErrors bool) bool {
+	return r.Fatal == 0 && (ignoreErrors || r.Ignorable == 0)
+}
+
+func pass(format string, args ...any) Result {
+	return Result{Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(format string, args ...any) Result {
+	return Result{Status: StatusWarn, Message: fmt.Sprintf(format, args...)}
+}
+
+func skip(format string, args ...any) Result {
+	return Result{Status: StatusSkip, Message: fmt.Sprintf(format, args...)}
+}
+
+func fatal(format string, args ...any) Result {
+	return Result{Status: StatusFail, Severity: SeverityFatal, Message: fmt.Sprintf(format, args...)}
+}
+
+func ignorable(format string, args ...any) Result {
+	return Result{Status: StatusFail, Severity: SeverityIgnorable, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,121 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeHost returns a Linux host with files and the commands of outputs,
+// keyed by their command line. A command listed with an error output exits
+// with a non zero status.
+func fakeHost(files fstest.MapFS, outputs map[string]string) Host {
+	installed := map[string]bool{}
+	for cmd := range outputs {
+		installed[strings.Fields(cmd)[0]] = true
+	}
+	return Host{
+		FS: files,
+		OS: "linux",
+		Runner: &RunnerMock{
+			LookPathFunc: func(name string) (string, error) {
+				if !installed[name] {
+					return "", exec.ErrNotFound
+				}
+				return "/usr/bin/" + name, nil
+			},
+			OutputFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				out, ok := outputs[strings.Join(append([]string{name}, args...), " ")]
+				if !ok || out == "error" {
+					return nil, errors.New("exit status 1")
+				}
+				return []byte(out), nil
+			},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	checks := []Check{
+		{Name: "a", Run: func(context.Context, Host, Config) []Result {
+			return []Result{pass("ok"), warn("careful")}
+		}},
+		{Name: "b", Run: func(context.Context, Host, Config) []Result {
+			return []Result{ignorable("forcible"), skip("not applicable")}
+		}},
+	}
+
+	report := Run(context.Background(), fakeHost(nil, nil), DefaultConfig(), checks...)
+	want := Report{
+		Results: []Result{
+			{Check: "a", Status: StatusPass, Message: "ok"},
+			{Check: "a", Status: StatusWarn, Message: "careful"},
+			{Check: "b", Status: StatusFail, Severity: SeverityIgnorable, Message: "forcible"},
+			{Check: "b", Status: StatusSkip, Message: "not applicable"},
+		},
+		Ignorable: 1,
+		Warnings:  1,
+	}
+	if !reflect.DeepEqual(want, report) {
+		t.Errorf("want: %+v != got: %+v", want, report)
+	}
+	if report.OK(false) {
+		t.Error("ignorable failure not reported")
+	}
+	if !report.OK(true) {
+		t.Error("ignorable failure not ignored")
+	}
+
+	report.add(fatal("broken"))
+	if report.OK(true) {
+		t.Error("fatal failure ignored")
+	}
+}
+
+func TestReport_JSON(t *testing.T) {
+	report := Report{Results: []Result{{Check: "fips", Status: StatusFail, Severity: SeverityIgnorable, Message: "FIPS mode enabled"}}, Ignorable: 1}
+	got, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"results":[{"check":"fips","status":"fail","severity":"ignorable","message":"FIPS mode enabled"}],"fatal":0,"ignorable":1,"warnings":0}`
+	if want != string(got) {
+		t.Errorf("want: %v != got: %v", want, string(got))
+	}
+}
+
+func TestRun_DefaultChecks(t *testing.T) {
+	host := fakeHost(fstest.MapFS{
+		"etc/resolv.conf": {Data: []byte("nameserver 1.1.1.1\n")},
+	}, map[string]string{
+		"curl":              "",
+		"systemctl":         "",
+		"id -u":             "1000",
+		"id calyptia":       "uid=1000(calyptia)",
+		"getent group core": "core:x:1000:",
+		"nslookup 8.8.8.8":  "dns.google",
+		"getenforce":        "Disabled",
+	})
+	cfg := DefaultConfig()
+	cfg.User, cfg.Group = "calyptia", "core"
+
+	report := Run(context.Background(), host, cfg)
+	if !report.OK(false) || report.Warnings != 0 {
+		t.Errorf("unexpected failures: %+v", report.Results)
+	}
+	var names []string
+	for _, r := range report.Results {
+		if len(names) == 0 || names[len(names)-1] != r.Check {
+			names = append(names, r.Check)
+		}
+	}
+	want := []string{"system", "selinux", "crypto", "fips", "firewall", "cidr", "local-packages"}
+	if !reflect.DeepEqual(want, names) {
+		t.Errorf("want: %v != got: %v", want, names)
+	}
+}
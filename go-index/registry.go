@@ -0,0 +1,83 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+const (
+	ContainerRepository     = "calyptia/core"
+	OperatorRepository      = "calyptia/core-operator"
+	CoreFluentBitRepository = "calyptia/core/calyptia-fluent-bit"
+)
+
+var (
+	// ContainerTagFilter keeps the release tags of Calyptia Core and their
+	// major and minor aliases, as published in container.index.json.
+	ContainerTagFilter = regexp.MustCompile(`^v\d+(\.\d+){0,2}$`)
+	// OperatorTagFilter keeps the release tags of the operator, as selected by
+	// scripts/create-container-index.sh.
+	OperatorTagFilter = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+	// CoreFluentBitTagFilter keeps the tags of Core Fluent Bit for which a
+	// schema is generated, as selected by scripts/create-core-fluent-bit-schemas.sh.
+	CoreFluentBitTagFilter = regexp.MustCompile(`^([0-9]+\.|v)`)
+)
+
+type (
+	// RegistryFetcher lists the tags of a repository straight from an OCI
+	// registry instead of the published index files.
+	RegistryFetcher struct {
+		Client     *oci.Client
+		Repository string
+		// Filter keeps the matching tags only, nil keeps every tag.
+		Filter *regexp.Regexp
+	}
+
+	registryContainerFetcher struct{ *RegistryFetcher }
+	registryOperatorFetcher  struct{ *RegistryFetcher }
+)
+
+// Tags returns the filtered tags in registry order.
+func (f *RegistryFetcher) Tags(ctx context.Context) ([]string, error) {
+	client := f.Client
+	if client == nil {
+		client = &oci.Client{}
+	}
+
+	tags, err := client.Tags(ctx, f.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list tags of %s: %w", f.Repository, err)
+	}
+	if f.Filter == nil {
+		return tags, nil
+	}
+
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if f.Filter.MatchString(tag) {
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// Container adapts f to ContainerIndexFetch.
+func (f *RegistryFetcher) Container() ContainerIndexFetch {
+	return registryContainerFetcher{f}
+}
+
+// Operator adapts f to OperatorIndexFetch.
+func (f *RegistryFetcher) Operator() OperatorIndexFetch {
+	return registryOperatorFetcher{f}
+}
+
+func (f registryContainerFetcher) GetImages(ctx context.Context) (ContainerImages, error) {
+	return f.Tags(ctx)
+}
+
+func (f registryOperatorFetcher) GetImages(ctx context.Context) (OperatorImages, error) {
+	return f.Tags(ctx)
+}
@@ -0,0 +1,35 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/calyptia/core-images-index/go-index/oci"
+	"github.com/calyptia/core-images-index/go-index/oci/ocitest"
+)
+
+func TestRegistryFetcher(t *testing.T) {
+	tags := map[string][]ocitest.Image{}
+	for _, tag := range []string{"v1.0.0", "sha256-abc.sig", "v1.1.0", "latest", "v1.2.0-rc1"} {
+		tags[tag] = []ocitest.Image{{Platform: oci.Platform{OS: "linux", Architecture: "amd64"}}}
+	}
+	r := ocitest.NewRegistry(t, ocitest.Config{Auth: true, Repositories: map[string]ocitest.Repository{
+		OperatorRepository: {Tags: tags},
+	}})
+
+	fetch := &RegistryFetcher{
+		Client:     r.OCIClient(),
+		Repository: OperatorRepository,
+		Filter:     OperatorTagFilter,
+	}
+
+	operator := Operator{Fetcher: fetch.Operator()}
+	got, err := operator.All(context.Background())
+	if err != nil {
+		t.Fatalf("all: %v", err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
@@ -0,0 +1,183 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+const (
+	// IndexFormatV2 is the version of the index documents carrying release
+	// metadata. Legacy documents are bare arrays of tags.
+	IndexFormatV2 = 2
+
+	ChannelStable = "stable"
+	ChannelRC     = "rc"
+)
+
+type (
+	// Release is an entry of an index with its metadata. Only Tag is known
+	// when it is read from a legacy index.
+	Release struct {
+		Tag        string    `json:"tag"`
+		ReleasedAt time.Time `json:"released_at,omitzero"`
+		// Digest of the image, such as sha256:...
+		Digest string `json:"digest,omitempty"`
+		// Platforms such as linux/amd64 and linux/arm64/v8.
+		Platforms []string `json:"platforms,omitempty"`
+		// Channel is ChannelStable or ChannelRC.
+		Channel    string `json:"channel,omitempty"`
+		Deprecated bool   `json:"deprecated,omitempty"`
+		// EOL is set once the release is no longer supported.
+		EOL      bool   `json:"eol,omitempty"`
+		NotesURL string `json:"notes_url,omitempty"`
+	}
+
+	// Releases decodes both the v2 index documents and the legacy arrays of
+	// tags.
+	Releases []Release
+
+	// ReleaseIndex is the v2 index document.
+	ReleaseIndex struct {
+		Version  int       `json:"version"`
+		Releases []Release `json:"releases"`
+	}
+
+	// ReleaseFetch is implemented by the fetchers able to read the metadata
+	// of the v2 index format.
+	ReleaseFetch interface {
+		GetReleases(ctx context.Context) (Releases, error)
+	}
+)
+
+func (r *Releases) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if isNull(data) {
+		// Like a nil slice, null is an empty index.
+		return nil
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var tags []string
+		if err := json.Unmarshal(data, &tags); err != nil {
+			return err
+		}
+		*r = releasesFromTags(tags)
+		return nil
+	}
+
+	var doc ReleaseIndex
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Version != IndexFormatV2 {
+		return fmt.Errorf("unsupported index format version %d", doc.Version)
+	}
+	*r = doc.Releases
+	return nil
+}
+
+// Tags returns the tags of the releases in order.
+func (r Releases) Tags() []string {
+	out := make([]string, 0, len(r))
+	for _, release := range r {
+		out = append(out, release.Tag)
+	}
+	return out
+}
+
+// Match returns the release equal to version.
+func (r Releases) Match(version string) (Release, error) {
+	want, err := semver.NewVersion(version)
+	if err != nil {
+		return Release{}, err
+	}
+	for _, release := range r {
+		got, err := semver.NewVersion(release.Tag)
+		if err == nil && got.Equal(want) {
+			return release, nil
+		}
+	}
+	return Release{}, ErrNoMatchingImage
+}
+
+// MarshalReleases encodes releases as an indented v2 index document, ordered
+// like NormalizeTags.
+func MarshalReleases(releases Releases) ([]byte, error) {
+	byTag := make(map[string]Release, len(releases))
+	for _, release := range releases {
+		if _, ok := byTag[release.Tag]; !ok {
+			byTag[release.Tag] = release
+		}
+	}
+
+	doc := ReleaseIndex{Version: IndexFormatV2, Releases: []Release{}}
+	for _, tag := range NormalizeTags(releases.Tags()) {
+		doc.Releases = append(doc.Releases, byTag[tag])
+	}
+	return marshalIndent(doc)
+}
+
+func (c *ContainerImages) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		return nil
+	}
+	var releases Releases
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return err
+	}
+	*c = releases.Tags()
+	return nil
+}
+
+func (c *OperatorImages) UnmarshalJSON(data []byte) error {
+	if isNull(data) {
+		return nil
+	}
+	var releases Releases
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return err
+	}
+	*c = releases.Tags()
+	return nil
+}
+
+func isNull(data []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+}
+
+// releasesFromTags returns the releases of a legacy index, inferring the
+// channel from the pre-release part of the tags.
+func releasesFromTags(tags []string) Releases {
+	out := make(Releases, 0, len(tags))
+	for _, tag := range tags {
+		release := Release{Tag: tag}
+		if ver, err := semver.NewSemver(tag); err == nil {
+			release.Channel = ChannelStable
+			if ver.Prerelease() != "" {
+				release.Channel = ChannelRC
+			}
+		}
+		out = append(out, release)
+	}
+	return out
+}
+
+// fetchReleases reads the releases from fetcher when it implements
+// ReleaseFetch, and from its tags otherwise.
+func fetchReleases[T ~[]string](ctx context.Context, fetcher interface {
+	GetImages(ctx context.Context) (T, error)
+},
+) (Releases, error) {
+	if f, ok := fetcher.(ReleaseFetch); ok {
+		return f.GetReleases(ctx)
+	}
+	tags, err := fetcher.GetImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return releasesFromTags(tags), nil
+}
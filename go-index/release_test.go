@@ -0,0 +1,174 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const releaseIndexV2 = `{
+  "version": 2,
+  "releases": [
+    {
+      "tag": "v1.1.0-rc1",
+      "released_at": "2026-01-02T00:00:00Z",
+      "channel": "rc"
+    },
+    {
+      "tag": "v1.0.0",
+      "released_at": "2025-12-01T00:00:00Z",
+      "digest": "sha256:abc",
+      "platforms": ["linux/amd64", "linux/arm64"],
+      "channel": "stable",
+      "deprecated": true,
+      "notes_url": "https://example.com/v1.0.0"
+    }
+  ]
+}`
+
+func TestReleases_UnmarshalJSON(t *testing.T) {
+	tt := []struct {
+		name    string
+		data    string
+		want    Releases
+		wantErr bool
+	}{
+		{
+			name: "legacy",
+			data: `["v1.0.0", "v1", "v1.1.0-rc1", "latest"]`,
+			want: Releases{
+				{Tag: "v1.0.0", Channel: ChannelStable},
+				{Tag: "v1", Channel: ChannelStable},
+				{Tag: "v1.1.0-rc1", Channel: ChannelRC},
+				{Tag: "latest"},
+			},
+		},
+		{
+			name: "v2",
+			data: releaseIndexV2,
+			want: Releases{
+				{Tag: "v1.1.0-rc1", ReleasedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Channel: ChannelRC},
+				{
+					Tag:        "v1.0.0",
+					ReleasedAt: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+					Digest:     "sha256:abc",
+					Platforms:  []string{"linux/amd64", "linux/arm64"},
+					Channel:    ChannelStable,
+					Deprecated: true,
+					NotesURL:   "https://example.com/v1.0.0",
+				},
+			},
+		},
+		{
+			name: "null",
+			data: `null`,
+			want: nil,
+		},
+		{
+			name:    "unknown version",
+			data:    `{"version": 3, "releases": []}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got Releases
+			err := json.Unmarshal([]byte(tc.data), &got)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %+v != got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestImages_UnmarshalJSON_null(t *testing.T) {
+	var container ContainerImages
+	if err := json.Unmarshal([]byte(`null`), &container); err != nil || len(container) != 0 {
+		t.Fatalf("want: empty != got: %v (%v)", container, err)
+	}
+
+	var operator OperatorImages
+	if err := json.Unmarshal([]byte(` null `), &operator); err != nil || len(operator) != 0 {
+		t.Fatalf("want: empty != got: %v (%v)", operator, err)
+	}
+
+	fetcher := &OperatorIndexFSFetcher{FS: fstest.MapFS{OperatorIndexFile: {Data: []byte("null\n")}}}
+	if _, err := (&Operator{Fetcher: fetcher}).Last(context.Background()); !errors.Is(err, ErrNoMatchingImage) {
+		t.Fatalf("want: %v != got: %v", ErrNoMatchingImage, err)
+	}
+}
+
+func TestContainer_Release(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{ContainerIndexFile: &fstest.MapFile{Data: []byte(releaseIndexV2)}}
+
+	container := &Container{Fetcher: &ContainerIndexRetryFetcher{Fetcher: &ContainerIndexFSFetcher{FS: fsys}}}
+	got, err := container.Release(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sha256:abc"; want != got.Digest || !got.Deprecated {
+		t.Errorf("want: %v != got: %+v", want, got)
+	}
+
+	// The legacy API reads the v2 document too.
+	all, err := container.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0-rc1"}; !reflect.DeepEqual(want, all) {
+		t.Errorf("want: %v != got: %v", want, all)
+	}
+
+	if _, err := container.Release(ctx, "2.0.0"); !errors.Is(err, ErrNoMatchingImage) {
+		t.Errorf("want: %v != got: %v", ErrNoMatchingImage, err)
+	}
+}
+
+func TestOperator_Releases(t *testing.T) {
+	operator := &Operator{Fetcher: &OperatorIndexFetchMock{
+		GetImagesFunc: func(ctx context.Context) (OperatorImages, error) {
+			return OperatorImages{"v1.0.0"}, nil
+		},
+	}}
+
+	got, err := operator.Releases(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Releases{{Tag: "v1.0.0", Channel: ChannelStable}}); !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v != got: %+v", want, got)
+	}
+}
+
+func TestFormatIndex_v2(t *testing.T) {
+	got, err := FormatIndex([]byte(releaseIndexV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc ReleaseIndex
+	if err := json.Unmarshal(got, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := IndexFormatV2, doc.Version; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+	if want, got := []string{"v1.0.0", "v1.1.0-rc1"}, Releases(doc.Releases).Tags(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
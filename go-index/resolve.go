@@ -0,0 +1,128 @@
+package index
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/calyptia/core-images-index/go-index/oci"
+)
+
+type (
+	// PinnedImage is an image tag resolved to immutable digests.
+	PinnedImage struct {
+		// Image is the repository including the registry host, like ghcr.io/calyptia/core-operator.
+		Image string `json:"image"`
+		Tag   string `json:"tag"`
+		// Digest of the manifest the tag points to, an image index for
+		// multi-arch images.
+		Digest string `json:"digest"`
+		// Reference is the fully pinned image@digest reference.
+		Reference string `json:"reference"`
+		// Platforms lists the per platform manifests of a multi-arch image, or
+		// the single platform of the image when it can be determined.
+		Platforms []PinnedPlatform `json:"platforms,omitempty"`
+	}
+
+	PinnedPlatform struct {
+		// Platform in the os/arch[/variant] form.
+		Platform  string `json:"platform"`
+		Digest    string `json:"digest"`
+		Reference string `json:"reference"`
+	}
+
+	// DigestResolver resolves versions of the indexes to digests through the
+	// OCI Distribution API.
+	DigestResolver struct {
+		Client *oci.Client
+		// ContainerRepository and OperatorRepository default to the
+		// ContainerRepository and OperatorRepository constants.
+		ContainerRepository string
+		OperatorRepository  string
+	}
+)
+
+// Platform returns the pinned platform matching os/arch[/variant], if any.
+func (p PinnedImage) Platform(platform string) (PinnedPlatform, bool) {
+	for _, candidate := range p.Platforms {
+		if candidate.Platform == platform {
+			return candidate, true
+		}
+	}
+	return PinnedPlatform{}, false
+}
+
+// ResolveContainer matches version in the container index and pins its image.
+func (r *DigestResolver) ResolveContainer(ctx context.Context, idx ContainerIndex, version string) (PinnedImage, error) {
+	tag, err := idx.Match(ctx, version)
+	if err != nil {
+		return PinnedImage{}, err
+	}
+	repository := r.ContainerRepository
+	if repository == "" {
+		repository = ContainerRepository
+	}
+	return r.Resolve(ctx, repository, tag)
+}
+
+// ResolveOperator matches version in the operator index and pins its image.
+func (r *DigestResolver) ResolveOperator(ctx context.Context, idx OperatorIndex, version string) (PinnedImage, error) {
+	tag, err := idx.Match(ctx, version)
+	if err != nil {
+		return PinnedImage{}, err
+	}
+	repository := r.OperatorRepository
+	if repository == "" {
+		repository = OperatorRepository
+	}
+	return r.Resolve(ctx, repository, tag)
+}
+
+// Resolve pins tag of repository. For single platform images the platform is
+// read from the image configuration.
+func (r *DigestResolver) Resolve(ctx context.Context, repository, tag string) (PinnedImage, error) {
+	client := r.Client
+	if client == nil {
+		client = &oci.Client{}
+	}
+
+	image := client.Host() + "/" + repository
+	manifest, err := client.Manifest(ctx, repository, tag)
+	if err != nil {
+		return PinnedImage{}, fmt.Errorf("cannot resolve %s:%s: %w", image, tag, err)
+	}
+
+	out := PinnedImage{
+		Image:     image,
+		Tag:       tag,
+		Digest:    manifest.Digest,
+		Reference: image + "@" + manifest.Digest,
+	}
+
+	if manifest.IsIndex() {
+		for _, desc := range manifest.Manifests {
+			// Attestation manifests are listed with an unknown platform.
+			if desc.Platform == nil || desc.Platform.OS == "unknown" {
+				continue
+			}
+			out.Platforms = append(out.Platforms, PinnedPlatform{
+				Platform:  desc.Platform.String(),
+				Digest:    desc.Digest,
+				Reference: image + "@" + desc.Digest,
+			})
+		}
+		return out, nil
+	}
+
+	if manifest.Config != nil {
+		platform, err := client.ConfigPlatform(ctx, repository, *manifest.Config)
+		if err != nil {
+			return PinnedImage{}, fmt.Errorf("cannot resolve platform of %s:%s: %w", image, tag, err)
+		}
+		out.Platforms = []PinnedPlatform{{
+			Platform:  platform.String(),
+			Digest:    manifest.Digest,
+			Reference: out.Reference,
+		}}
+	}
+	return out, nil
+}
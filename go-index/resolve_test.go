@@ -0,0 +1,86 @@
+package index
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calyptia/core-images-index/go-index/oci"
+	"github.com/calyptia/core-images-index/go-index/oci/ocitest"
+)
+
+func TestDigestResolver(t *testing.T) {
+	amd64 := ocitest.Image{Platform: oci.Platform{OS: "linux", Architecture: "amd64"}}
+	arm64 := ocitest.Image{Platform: oci.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}}
+	attestation := ocitest.Image{Platform: oci.Platform{OS: "unknown", Architecture: "unknown"}}
+	r := ocitest.NewRegistry(t, ocitest.Config{Auth: true, Repositories: map[string]ocitest.Repository{
+		OperatorRepository:  {Tags: map[string][]ocitest.Image{"v1.1.0": {amd64, arm64, attestation}}},
+		ContainerRepository: {Tags: map[string][]ocitest.Image{"v0.2.0": {arm64}}},
+	}})
+
+	resolver := &DigestResolver{Client: r.OCIClient()}
+	host := strings.TrimPrefix(r.URL, "http://")
+	ctx := context.Background()
+
+	multi, err := resolver.Client.Manifest(ctx, OperatorRepository, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, err := resolver.Client.Manifest(ctx, ContainerRepository, "v0.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amd64Digest, arm64Digest := multi.Manifests[0].Digest, multi.Manifests[1].Digest
+	if arm64Digest != single.Digest {
+		t.Fatalf("want: the same arm64 image %s != got: %s", single.Digest, arm64Digest)
+	}
+
+	operator := &Operator{Fetcher: &OperatorIndexFetchMock{
+		GetImagesFunc: func(ctx context.Context) (OperatorImages, error) {
+			return OperatorImages{"v1.0.0", "v1.1.0"}, nil
+		},
+	}}
+	pinned, err := resolver.ResolveOperator(ctx, operator, "1.1.0")
+	if err != nil {
+		t.Fatalf("resolve operator: %v", err)
+	}
+	image := host + "/" + OperatorRepository
+	want := PinnedImage{
+		Image:     image,
+		Tag:       "v1.1.0",
+		Digest:    multi.Digest,
+		Reference: image + "@" + multi.Digest,
+		Platforms: []PinnedPlatform{
+			{Platform: "linux/amd64", Digest: amd64Digest, Reference: image + "@" + amd64Digest},
+			{Platform: "linux/arm64/v8", Digest: arm64Digest, Reference: image + "@" + arm64Digest},
+		},
+	}
+	if !reflect.DeepEqual(want, pinned) {
+		t.Errorf("want: %+v != got: %+v", want, pinned)
+	}
+	if _, ok := pinned.Platform("linux/arm64/v8"); !ok {
+		t.Error("arm64 platform not found")
+	}
+
+	container := &Container{Fetcher: &ContainerIndexFetchMock{
+		GetImagesFunc: func(ctx context.Context) (ContainerImages, error) {
+			return ContainerImages{"v0.2.0"}, nil
+		},
+	}}
+	pinned, err = resolver.ResolveContainer(ctx, container, "v0.2.0")
+	if err != nil {
+		t.Fatalf("resolve container: %v", err)
+	}
+	if want, got := []PinnedPlatform{{
+		Platform:  "linux/arm64/v8",
+		Digest:    single.Digest,
+		Reference: host + "/" + ContainerRepository + "@" + single.Digest,
+	}}, pinned.Platforms; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v != got: %+v", want, got)
+	}
+
+	if _, err := resolver.ResolveContainer(ctx, container, "v9.9.9"); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
@@ -0,0 +1,326 @@
+package index
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts    = 4
+	defaultRetryMaxElapsed     = time.Minute
+	defaultRetryInitialBackoff = 250 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+)
+
+type (
+	// RetryPolicy retries an operation with exponential backoff and jitter
+	// until it succeeds, fails with a non retryable error or the attempts or
+	// elapsed time budget are exhausted. The zero value uses sensible defaults.
+	RetryPolicy struct {
+		// MaxAttempts including the first one, defaults to 4.
+		MaxAttempts int
+		// MaxElapsed bounds the total time spent retrying, defaults to 1 minute.
+		MaxElapsed time.Duration
+		// InitialBackoff is the delay before the first retry, defaults to 250ms.
+		InitialBackoff time.Duration
+		// MaxBackoff caps the delay between attempts, defaults to 10s.
+		MaxBackoff time.Duration
+		// Retryable classifies errors, defaults to IsRetryable.
+		Retryable func(error) bool
+	}
+
+	ContainerIndexRetryFetcher struct {
+		Fetcher ContainerIndexFetch
+		Policy  RetryPolicy
+	}
+
+	OperatorIndexRetryFetcher struct {
+		Fetcher OperatorIndexFetch
+		Policy  RetryPolicy
+	}
+
+	SchemaRetryFetcher struct {
+		Fetcher SchemaFetch
+		Policy  RetryPolicy
+	}
+
+	FileRetryFetcher struct {
+		Fetcher FileFetch
+		Policy  RetryPolicy
+	}
+)
+
+// Do calls fn until it succeeds or the policy gives up, returning the last error.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+	maxElapsed := p.MaxElapsed
+	if maxElapsed <= 0 {
+		maxElapsed = defaultRetryMaxElapsed
+	}
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	deadline := time.Now().Add(maxElapsed)
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up, as opposed to a per attempt timeout.
+			return errors.Join(ctx.Err(), err)
+		}
+		if err == nil || attempt >= maxAttempts || !retryable(err) {
+			return err
+		}
+
+		wait := jitter(backoff(initial, maxBackoff, attempt-1))
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+			wait = statusErr.RetryAfter
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("retry budget exhausted after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// jitter spreads d over [d/2, d] so that clients do not retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + rand.N(half+1) //nolint:gosec // jitter does not need a cryptographic source.
+}
+
+// IsRetryable reports whether err is likely transient: throttling and server
+// side statuses, timeouts, such as http.Client.Timeout, and dropped
+// connections. Context cancellation, client errors, unknown hosts,
+// certificate failures and malformed documents are not. The expiry of the
+// caller's context is detected by RetryPolicy.Do.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+			http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var (
+		certErr      x509.CertificateInvalidError
+		unknownCAErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		netErr       net.Error
+		dnsErr       *net.DNSError
+		urlErr       *url.Error
+	)
+	if errors.As(err, &certErr) || errors.As(err, &unknownCAErr) || errors.As(err, &hostnameErr) {
+		return false
+	}
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	case errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	case errors.As(err, &urlErr) && (errors.Is(urlErr.Err, io.EOF) || errors.Is(urlErr.Err, io.ErrUnexpectedEOF)):
+		// The server closed the connection before or while replying, as
+		// opposed to a complete but malformed document.
+		return true
+	}
+	return false
+}
+
+func (f *ContainerIndexRetryFetcher) GetImages(ctx context.Context) (ContainerImages, error) {
+	var out ContainerImages
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.Fetcher.GetImages(ctx)
+		return err
+	})
+	return out, err
+}
+
+func (f *OperatorIndexRetryFetcher) GetImages(ctx context.Context) (OperatorImages, error) {
+	var out OperatorImages
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.Fetcher.GetImages(ctx)
+		return err
+	})
+	return out, err
+}
+
+// GetReleases retries reading the releases of the wrapped fetcher.
+func (f *ContainerIndexRetryFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	var out Releases
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = fetchReleases[ContainerImages](ctx, f.Fetcher)
+		return err
+	})
+	return out, err
+}
+
+// GetReleases retries reading the releases of the wrapped fetcher.
+func (f *OperatorIndexRetryFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	var out Releases
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = fetchReleases[OperatorImages](ctx, f.Fetcher)
+		return err
+	})
+	return out, err
+}
+
+// GetChannels retries reading the channels of the wrapped fetcher. It
+// reports ErrNotFound when the wrapped fetcher has no channel definitions.
+func (f *ContainerIndexRetryFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	return retryChannels(ctx, f.Policy, f.Fetcher)
+}
+
+// GetChannels retries reading the channels of the wrapped fetcher. It
+// reports ErrNotFound when the wrapped fetcher has no channel definitions.
+func (f *OperatorIndexRetryFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	return retryChannels(ctx, f.Policy, f.Fetcher)
+}
+
+// GetDenylist retries reading the yanked releases of the wrapped fetcher. It
+// reports ErrNotFound when the wrapped fetcher has no denylist.
+func (f *ContainerIndexRetryFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	return retryDenylist(ctx, f.Policy, f.Fetcher)
+}
+
+// GetDenylist retries reading the yanked releases of the wrapped fetcher. It
+// reports ErrNotFound when the wrapped fetcher has no denylist.
+func (f *OperatorIndexRetryFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	return retryDenylist(ctx, f.Policy, f.Fetcher)
+}
+
+// GetMappings retries reading the operator mappings of the wrapped fetcher.
+// It reports ErrNotFound when the wrapped fetcher has no mappings.
+func (f *OperatorIndexRetryFetcher) GetMappings(ctx context.Context) (OperatorMappings, error) {
+	m, ok := f.Fetcher.(MappingFetch)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	var out OperatorMappings
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = m.GetMappings(ctx)
+		return err
+	})
+	return out, err
+}
+
+func retryDenylist(ctx context.Context, policy RetryPolicy, fetcher any) (Denylist, error) {
+	f, ok := fetcher.(DenylistFetch)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	var out Denylist
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.GetDenylist(ctx)
+		return err
+	})
+	return out, err
+}
+
+func retryChannels(ctx context.Context, policy RetryPolicy, fetcher any) (Channels, error) {
+	f, ok := fetcher.(ChannelFetch)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	var out Channels
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.GetChannels(ctx)
+		return err
+	})
+	return out, err
+}
+
+func (f *SchemaRetryFetcher) GetSchema(ctx context.Context, version string) (Schema, error) {
+	var out Schema
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.Fetcher.GetSchema(ctx, version)
+		return err
+	})
+	return out, err
+}
+
+func (f *SchemaRetryFetcher) GetLuaSchema(ctx context.Context, version string) (LuaSchema, error) {
+	var out LuaSchema
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.Fetcher.GetLuaSchema(ctx, version)
+		return err
+	})
+	return out, err
+}
+
+func (f *SchemaRetryFetcher) GetPluginsSchema(ctx context.Context, version string) (PluginsSchema, error) {
+	var out PluginsSchema
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.Fetcher.GetPluginsSchema(ctx, version)
+		return err
+	})
+	return out, err
+}
+
+func (f *FileRetryFetcher) GetFile(ctx context.Context, name string) ([]byte, error) {
+	var out []byte
+	err := f.Policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = f.Fetcher.GetFile(ctx, name)
+		return err
+	})
+	return out, err
+}
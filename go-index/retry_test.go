@@ -0,0 +1,212 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestContainerIndexRetryFetcher(t *testing.T) {
+	tt := []struct {
+		name         string
+		failures     int32
+		status       int
+		retryAfter   string
+		policy       RetryPolicy
+		wantAttempts int32
+		wantError    error
+	}{
+		{
+			name:         "recovers from unavailable",
+			failures:     2,
+			status:       http.StatusServiceUnavailable,
+			wantAttempts: 3,
+		},
+		{
+			name:         "honors retry after",
+			failures:     1,
+			status:       http.StatusTooManyRequests,
+			retryAfter:   "1",
+			wantAttempts: 2,
+		},
+		{
+			name:         "not found is not retried",
+			failures:     5,
+			status:       http.StatusNotFound,
+			wantAttempts: 1,
+			wantError:    ErrNotFound,
+		},
+		{
+			name:         "attempts exhausted",
+			failures:     5,
+			status:       http.StatusBadGateway,
+			policy:       RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			wantAttempts: 2,
+		},
+		{
+			name:         "retry after beyond budget",
+			failures:     5,
+			status:       http.StatusServiceUnavailable,
+			retryAfter:   "120",
+			policy:       RetryPolicy{MaxElapsed: time.Second},
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) <= tc.failures {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.status)
+					return
+				}
+				fmt.Fprint(w, `["v0.1.0"]`)
+			}))
+			defer ts.Close()
+
+			policy := tc.policy
+			if policy.InitialBackoff == 0 {
+				policy.InitialBackoff = time.Millisecond
+			}
+			fetch := &ContainerIndexRetryFetcher{
+				Fetcher: &ContainerIndexFetcher{URL: ts.URL, Client: ts.Client()},
+				Policy:  policy,
+			}
+
+			images, err := fetch.GetImages(context.Background())
+			if want, got := tc.wantAttempts, attempts.Load(); want != got {
+				t.Errorf("attempts want: %v != got: %v", want, got)
+			}
+
+			wantErr := tc.wantError != nil || tc.wantAttempts <= tc.failures
+			if wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if tc.wantError != nil && !errors.Is(err, tc.wantError) {
+					t.Errorf("error: %v != %v", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(images) != 1 {
+				t.Errorf("unexpected images: %v", images)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_ContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour, MaxElapsed: 2 * time.Hour}
+
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		cancel()
+		return &StatusError{StatusCode: http.StatusServiceUnavailable}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error: %v != %v", err, context.Canceled)
+	}
+}
+
+func TestRetryPolicy_ContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := RetryPolicy{InitialBackoff: time.Millisecond}.Do(ctx, func(ctx context.Context) error {
+		attempts++
+		<-ctx.Done()
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: ctx.Err()}
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || attempts != 1 {
+		t.Errorf("want: %v after 1 attempt != got: %v after %d", context.DeadlineExceeded, err, attempts)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `["v0.1.0"`)
+	}))
+	defer ts.Close()
+
+	_, malformed := (&ContainerIndexFetcher{URL: ts.URL, Client: ts.Client()}).GetImages(context.Background())
+
+	dropped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		fmt.Fprint(w, `["v0.1.0"`)
+	}))
+	defer dropped.Close()
+	_, truncated := (&ContainerIndexFetcher{URL: dropped.URL, Client: dropped.Client()}).GetImages(context.Background())
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	_, timeout := (&ContainerIndexFetcher{URL: slow.URL, Client: &http.Client{Timeout: 10 * time.Millisecond}}).GetImages(context.Background())
+
+	noSuchHost := &url.Error{Op: "Get", URL: "https://example.invalid", Err: &net.OpError{
+		Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true},
+	}}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	_, refused := (&ContainerIndexFetcher{URL: closed.URL}).GetImages(context.Background())
+
+	tt := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "unavailable", err: &StatusError{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "forbidden", err: &StatusError{StatusCode: http.StatusForbidden}, want: false},
+		{name: "malformed document", err: malformed, want: false},
+		{name: "truncated body", err: truncated, want: true},
+		{name: "client timeout", err: timeout, want: true},
+		{name: "no such host", err: noSuchHost, want: false},
+		{name: "connection refused", err: refused, want: true},
+		{name: "other", err: ErrNoMatchingImage, want: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); tc.want != got {
+				t.Errorf("want: %v != got: %v (%v)", tc.want, got, tc.err)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tt := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute},
+		{value: "soon", want: 0},
+	}
+
+	for _, tc := range tt {
+		if got := parseRetryAfter(tc.value, now); tc.want != got {
+			t.Errorf("%q want: %v != got: %v", tc.value, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	SchemaDir         = "schemas"
+	SchemaFile        = "core-fluent-bit.json"
+	LuaSchemaFile     = "core-fluent-bit-lua.json"
+	PluginsSchemaFile = "core-fluent-bit-plugins.json"
+
+	schemaBaseURL = "https://raw.githubusercontent.com/calyptia/core-images-index/main/schemas"
+)
+
+type (
+	// Schema is the Core Fluent Bit schema as produced by running the image with -J.
+	Schema struct {
+		FluentBit  SchemaInfo     `json:"fluent-bit"`
+		Customs    []SchemaPlugin `json:"customs"`
+		Inputs     []SchemaPlugin `json:"inputs"`
+		Processors []SchemaPlugin `json:"processors,omitempty"`
+		Filters    []SchemaPlugin `json:"filters"`
+		Outputs    []SchemaPlugin `json:"outputs"`
+	}
+
+	SchemaInfo struct {
+		Version       string `json:"version"`
+		SchemaVersion string `json:"schema_version"`
+		OS            string `json:"os"`
+	}
+
+	SchemaPlugin struct {
+		Type        string           `json:"type"`
+		Name        string           `json:"name"`
+		Description string           `json:"description"`
+		Properties  SchemaProperties `json:"properties"`
+	}
+
+	SchemaProperties struct {
+		Options       []SchemaOption `json:"options,omitempty"`
+		GlobalOptions []SchemaOption `json:"global_options,omitempty"`
+		Networking    []SchemaOption `json:"networking,omitempty"`
+		NetworkTLS    []SchemaOption `json:"network_tls,omitempty"`
+	}
+
+	SchemaOption struct {
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		Default     *string `json:"default"`
+		Type        string  `json:"type"`
+	}
+
+	// LuaSchema describes the processing rules shipped with the image in /schema.json.
+	LuaSchema struct {
+		Version          string                       `json:"version,omitempty"`
+		ProcessingRules  map[string]LuaProcessingRule `json:"processingRules"`
+		UISchemaFallback json.RawMessage              `json:"uiSchemaFallback,omitempty"`
+	}
+
+	LuaProcessingRule struct {
+		Label       string          `json:"label,omitempty"`
+		Description string          `json:"description,omitempty"`
+		JSONSchema  json.RawMessage `json:"jsonSchema,omitempty"`
+		UISchema    json.RawMessage `json:"uiSchema,omitempty"`
+	}
+
+	// PluginsSchema lists the enterprise plugins bundled in the image.
+	PluginsSchema struct {
+		Plugins []EnterprisePlugin `json:"plugins"`
+	}
+
+	EnterprisePlugin struct {
+		Name         string `json:"name"`
+		Version      string `json:"version,omitempty"`
+		Repository   string `json:"repository,omitempty"`
+		ArtefactName string `json:"artefact_name,omitempty"`
+	}
+
+	// SchemaFetch retrieves the schema documents committed under schemas/<version>/.
+	// Documents missing for a version are reported with ErrNotFound.
+	SchemaFetch interface {
+		GetSchema(ctx context.Context, version string) (Schema, error)
+		GetLuaSchema(ctx context.Context, version string) (LuaSchema, error)
+		GetPluginsSchema(ctx context.Context, version string) (PluginsSchema, error)
+	}
+
+	SchemaFetcher struct {
+		// BaseURL of the schemas directory, defaults to the one published on GitHub.
+		BaseURL string
+		// Client used for the requests, defaults to http.DefaultClient.
+		Client *http.Client
+		// Verifier checks the integrity of the schemas when set.
+		Verifier Verifier
+	}
+
+	// SchemaFSFetcher reads schemas from a filesystem rooted at the repository,
+	// that is, one holding the schemas directory.
+	SchemaFSFetcher struct {
+		FS fs.FS
+		// Verifier checks the integrity of the schemas when set.
+		Verifier Verifier
+	}
+)
+
+// Plugins returns every plugin of the schema regardless of its kind.
+func (s Schema) Plugins() []SchemaPlugin {
+	var out []SchemaPlugin
+	for _, plugins := range [][]SchemaPlugin{s.Customs, s.Inputs, s.Processors, s.Filters, s.Outputs} {
+		out = append(out, plugins...)
+	}
+	return out
+}
+
+func (f *SchemaFetcher) GetSchema(ctx context.Context, version string) (Schema, error) {
+	var out Schema
+	err := fetchJSON(ctx, f.Client, f.url(version, SchemaFile), f.Verifier, &out)
+	return out, err
+}
+
+func (f *SchemaFetcher) GetLuaSchema(ctx context.Context, version string) (LuaSchema, error) {
+	var out LuaSchema
+	err := fetchJSON(ctx, f.Client, f.url(version, LuaSchemaFile), f.Verifier, &out)
+	return out, err
+}
+
+func (f *SchemaFetcher) GetPluginsSchema(ctx context.Context, version string) (PluginsSchema, error) {
+	var out PluginsSchema
+	err := fetchJSON(ctx, f.Client, f.url(version, PluginsSchemaFile), f.Verifier, &out)
+	return out, err
+}
+
+func (f *SchemaFetcher) url(version, file string) string {
+	base := f.BaseURL
+	if base == "" {
+		base = schemaBaseURL
+	}
+	return strings.TrimSuffix(base, "/") + "/" + version + "/" + file
+}
+
+func (f *SchemaFSFetcher) GetSchema(ctx context.Context, version string) (Schema, error) {
+	var out Schema
+	err := readJSON(ctx, f.FS, path.Join(SchemaDir, version, SchemaFile), f.Verifier, &out)
+	return out, err
+}
+
+func (f *SchemaFSFetcher) GetLuaSchema(ctx context.Context, version string) (LuaSchema, error) {
+	var out LuaSchema
+	err := readJSON(ctx, f.FS, path.Join(SchemaDir, version, LuaSchemaFile), f.Verifier, &out)
+	return out, err
+}
+
+func (f *SchemaFSFetcher) GetPluginsSchema(ctx context.Context, version string) (PluginsSchema, error) {
+	var out PluginsSchema
+	err := readJSON(ctx, f.FS, path.Join(SchemaDir, version, PluginsSchemaFile), f.Verifier, &out)
+	return out, err
+}
+
+// Versions lists the schema versions available in the filesystem.
+func (f *SchemaFSFetcher) Versions(_ context.Context) ([]string, error) {
+	entries, err := fs.ReadDir(f.FS, SchemaDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			out = append(out, entry.Name())
+		}
+	}
+	return sortVersions(out), nil
+}
@@ -0,0 +1,82 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	PluginKindLua        = "lua"
+	PluginKindEnterprise = "enterprise"
+)
+
+type (
+	// PluginCatalog lists every plugin available in a Core Fluent Bit version.
+	PluginCatalog struct {
+		Version string          `json:"version"`
+		Plugins []CatalogPlugin `json:"plugins"`
+	}
+
+	CatalogPlugin struct {
+		// Kind is the schema plugin type (input, filter, output, processor, custom),
+		// PluginKindLua for processing rules or PluginKindEnterprise.
+		Kind        string `json:"kind"`
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version,omitempty"`
+	}
+)
+
+// Catalog builds the plugin catalog of version from its core, lua and enterprise
+// plugin schemas. The lua and enterprise schemas are optional as older versions
+// do not ship them.
+func Catalog(ctx context.Context, fetch SchemaFetch, version string) (PluginCatalog, error) {
+	out := PluginCatalog{Version: version}
+
+	schema, err := fetch.GetSchema(ctx, version)
+	if err != nil {
+		return out, fmt.Errorf("cannot get schema %s: %w", version, err)
+	}
+	for _, plugin := range schema.Plugins() {
+		out.Plugins = append(out.Plugins, CatalogPlugin{
+			Kind:        plugin.Type,
+			Name:        plugin.Name,
+			Description: plugin.Description,
+		})
+	}
+
+	lua, err := fetch.GetLuaSchema(ctx, version)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return out, fmt.Errorf("cannot get lua schema %s: %w", version, err)
+	}
+	for name, rule := range lua.ProcessingRules {
+		out.Plugins = append(out.Plugins, CatalogPlugin{
+			Kind:        PluginKindLua,
+			Name:        name,
+			Description: rule.Description,
+		})
+	}
+
+	plugins, err := fetch.GetPluginsSchema(ctx, version)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return out, fmt.Errorf("cannot get plugins schema %s: %w", version, err)
+	}
+	for _, plugin := range plugins.Plugins {
+		out.Plugins = append(out.Plugins, CatalogPlugin{
+			Kind:    PluginKindEnterprise,
+			Name:    plugin.Name,
+			Version: plugin.Version,
+		})
+	}
+
+	sort.SliceStable(out.Plugins, func(i, j int) bool {
+		if out.Plugins[i].Kind != out.Plugins[j].Kind {
+			return out.Plugins[i].Kind < out.Plugins[j].Kind
+		}
+		return out.Plugins[i].Name < out.Plugins[j].Name
+	})
+
+	return out, nil
+}
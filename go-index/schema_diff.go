@@ -0,0 +1,166 @@
+package index
+
+import (
+	"sort"
+)
+
+type (
+	// SchemaDiff describes the plugin and option changes between two schema versions.
+	SchemaDiff struct {
+		From    string       `json:"from"`
+		To      string       `json:"to"`
+		Added   []PluginRef  `json:"added,omitempty"`
+		Removed []PluginRef  `json:"removed,omitempty"`
+		Changed []PluginDiff `json:"changed,omitempty"`
+	}
+
+	PluginRef struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+
+	PluginDiff struct {
+		PluginRef
+		AddedOptions   []string       `json:"added_options,omitempty"`
+		RemovedOptions []string       `json:"removed_options,omitempty"`
+		ChangedOptions []OptionChange `json:"changed_options,omitempty"`
+	}
+
+	OptionChange struct {
+		Name        string  `json:"name"`
+		FromType    string  `json:"from_type"`
+		ToType      string  `json:"to_type"`
+		FromDefault *string `json:"from_default"`
+		ToDefault   *string `json:"to_default"`
+	}
+)
+
+// Empty reports whether both schemas expose the same plugins and options.
+func (d SchemaDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSchemas compares the plugins of from and to. Descriptions are ignored,
+// only option names, types and defaults are compared.
+func DiffSchemas(from, to Schema) SchemaDiff {
+	out := SchemaDiff{
+		From: from.FluentBit.Version,
+		To:   to.FluentBit.Version,
+	}
+
+	fromPlugins := pluginsByRef(from)
+	toPlugins := pluginsByRef(to)
+
+	for ref, plugin := range toPlugins {
+		old, ok := fromPlugins[ref]
+		if !ok {
+			out.Added = append(out.Added, ref)
+			continue
+		}
+		if diff := diffPlugin(old, plugin); diff != nil {
+			out.Changed = append(out.Changed, *diff)
+		}
+	}
+	for ref := range fromPlugins {
+		if _, ok := toPlugins[ref]; !ok {
+			out.Removed = append(out.Removed, ref)
+		}
+	}
+
+	sortRefs(out.Added)
+	sortRefs(out.Removed)
+	sort.Slice(out.Changed, func(i, j int) bool {
+		return refLess(out.Changed[i].PluginRef, out.Changed[j].PluginRef)
+	})
+
+	return out
+}
+
+func diffPlugin(from, to SchemaPlugin) *PluginDiff {
+	out := PluginDiff{PluginRef: PluginRef{Type: to.Type, Name: to.Name}}
+
+	fromOptions := optionsByName(from)
+	toOptions := optionsByName(to)
+
+	for name, option := range toOptions {
+		old, ok := fromOptions[name]
+		if !ok {
+			out.AddedOptions = append(out.AddedOptions, name)
+			continue
+		}
+		if old.Type != option.Type || !equalDefault(old.Default, option.Default) {
+			out.ChangedOptions = append(out.ChangedOptions, OptionChange{
+				Name:        name,
+				FromType:    old.Type,
+				ToType:      option.Type,
+				FromDefault: old.Default,
+				ToDefault:   option.Default,
+			})
+		}
+	}
+	for name := range fromOptions {
+		if _, ok := toOptions[name]; !ok {
+			out.RemovedOptions = append(out.RemovedOptions, name)
+		}
+	}
+
+	if len(out.AddedOptions) == 0 && len(out.RemovedOptions) == 0 && len(out.ChangedOptions) == 0 {
+		return nil
+	}
+
+	sort.Strings(out.AddedOptions)
+	sort.Strings(out.RemovedOptions)
+	sort.Slice(out.ChangedOptions, func(i, j int) bool {
+		return out.ChangedOptions[i].Name < out.ChangedOptions[j].Name
+	})
+
+	return &out
+}
+
+func pluginsByRef(schema Schema) map[PluginRef]SchemaPlugin {
+	out := map[PluginRef]SchemaPlugin{}
+	for _, plugin := range schema.Plugins() {
+		out[PluginRef{Type: plugin.Type, Name: plugin.Name}] = plugin
+	}
+	return out
+}
+
+// optionsByName flattens the option groups of a plugin, the first occurrence of
+// a name wins.
+func optionsByName(plugin SchemaPlugin) map[string]SchemaOption {
+	out := map[string]SchemaOption{}
+	groups := [][]SchemaOption{
+		plugin.Properties.Options,
+		plugin.Properties.Networking,
+		plugin.Properties.NetworkTLS,
+		plugin.Properties.GlobalOptions,
+	}
+	for _, group := range groups {
+		for _, option := range group {
+			if _, ok := out[option.Name]; !ok {
+				out[option.Name] = option
+			}
+		}
+	}
+	return out
+}
+
+func equalDefault(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sortRefs(refs []PluginRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return refLess(refs[i], refs[j])
+	})
+}
+
+func refLess(a, b PluginRef) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	return a.Name < b.Name
+}
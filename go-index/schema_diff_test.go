@@ -0,0 +1,59 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	from := Schema{
+		FluentBit: SchemaInfo{Version: "1.0.0"},
+		Inputs: []SchemaPlugin{
+			{Type: "input", Name: "dummy", Properties: SchemaProperties{Options: []SchemaOption{
+				{Name: "rate", Type: "integer", Default: str("1")},
+				{Name: "samples", Type: "integer"},
+			}}},
+			{Type: "input", Name: "tail"},
+		},
+		Outputs: []SchemaPlugin{{Type: "output", Name: "stdout"}},
+	}
+	to := Schema{
+		FluentBit: SchemaInfo{Version: "1.1.0"},
+		Inputs: []SchemaPlugin{
+			{Type: "input", Name: "dummy", Properties: SchemaProperties{Options: []SchemaOption{
+				{Name: "rate", Type: "integer", Default: str("5")},
+				{Name: "copies", Type: "integer"},
+			}}},
+		},
+		Outputs: []SchemaPlugin{
+			{Type: "output", Name: "stdout"},
+			{Type: "output", Name: "s3"},
+		},
+	}
+
+	want := SchemaDiff{
+		From:    "1.0.0",
+		To:      "1.1.0",
+		Added:   []PluginRef{{Type: "output", Name: "s3"}},
+		Removed: []PluginRef{{Type: "input", Name: "tail"}},
+		Changed: []PluginDiff{{
+			PluginRef:      PluginRef{Type: "input", Name: "dummy"},
+			AddedOptions:   []string{"copies"},
+			RemovedOptions: []string{"samples"},
+			ChangedOptions: []OptionChange{{
+				Name: "rate", FromType: "integer", ToType: "integer", FromDefault: str("1"), ToDefault: str("5"),
+			}},
+		}},
+	}
+
+	got := DiffSchemas(from, to)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %+v != got: %+v", want, got)
+	}
+
+	if !DiffSchemas(to, to).Empty() {
+		t.Error("diff of identical schemas is not empty")
+	}
+}
@@ -0,0 +1,251 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+const schemaGoldenFile = "testdata/schemas.golden"
+
+var (
+	updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+	// knownOptionTypes are the option types Core Fluent Bit reports with -J.
+	knownOptionTypes = map[string]bool{
+		"string":                              true,
+		"boolean":                             true,
+		"integer":                             true,
+		"double":                              true,
+		"time":                                true,
+		"size":                                true,
+		"prefixed string":                     true,
+		"multiple comma delimited strings":    true,
+		"space delimited strings (minimum 1)": true,
+		"space delimited strings (minimum 2)": true,
+		"space delimited strings (minimum 3)": true,
+		"space delimited strings (minimum 4)": true,
+		"variant":                             true,
+		"deprecated":                          true,
+	}
+)
+
+// TestSchemaGolden loads every committed schema version through the fetchers,
+// checks their invariants and compares a summary of each with
+// testdata/schemas.golden, rewritten with -update.
+func TestSchemaGolden(t *testing.T) {
+	fsys := os.DirFS("..")
+	fetch := &SchemaFSFetcher{FS: fsys}
+	versions, err := fetch.Versions(context.Background())
+	if err != nil {
+		t.Fatalf("versions: %v", err)
+	}
+
+	summaries := make([]string, len(versions))
+	t.Run("versions", func(t *testing.T) {
+		for i, version := range versions {
+			t.Run(version, func(t *testing.T) {
+				t.Parallel()
+				summaries[i] = checkSchemaVersion(t, fsys, fetch, version)
+			})
+		}
+	})
+
+	got := strings.Join(summaries, "\n") + "\n"
+	if *updateGolden && !t.Failed() {
+		if err := os.WriteFile(schemaGoldenFile, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(schemaGoldenFile)
+	if err != nil {
+		t.Fatalf("%v, run the test with -update to create it", err)
+	}
+	if string(want) != got {
+		t.Errorf("schema summaries differ from %s, run the test with -update and review the diff:\n%s",
+			schemaGoldenFile, lineDiff(string(want), got))
+	}
+}
+
+// checkSchemaVersion checks the schemas of version and returns its summary.
+func checkSchemaVersion(t *testing.T, fsys fs.FS, fetch *SchemaFSFetcher, version string) string {
+	ctx := context.Background()
+
+	schema, err := fetch.GetSchema(ctx, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSchemaFile(t, fsys, version, SchemaFile, schema)
+	options := checkPlugins(t, schema)
+
+	lua, err := fetch.GetLuaSchema(ctx, version)
+	luaRules := "-"
+	switch {
+	case errors.Is(err, ErrNotFound):
+		// Images released before 23.1 have no Lua schema.
+		if ver, err := semver.NewSemver(version); err == nil && !ver.LessThan(firstLuaSchema) {
+			t.Errorf("missing %s", LuaSchemaFile)
+		}
+	case err != nil:
+		t.Fatal(err)
+	default:
+		checkSchemaFile(t, fsys, version, LuaSchemaFile, lua)
+		luaRules = fmt.Sprint(len(lua.ProcessingRules))
+	}
+
+	plugins, err := fetch.GetPluginsSchema(ctx, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSchemaFile(t, fsys, version, PluginsSchemaFile, plugins)
+	names := make([]string, len(plugins.Plugins))
+	for i, p := range plugins.Plugins {
+		names[i] = p.Name
+	}
+	checkUnique(t, "enterprise plugin", names)
+
+	return fmt.Sprintf("%s fluent-bit=%s schema=%s customs=%d inputs=%d processors=%d filters=%d outputs=%d options=%d lua-rules=%s enterprise-plugins=%d",
+		version, schema.FluentBit.Version, schema.FluentBit.SchemaVersion,
+		len(schema.Customs), len(schema.Inputs), len(schema.Processors), len(schema.Filters), len(schema.Outputs),
+		options, luaRules, len(plugins.Plugins))
+}
+
+// checkSchemaFile checks that the typed model v holds every value of the
+// document and that its -pretty twin holds the same value.
+func checkSchemaFile(t *testing.T, fsys fs.FS, version, file string, v any) {
+	t.Helper()
+
+	name := path.Join(SchemaDir, version, file)
+	compact, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := decodeValue(compact)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	prettyName := strings.TrimSuffix(name, ".json") + "-pretty.json"
+	pretty, err := fs.ReadFile(fsys, prettyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prettyValue, err := decodeValue(pretty)
+	if err != nil {
+		t.Fatalf("%s: %v", prettyName, err)
+	}
+	if !reflect.DeepEqual(want, prettyValue) {
+		t.Errorf("%s differs from %s", prettyName, file)
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeValue(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Empty lists and nulls are omitted or added by the encoder, they carry
+	// no value.
+	if !reflect.DeepEqual(pruneEmpty(want), pruneEmpty(got)) {
+		t.Errorf("%s does not round-trip through %T", name, v)
+	}
+}
+
+// checkPlugins checks the plugin names and option types of every kind and
+// returns the number of options.
+func checkPlugins(t *testing.T, schema Schema) int {
+	t.Helper()
+
+	options := 0
+	for kind, plugins := range map[string][]SchemaPlugin{
+		"custom":    schema.Customs,
+		"input":     schema.Inputs,
+		"processor": schema.Processors,
+		"filter":    schema.Filters,
+		"output":    schema.Outputs,
+	} {
+		names := make([]string, len(plugins))
+		for i, p := range plugins {
+			names[i] = p.Name
+			props := p.Properties
+			for _, group := range [][]SchemaOption{props.Options, props.GlobalOptions, props.Networking, props.NetworkTLS} {
+				for _, o := range group {
+					if !knownOptionTypes[o.Type] {
+						t.Errorf("%s %s: option %s has unknown type %q", kind, p.Name, o.Name, o.Type)
+					}
+					options++
+				}
+			}
+		}
+		checkUnique(t, kind, names)
+	}
+	return options
+}
+
+func checkUnique(t *testing.T, kind string, names []string) {
+	t.Helper()
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			t.Errorf("duplicate %s %s", kind, name)
+		}
+		seen[name] = true
+	}
+}
+
+// pruneEmpty removes the null and empty list members of objects in v.
+func pruneEmpty(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if list, ok := e.([]any); e == nil || ok && len(list) == 0 {
+				delete(v, k)
+				continue
+			}
+			v[k] = pruneEmpty(e)
+		}
+	case []any:
+		for i := range v {
+			v[i] = pruneEmpty(v[i])
+		}
+	}
+	return v
+}
+
+// lineDiff lists the lines removed from want and added in got.
+func lineDiff(want, got string) string {
+	wantLines := map[string]bool{}
+	for _, line := range strings.Split(want, "\n") {
+		wantLines[line] = true
+	}
+	gotLines := map[string]bool{}
+	for _, line := range strings.Split(got, "\n") {
+		gotLines[line] = true
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(want, "\n") {
+		if !gotLines[line] {
+			b.WriteString("- " + line + "\n")
+		}
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if !wantLines[line] {
+			b.WriteString("+ " + line + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestSchemaFSFetcher(t *testing.T) {
+	ctx := context.Background()
+	fetch := &SchemaFSFetcher{FS: os.DirFS("..")}
+
+	versions, err := fetch.Versions(ctx)
+	if err != nil {
+		t.Fatalf("versions: %v", err)
+	}
+	if len(versions) == 0 {
+		t.Fatal("no schema versions found")
+	}
+
+	latest := versions[len(versions)-1]
+	schema, err := fetch.GetSchema(ctx, latest)
+	if err != nil {
+		t.Fatalf("schema %s: %v", latest, err)
+	}
+	if want, got := latest, schema.FluentBit.Version; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+	if len(schema.Inputs) == 0 || len(schema.Outputs) == 0 {
+		t.Errorf("schema %s has no inputs or outputs", latest)
+	}
+
+	_, err = fetch.GetLuaSchema(ctx, "22.7.2")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error: %v != %v", err, ErrNotFound)
+	}
+}
+
+func TestSchemaFetcher(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("../schemas")))
+	defer ts.Close()
+
+	ctx := context.Background()
+	fetch := &SchemaFetcher{BaseURL: ts.URL, Client: ts.Client()}
+
+	plugins, err := fetch.GetPluginsSchema(ctx, "23.1.1")
+	if err != nil {
+		t.Fatalf("plugins schema: %v", err)
+	}
+	if len(plugins.Plugins) == 0 {
+		t.Error("no enterprise plugins found")
+	}
+
+	_, err = fetch.GetSchema(ctx, "0.0.0")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error: %v != %v", err, ErrNotFound)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	ctx := context.Background()
+	fetch := &SchemaFSFetcher{FS: os.DirFS("..")}
+
+	tt := []struct {
+		name     string
+		version  string
+		wantKind string
+	}{
+		{name: "with lua", version: "23.1.1", wantKind: PluginKindLua},
+		{name: "without lua", version: "22.7.2", wantKind: PluginKindEnterprise},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			catalog, err := Catalog(ctx, fetch, tc.version)
+			if err != nil {
+				t.Fatalf("catalog: %v", err)
+			}
+
+			kinds := map[string]int{}
+			for _, plugin := range catalog.Plugins {
+				kinds[plugin.Kind]++
+			}
+			if kinds["input"] == 0 || kinds[tc.wantKind] == 0 {
+				t.Errorf("unexpected plugin kinds: %v", kinds)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Package server exposes the container, operator and schema indexes over HTTP
+// so that clients do not need to fetch and sort the raw files themselves.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	semver "github.com/hashicorp/go-version"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+type (
+	// Config holds the backing indexes of the server.
+	Config struct {
+		Container index.ContainerIndex
+		Operator  index.OperatorIndex
+		Schemas   index.SchemaFetch
+		// MaxAge is advertised through Cache-Control, zero disables caching.
+		MaxAge time.Duration
+	}
+
+	// VersionResponse is returned by the latest and match endpoints.
+	VersionResponse struct {
+		Version string `json:"version"`
+	}
+
+	// ErrorResponse is returned on failure.
+	ErrorResponse struct {
+		Error string `json:"error"`
+	}
+
+	versionIndex interface {
+		All(ctx context.Context) ([]string, error)
+		Last(ctx context.Context) (string, error)
+		Latest(ctx context.Context, channel string) (string, error)
+		Match(ctx context.Context, version string) (string, error)
+	}
+
+	server struct {
+		Config
+	}
+)
+
+// New returns a handler serving:
+//
+//	GET /v1/{container,operator}/versions
+//	GET /v1/{container,operator}/latest[?channel={channel}]
+//	GET /v1/{container,operator}/match/{version}
+//	GET /v1/schemas/{version}
+//	GET /v1/schemas/{version}/plugins
+//	GET /v1/schemas/diff?from={version}&to={version}
+func New(cfg Config) http.Handler {
+	s := &server{Config: cfg}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	s.handleIndex(mux, "container", cfg.Container)
+	s.handleIndex(mux, "operator", cfg.Operator)
+
+	mux.HandleFunc("GET /v1/schemas/diff", s.schemaDiff)
+	mux.HandleFunc("GET /v1/schemas/{version}", s.schema)
+	mux.HandleFunc("GET /v1/schemas/{version}/plugins", s.plugins)
+
+	return mux
+}
+
+func (s *server) handleIndex(mux *http.ServeMux, name string, idx versionIndex) {
+	mux.HandleFunc("GET /v1/"+name+"/versions", func(w http.ResponseWriter, r *http.Request) {
+		versions, err := idx.All(r.Context())
+		if err != nil {
+			s.error(w, err)
+			return
+		}
+		if versions == nil {
+			versions = []string{}
+		}
+		s.json(w, r, versions)
+	})
+
+	mux.HandleFunc("GET /v1/"+name+"/latest", func(w http.ResponseWriter, r *http.Request) {
+		var (
+			version string
+			err     error
+		)
+		if channel := r.URL.Query().Get("channel"); channel != "" {
+			version, err = idx.Latest(r.Context(), channel)
+		} else {
+			version, err = idx.Last(r.Context())
+		}
+		if err != nil {
+			s.error(w, err)
+			return
+		}
+		s.json(w, r, VersionResponse{Version: version})
+	})
+
+	mux.HandleFunc("GET /v1/"+name+"/match/{version}", func(w http.ResponseWriter, r *http.Request) {
+		version, err := validVersion(r.PathValue("version"))
+		if err != nil {
+			s.error(w, err)
+			return
+		}
+
+		version, err = idx.Match(r.Context(), version)
+		if err != nil {
+			s.error(w, err)
+			return
+		}
+		s.json(w, r, VersionResponse{Version: version})
+	})
+}
+
+func (s *server) schema(w http.ResponseWriter, r *http.Request) {
+	version, err := validVersion(r.PathValue("version"))
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+
+	schema, err := s.Schemas.GetSchema(r.Context(), version)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	s.json(w, r, schema)
+}
+
+func (s *server) plugins(w http.ResponseWriter, r *http.Request) {
+	version, err := validVersion(r.PathValue("version"))
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+
+	catalog, err := index.Catalog(r.Context(), s.Schemas, version)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	s.json(w, r, catalog)
+}
+
+func (s *server) schemaDiff(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, err := validVersion(query.Get("from"))
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	to, err := validVersion(query.Get("to"))
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+
+	fromSchema, err := s.Schemas.GetSchema(r.Context(), from)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	toSchema, err := s.Schemas.GetSchema(r.Context(), to)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+
+	diff := index.DiffSchemas(fromSchema, toSchema)
+	diff.From, diff.To = from, to
+	s.json(w, r, diff)
+}
+
+// json writes v with an ETag derived from its encoding so that clients can
+// revalidate with If-None-Match.
+func (s *server) json(w http.ResponseWriter, r *http.Request, v any) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		s.error(w, err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	if s.MaxAge > 0 {
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.MaxAge.Seconds())))
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", "application/json")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (s *server) error(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	var yanked *index.ErrVersionYanked
+	switch {
+	case errors.As(err, &yanked):
+		status = http.StatusGone
+	case errors.Is(err, errBadVersion), errors.Is(err, index.ErrUnknownChannel):
+		status = http.StatusBadRequest
+	case errors.Is(err, index.ErrNoMatchingImage), errors.Is(err, index.ErrNotFound):
+		status = http.StatusNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+}
+
+var errBadVersion = errors.New("invalid version")
+
+// validVersion rejects anything but a semantic version before it is used to
+// build a path or URL.
+func validVersion(version string) (string, error) {
+	if _, err := semver.NewVersion(version); err != nil {
+		return "", fmt.Errorf("%w %q", errBadVersion, version)
+	}
+	return version, nil
+}
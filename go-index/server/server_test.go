@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	index "github.com/calyptia/core-images-index/go-index"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(New(Config{
+		Container: &index.Container{Fetcher: &index.ContainerIndexFetchMock{
+			GetImagesFunc: func(ctx context.Context) (index.ContainerImages, error) {
+				return index.ContainerImages{"v0.2.6", "v0.2.4", "v1.0.0", "v0.2.5", "v1.1.0-rc1"}, nil
+			},
+		}},
+		Operator: &index.Operator{Fetcher: &index.OperatorIndexFetchMock{
+			GetImagesFunc: func(ctx context.Context) (index.OperatorImages, error) {
+				return index.OperatorImages{}, nil
+			},
+		}},
+		Schemas: &index.SchemaFSFetcher{FS: os.DirFS("../..")},
+		MaxAge:  time.Minute,
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestServer(t *testing.T) {
+	ts := newTestServer(t)
+
+	tt := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{path: "/v1/container/versions", wantStatus: http.StatusOK, wantBody: `["v0.2.4","v0.2.5","v0.2.6","v1.0.0","v1.1.0-rc1"]`},
+		{path: "/v1/container/latest", wantStatus: http.StatusOK, wantBody: `{"version":"v1.1.0-rc1"}`},
+		{path: "/v1/container/latest?channel=stable", wantStatus: http.StatusOK, wantBody: `{"version":"v1.0.0"}`},
+		{path: "/v1/container/latest?channel=edge", wantStatus: http.StatusOK, wantBody: `{"version":"v1.1.0-rc1"}`},
+		{path: "/v1/container/latest?channel=nightly", wantStatus: http.StatusBadRequest},
+		{path: "/v1/container/match/0.2.5", wantStatus: http.StatusOK, wantBody: `{"version":"v0.2.5"}`},
+		{path: "/v1/container/match/0.2.7", wantStatus: http.StatusNotFound},
+		{path: "/v1/container/match/latest", wantStatus: http.StatusBadRequest},
+		{path: "/v1/operator/versions", wantStatus: http.StatusOK, wantBody: `[]`},
+		{path: "/v1/operator/latest", wantStatus: http.StatusNotFound},
+		{path: "/v1/schemas/23.1.1/plugins", wantStatus: http.StatusOK},
+		{path: "/v1/schemas/0.0.1/plugins", wantStatus: http.StatusNotFound},
+		{path: "/v1/schemas/..%2f..%2fetc/plugins", wantStatus: http.StatusBadRequest},
+		{path: "/v1/schemas/diff?from=23.1.1&to=23.2.1", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			res, err := ts.Client().Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if want, got := tc.wantStatus, res.StatusCode; want != got {
+				t.Fatalf("want: %v != got: %v", want, got)
+			}
+
+			var body json.RawMessage
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if tc.wantBody != "" && tc.wantBody != string(body) {
+				t.Errorf("want: %s != got: %s", tc.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestServer_ETag(t *testing.T) {
+	ts := newTestServer(t)
+
+	res, err := ts.Client().Get(ts.URL + "/v1/container/versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+	if want, got := "public, max-age=60", res.Header.Get("Cache-Control"); want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/container/versions", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("If-None-Match", etag)
+
+	res, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if want, got := http.StatusNotModified, res.StatusCode; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
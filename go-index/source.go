@@ -0,0 +1,544 @@
+package index
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const DefaultBaseURL = "https://raw.githubusercontent.com/calyptia/core-images-index/main"
+
+var ErrNoSource = fmt.Errorf("no index source configured")
+
+type (
+	// Source is a named location holding the index files with the layout of
+	// this repository. Any fetcher can be nil if the source does not serve it.
+	Source struct {
+		Name      string
+		Container ContainerIndexFetch
+		Operator  OperatorIndexFetch
+		Schemas   SchemaFetch
+		// Files reads the raw files of the source, such as the index files
+		// bundled for offline use.
+		Files FileFetch
+	}
+
+	// FileFetch reads a file by its path in the layout of this repository,
+	// such as operator.index.json or schemas/24.7.1/core-fluent-bit.json.
+	FileFetch interface {
+		GetFile(ctx context.Context, name string) ([]byte, error)
+	}
+
+	// URLFileFetcher reads files below BaseURL.
+	URLFileFetcher struct {
+		BaseURL string
+		// Client used for the requests, defaults to http.DefaultClient.
+		Client *http.Client
+		// Verifier checks the integrity of the files when set.
+		Verifier Verifier
+	}
+
+	// FSFileFetcher reads files from a filesystem rooted at the repository.
+	FSFileFetcher struct {
+		FS fs.FS
+		// Verifier checks the integrity of the files when set.
+		Verifier Verifier
+	}
+
+	// MultiSource tries its sources in order and returns the first successful
+	// result. In consensus mode every source is fetched and the ones disagreeing
+	// with the served result are reported.
+	MultiSource struct {
+		Sources   []Source
+		Consensus bool
+		// Quorum is the number of sources, including the serving one, that must
+		// return the exact same tags in consensus mode. Zero disables the check.
+		Quorum int
+		// OnServed is called with the report of every index fetched through
+		// ContainerFetcher and OperatorFetcher.
+		OnServed func(SourceReport)
+	}
+
+	// SourceReport tells which source served an index and, in consensus mode,
+	// how the other sources differ from it.
+	SourceReport struct {
+		Index         IndexKind
+		Source        string
+		Agreeing      []string
+		Disagreements []SourceDisagreement
+	}
+
+	SourceDisagreement struct {
+		Source string
+		// Missing holds the served tags absent from this source.
+		Missing []string
+		// Extra holds the tags of this source absent from the served result.
+		Extra []string
+		// Err is set when the source could not be fetched.
+		Err error
+	}
+
+	// QuorumError is returned in consensus mode when too few sources agree.
+	QuorumError struct {
+		Report SourceReport
+		Quorum int
+	}
+
+	multiContainerFetcher struct{ *MultiSource }
+	multiOperatorFetcher  struct{ *MultiSource }
+	multiSchemaFetcher    struct{ *MultiSource }
+)
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("%s index served by %s: %d of %d required sources agree",
+		e.Report.Index, e.Report.Source, len(e.Report.Agreeing), e.Quorum)
+}
+
+// NewURLSource reads the index files below baseURL, such as DefaultBaseURL.
+func NewURLSource(baseURL string, client *http.Client) Source {
+	base := strings.TrimSuffix(baseURL, "/")
+	return Source{
+		Name:      base,
+		Container: &ContainerIndexFetcher{URL: base + "/" + ContainerIndexFile, Client: client},
+		Operator:  &OperatorIndexFetcher{URL: base + "/" + OperatorIndexFile, Client: client},
+		Schemas:   &SchemaFetcher{BaseURL: base + "/" + SchemaDir, Client: client},
+		Files:     &URLFileFetcher{BaseURL: base, Client: client},
+	}
+}
+
+// NewFSSource reads the index files from fsys, for instance an embed.FS or a
+// checkout of this repository.
+func NewFSSource(name string, fsys fs.FS) Source {
+	return Source{
+		Name:      name,
+		Container: &ContainerIndexFSFetcher{FS: fsys},
+		Operator:  &OperatorIndexFSFetcher{FS: fsys},
+		Schemas:   &SchemaFSFetcher{FS: fsys},
+		Files:     &FSFileFetcher{FS: fsys},
+	}
+}
+
+// OpenSource returns a URL source for http and https locations, a source
+// reading a zip archive, such as an offline bundle, for .zip locations and a
+// directory source otherwise. The archive stays open for the life of the
+// process. The files of an archive or directory holding a bundle manifest are
+// checked against it when it is opened, every fetcher of the source failing
+// with the *VerificationError of a corrupted bundle.
+func OpenSource(location string) Source {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return NewURLSource(location, nil)
+	}
+	if strings.HasSuffix(location, ".zip") {
+		archive, err := zip.OpenReader(location)
+		if err != nil {
+			// The error is not wrapped so that a missing archive is not
+			// taken for a missing index document.
+			return NewFSSource(location, errFS{err: errors.New(err.Error())})
+		}
+		if err := verifyBundle(archive); err != nil {
+			return NewFSSource(location, errFS{err: fmt.Errorf("%s: %w", location, err)})
+		}
+		return NewFSSource(location, archive)
+	}
+	fsys := os.DirFS(location)
+	if err := verifyBundle(fsys); err != nil {
+		return NewFSSource(location, errFS{err: fmt.Errorf("%s: %w", location, err)})
+	}
+	return NewFSSource(location, fsys)
+}
+
+// verifyBundle checks the files of fsys against its bundle manifest, if any.
+func verifyBundle(fsys fs.FS) error {
+	data, err := fs.ReadFile(fsys, BundleManifestFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var manifest struct {
+		Files []BundleFile `json:"files"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return &VerificationError{Name: BundleManifestFile, Reason: err.Error()}
+	}
+	return VerifyBundleFiles(fsys, manifest.Files)
+}
+
+func (f *URLFileFetcher) GetFile(ctx context.Context, name string) ([]byte, error) {
+	return fetchVerified(ctx, f.Client, strings.TrimSuffix(f.BaseURL, "/")+"/"+name, f.Verifier)
+}
+
+func (f *FSFileFetcher) GetFile(ctx context.Context, name string) ([]byte, error) {
+	return readVerified(ctx, f.FS, name, f.Verifier)
+}
+
+// errFS fails to open any file, for sources that cannot be opened.
+type errFS struct{ err error }
+
+func (f errFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: f.err}
+}
+
+// WithRetry wraps every fetcher of the source with policy.
+func (s Source) WithRetry(policy RetryPolicy) Source {
+	if s.Container != nil {
+		s.Container = &ContainerIndexRetryFetcher{Fetcher: s.Container, Policy: policy}
+	}
+	if s.Operator != nil {
+		s.Operator = &OperatorIndexRetryFetcher{Fetcher: s.Operator, Policy: policy}
+	}
+	if s.Schemas != nil {
+		s.Schemas = &SchemaRetryFetcher{Fetcher: s.Schemas, Policy: policy}
+	}
+	if s.Files != nil {
+		s.Files = &FileRetryFetcher{Fetcher: s.Files, Policy: policy}
+	}
+	return s
+}
+
+// WithVerifier checks the index documents, the schemas and the files of the
+// source with verifier. Only the URL and filesystem fetchers of this package
+// are changed, so it is called before WithRetry.
+func (s Source) WithVerifier(verifier Verifier) Source {
+	switch f := s.Container.(type) {
+	case *ContainerIndexFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Container = &c
+	case *ContainerIndexFSFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Container = &c
+	}
+	switch f := s.Operator.(type) {
+	case *OperatorIndexFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Operator = &c
+	case *OperatorIndexFSFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Operator = &c
+	}
+	switch f := s.Schemas.(type) {
+	case *SchemaFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Schemas = &c
+	case *SchemaFSFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Schemas = &c
+	}
+	switch f := s.Files.(type) {
+	case *URLFileFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Files = &c
+	case *FSFileFetcher:
+		c := *f
+		c.Verifier = verifier
+		s.Files = &c
+	}
+	return s
+}
+
+// FetchContainer fetches the container index and reports the serving source.
+func (m *MultiSource) FetchContainer(ctx context.Context) (ContainerImages, SourceReport, error) {
+	return fetchSources(ctx, m, IndexContainer, func(ctx context.Context, s Source) (ContainerImages, bool, error) {
+		if s.Container == nil {
+			return nil, false, nil
+		}
+		out, err := s.Container.GetImages(ctx)
+		return out, true, err
+	})
+}
+
+// FetchOperator fetches the operator index and reports the serving source.
+func (m *MultiSource) FetchOperator(ctx context.Context) (OperatorImages, SourceReport, error) {
+	return fetchSources(ctx, m, IndexOperator, func(ctx context.Context, s Source) (OperatorImages, bool, error) {
+		if s.Operator == nil {
+			return nil, false, nil
+		}
+		out, err := s.Operator.GetImages(ctx)
+		return out, true, err
+	})
+}
+
+// ContainerFetcher adapts the container index of m to ContainerIndexFetch.
+func (m *MultiSource) ContainerFetcher() ContainerIndexFetch {
+	return multiContainerFetcher{m}
+}
+
+// OperatorFetcher adapts the operator index of m to OperatorIndexFetch.
+func (m *MultiSource) OperatorFetcher() OperatorIndexFetch {
+	return multiOperatorFetcher{m}
+}
+
+// SchemaFetcher returns a SchemaFetch failing over between the sources. Schemas
+// are immutable per version so consensus is not checked.
+func (m *MultiSource) SchemaFetcher() SchemaFetch {
+	return multiSchemaFetcher{m}
+}
+
+func (f multiContainerFetcher) GetImages(ctx context.Context) (ContainerImages, error) {
+	out, report, err := f.FetchContainer(ctx)
+	if err == nil && f.OnServed != nil {
+		f.OnServed(report)
+	}
+	return out, err
+}
+
+func (f multiOperatorFetcher) GetImages(ctx context.Context) (OperatorImages, error) {
+	out, report, err := f.FetchOperator(ctx)
+	if err == nil && f.OnServed != nil {
+		f.OnServed(report)
+	}
+	return out, err
+}
+
+// GetReleases reads the releases of the first source serving them.
+func (f multiContainerFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	return firstDocument(ctx, f.Sources, containerOf, fetchReleases[ContainerImages])
+}
+
+// GetReleases reads the releases of the first source serving them.
+func (f multiOperatorFetcher) GetReleases(ctx context.Context) (Releases, error) {
+	return firstDocument(ctx, f.Sources, operatorOf, fetchReleases[OperatorImages])
+}
+
+// GetChannels reads the channel definitions of the first source publishing
+// them.
+func (f multiContainerFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	return firstDocument(ctx, f.Sources, containerOf, func(ctx context.Context, fetch ChannelFetch) (Channels, error) {
+		return fetch.GetChannels(ctx)
+	})
+}
+
+// GetChannels reads the channel definitions of the first source publishing
+// them.
+func (f multiOperatorFetcher) GetChannels(ctx context.Context) (Channels, error) {
+	return firstDocument(ctx, f.Sources, operatorOf, func(ctx context.Context, fetch ChannelFetch) (Channels, error) {
+		return fetch.GetChannels(ctx)
+	})
+}
+
+// GetDenylist reads the yanked releases of the first source publishing them.
+func (f multiContainerFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	return firstDocument(ctx, f.Sources, containerOf, func(ctx context.Context, fetch DenylistFetch) (Denylist, error) {
+		return fetch.GetDenylist(ctx)
+	})
+}
+
+// GetDenylist reads the yanked releases of the first source publishing them.
+func (f multiOperatorFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	return firstDocument(ctx, f.Sources, operatorOf, func(ctx context.Context, fetch DenylistFetch) (Denylist, error) {
+		return fetch.GetDenylist(ctx)
+	})
+}
+
+// GetMappings reads the operator mappings of the first source publishing
+// them.
+func (f multiOperatorFetcher) GetMappings(ctx context.Context) (OperatorMappings, error) {
+	return firstDocument(ctx, f.Sources, operatorOf, func(ctx context.Context, fetch MappingFetch) (OperatorMappings, error) {
+		return fetch.GetMappings(ctx)
+	})
+}
+
+func containerOf(s Source) ContainerIndexFetch { return s.Container }
+
+func operatorOf(s Source) OperatorIndexFetch { return s.Operator }
+
+// firstDocument reads a document published next to an index, such as the
+// denylist, trying the sources in order like GetImages. Sources whose fetcher
+// does not implement F are skipped. ErrNotFound is only reported when every
+// other source does not publish the document, so that a source failing is
+// not taken for a missing document.
+func firstDocument[I, F, T any](ctx context.Context, sources []Source, fetcher func(Source) I, get func(context.Context, F) (T, error)) (T, error) {
+	var (
+		zero T
+		errs []error
+	)
+	for _, s := range sources {
+		f, ok := any(fetcher(s)).(F)
+		if !ok {
+			continue
+		}
+		out, err := get(ctx, f)
+		switch {
+		case err == nil:
+			return out, nil
+		case ctx.Err() != nil:
+			return zero, ctx.Err()
+		case !errors.Is(err, ErrNotFound):
+			errs = append(errs, fmt.Errorf("source %s: %w", s.Name, err))
+		}
+	}
+	if len(errs) == 0 {
+		return zero, ErrNotFound
+	}
+	return zero, errors.Join(errs...)
+}
+
+func (f multiSchemaFetcher) GetSchema(ctx context.Context, version string) (Schema, error) {
+	return failover(ctx, f.Sources, func(ctx context.Context, s SchemaFetch) (Schema, error) {
+		return s.GetSchema(ctx, version)
+	})
+}
+
+func (f multiSchemaFetcher) GetLuaSchema(ctx context.Context, version string) (LuaSchema, error) {
+	return failover(ctx, f.Sources, func(ctx context.Context, s SchemaFetch) (LuaSchema, error) {
+		return s.GetLuaSchema(ctx, version)
+	})
+}
+
+func (f multiSchemaFetcher) GetPluginsSchema(ctx context.Context, version string) (PluginsSchema, error) {
+	return failover(ctx, f.Sources, func(ctx context.Context, s SchemaFetch) (PluginsSchema, error) {
+		return s.GetPluginsSchema(ctx, version)
+	})
+}
+
+// failover returns the first schema document found. A document missing from
+// every source is reported with ErrNotFound.
+func failover[T any](ctx context.Context, sources []Source, get func(context.Context, SchemaFetch) (T, error)) (T, error) {
+	var (
+		zero T
+		errs []error
+	)
+	for _, s := range sources {
+		if s.Schemas == nil {
+			continue
+		}
+		out, err := get(ctx, s.Schemas)
+		if err == nil {
+			return out, nil
+		}
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("source %s: %w", s.Name, err))
+	}
+	if len(errs) == 0 {
+		return zero, ErrNoSource
+	}
+	return zero, errors.Join(errs...)
+}
+
+type sourceResult[T ~[]string] struct {
+	source Source
+	tags   T
+	ok     bool
+	err    error
+}
+
+func fetchSources[T ~[]string](
+	ctx context.Context,
+	m *MultiSource,
+	kind IndexKind,
+	get func(context.Context, Source) (T, bool, error),
+) (T, SourceReport, error) {
+	report := SourceReport{Index: kind}
+
+	var results []sourceResult[T]
+	if m.Consensus {
+		results = fetchAll(ctx, m.Sources, get)
+	} else {
+		for _, s := range m.Sources {
+			tags, ok, err := get(ctx, s)
+			results = append(results, sourceResult[T]{source: s, tags: tags, ok: ok, err: err})
+			if ok && err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return nil, report, ctx.Err()
+			}
+		}
+	}
+
+	served := -1
+	var errs []error
+	for i, r := range results {
+		if !r.ok {
+			continue
+		}
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("source %s: %w", r.source.Name, r.err))
+			continue
+		}
+		if served < 0 {
+			served = i
+		}
+	}
+	if served < 0 {
+		if len(errs) == 0 {
+			return nil, report, ErrNoSource
+		}
+		return nil, report, errors.Join(errs...)
+	}
+
+	out := results[served].tags
+	report.Source = results[served].source.Name
+	if !m.Consensus {
+		report.Agreeing = []string{report.Source}
+		return out, report, nil
+	}
+
+	compareSources(&report, out, results)
+	if m.Quorum > 0 && len(report.Agreeing) < m.Quorum {
+		return out, report, &QuorumError{Report: report, Quorum: m.Quorum}
+	}
+	return out, report, nil
+}
+
+// compareSources records in report which sources agree with served.
+func compareSources[T ~[]string](report *SourceReport, served T, results []sourceResult[T]) {
+	for _, r := range results {
+		if !r.ok {
+			continue
+		}
+		if r.err != nil {
+			report.Disagreements = append(report.Disagreements, SourceDisagreement{Source: r.source.Name, Err: r.err})
+			continue
+		}
+		missing := missingTags(served, r.tags)
+		extra := missingTags(r.tags, served)
+		if len(missing) == 0 && len(extra) == 0 {
+			report.Agreeing = append(report.Agreeing, r.source.Name)
+			continue
+		}
+		report.Disagreements = append(report.Disagreements, SourceDisagreement{
+			Source:  r.source.Name,
+			Missing: missing,
+			Extra:   extra,
+		})
+	}
+}
+
+func fetchAll[T ~[]string](
+	ctx context.Context,
+	sources []Source,
+	get func(context.Context, Source) (T, bool, error),
+) []sourceResult[T] {
+	results := make([]sourceResult[T], len(sources))
+
+	var wg sync.WaitGroup
+	for i, s := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tags, ok, err := get(ctx, s)
+			results[i] = sourceResult[T]{source: s, tags: tags, ok: ok, err: err}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
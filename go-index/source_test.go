@@ -0,0 +1,313 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func containerSource(name string, images ContainerImages, err error) Source {
+	return Source{
+		Name: name,
+		Container: &ContainerIndexFetchMock{
+			GetImagesFunc: func(ctx context.Context) (ContainerImages, error) {
+				return images, err
+			},
+		},
+	}
+}
+
+func TestMultiSource_FetchContainer(t *testing.T) {
+	tt := []struct {
+		name              string
+		multi             MultiSource
+		wantSource        string
+		wantImages        ContainerImages
+		wantDisagreements []SourceDisagreement
+		wantError         bool
+	}{
+		{
+			name: "first source",
+			multi: MultiSource{Sources: []Source{
+				containerSource("github", ContainerImages{"v1.0.0"}, nil),
+				containerSource("mirror", ContainerImages{"v0.9.0"}, nil),
+			}},
+			wantSource: "github",
+			wantImages: ContainerImages{"v1.0.0"},
+		},
+		{
+			name: "failover",
+			multi: MultiSource{Sources: []Source{
+				containerSource("github", nil, http.ErrHandlerTimeout),
+				{Name: "no container index"},
+				containerSource("mirror", ContainerImages{"v0.9.0"}, nil),
+			}},
+			wantSource: "mirror",
+			wantImages: ContainerImages{"v0.9.0"},
+		},
+		{
+			name: "all failing",
+			multi: MultiSource{Sources: []Source{
+				containerSource("github", nil, http.ErrHandlerTimeout),
+			}},
+			wantError: true,
+		},
+		{
+			name: "consensus disagreement",
+			multi: MultiSource{Consensus: true, Sources: []Source{
+				containerSource("github", ContainerImages{"v1.0.0", "v1.1.0"}, nil),
+				containerSource("mirror", ContainerImages{"v1.0.0"}, nil),
+				containerSource("broken", nil, http.ErrHandlerTimeout),
+			}},
+			wantSource: "github",
+			wantImages: ContainerImages{"v1.0.0", "v1.1.0"},
+			wantDisagreements: []SourceDisagreement{
+				{Source: "mirror", Missing: []string{"v1.1.0"}},
+				{Source: "broken", Err: http.ErrHandlerTimeout},
+			},
+		},
+		{
+			name: "quorum not reached",
+			multi: MultiSource{Consensus: true, Quorum: 2, Sources: []Source{
+				containerSource("github", ContainerImages{"v1.0.0", "v1.1.0"}, nil),
+				containerSource("mirror", ContainerImages{"v1.0.0"}, nil),
+			}},
+			wantError: true,
+		},
+		{
+			name: "quorum reached",
+			multi: MultiSource{Consensus: true, Quorum: 2, Sources: []Source{
+				containerSource("github", ContainerImages{"v1.0.0"}, nil),
+				containerSource("mirror", ContainerImages{"v1.0.0"}, nil),
+			}},
+			wantSource: "github",
+			wantImages: ContainerImages{"v1.0.0"},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			images, report, err := tc.multi.FetchContainer(ctx)
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want, got := tc.wantSource, report.Source; want != got {
+				t.Errorf("want: %v != got: %v", want, got)
+			}
+			if want, got := tc.wantImages, images; !reflect.DeepEqual(want, got) {
+				t.Errorf("want: %v != got: %v", want, got)
+			}
+			if want, got := tc.wantDisagreements, report.Disagreements; !reflect.DeepEqual(want, got) {
+				t.Errorf("want: %+v != got: %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestMultiSource_Quorum(t *testing.T) {
+	multi := MultiSource{Consensus: true, Quorum: 2, Sources: []Source{
+		containerSource("github", ContainerImages{"v1.1.0"}, nil),
+		containerSource("mirror", ContainerImages{"v1.0.0"}, nil),
+	}}
+
+	_, _, err := multi.FetchContainer(context.Background())
+
+	var quorumErr *QuorumError
+	if !errors.As(err, &quorumErr) {
+		t.Fatalf("error: %v is not a QuorumError", err)
+	}
+	if want, got := []string{"github"}, quorumErr.Report.Agreeing; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
+
+func TestMultiSource_Sources(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	embedded := fstest.MapFS{
+		OperatorIndexFile: {Data: []byte(`["v1.0.0"]`)},
+	}
+
+	var served []SourceReport
+	multi := &MultiSource{
+		Sources: []Source{
+			NewURLSource(ts.URL, ts.Client()),
+			NewFSSource("embedded", embedded),
+			OpenSource(".."),
+		},
+		OnServed: func(report SourceReport) {
+			served = append(served, report)
+		},
+	}
+
+	ctx := context.Background()
+	images, err := multi.OperatorFetcher().GetImages(ctx)
+	if err != nil {
+		t.Fatalf("operator index: %v", err)
+	}
+	if want, got := (OperatorImages{"v1.0.0"}), images; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+
+	images2, err := multi.ContainerFetcher().GetImages(ctx)
+	if err != nil {
+		t.Fatalf("container index: %v", err)
+	}
+	if len(images2) == 0 {
+		t.Error("empty container index")
+	}
+
+	if want, got := []string{"embedded", ".."}, []string{served[0].Source, served[1].Source}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+
+	_, err = multi.SchemaFetcher().GetSchema(ctx, "0.0.0")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error: %v != %v", err, ErrNotFound)
+	}
+}
+
+func TestMultiSource_documents(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	mirror := fstest.MapFS{
+		OperatorIndexFile:    {Data: []byte(`["v1.0.0","v1.1.0","v1.2.0"]`)},
+		OperatorYankedFile:   {Data: []byte(`[{"version":"v1.2.0","reason":"broken"}]`)},
+		OperatorChannelsFile: {Data: []byte(`{"pinned":{"minors":["1.0"]}}`)},
+		OperatorMappingsFile: {Data: []byte(`{"v1.1.0":"ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1"}`)},
+	}
+	multi := &MultiSource{Sources: []Source{
+		NewURLSource(down.URL, down.Client()),
+		{Name: "registry", Operator: &OperatorIndexFetchMock{GetImagesFunc: func(ctx context.Context) (OperatorImages, error) {
+			return nil, ErrNotFound
+		}}},
+		NewFSSource("mirror", mirror),
+	}}
+	operator := &Operator{Fetcher: multi.OperatorFetcher()}
+	ctx := context.Background()
+
+	_, err := operator.Match(ctx, "1.2.0")
+	var yanked *ErrVersionYanked
+	if !errors.As(err, &yanked) || yanked.Reason != "broken" {
+		t.Fatalf("want: %v != got: %v", &ErrVersionYanked{Version: "v1.2.0", Reason: "broken"}, err)
+	}
+	if latest, err := operator.Latest(ctx, ChannelStable); err != nil || latest != "v1.1.0" {
+		t.Fatalf("want: v1.1.0 != got: %s (%v)", latest, err)
+	}
+	if pinned, err := operator.Latest(ctx, "pinned"); err != nil || pinned != "v1.0.0" {
+		t.Fatalf("want: v1.0.0 != got: %s (%v)", pinned, err)
+	}
+	image, err := operator.CoreFluentBitImage(ctx, "v1.1.0")
+	if err != nil || image != "ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1" {
+		t.Fatalf("want: ghcr.io/calyptia/core/calyptia-fluent-bit:24.7.1 != got: %s (%v)", image, err)
+	}
+	releases, err := operator.Releases(ctx)
+	if err != nil || len(releases) != 3 {
+		t.Fatalf("want: 3 releases != got: %v (%v)", releases, err)
+	}
+
+	// A source down is not taken for a missing denylist, which would serve
+	// yanked releases.
+	delete(mirror, OperatorYankedFile)
+	_, err = operator.Match(ctx, "1.2.0")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("want: status error != got: %v", err)
+	}
+	if _, err := operator.Last(ctx); !errors.As(err, &statusErr) {
+		t.Fatalf("want: status error != got: %v", err)
+	}
+
+	// Sources reachable without a denylist yank nothing.
+	multi.Sources = multi.Sources[1:]
+	if got, err := operator.Match(ctx, "1.2.0"); err != nil || got != "v1.2.0" {
+		t.Fatalf("want: v1.2.0 != got: %s (%v)", got, err)
+	}
+}
+
+func TestSource_Files(t *testing.T) {
+	ts := httptest.NewServer(http.FileServerFS(fstest.MapFS{
+		"schemas/24.7.1/core-fluent-bit.json": {Data: []byte(`{}`)},
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	for _, src := range []Source{
+		NewURLSource(ts.URL, ts.Client()),
+		NewFSSource("embedded", fstest.MapFS{"schemas/24.7.1/core-fluent-bit.json": {Data: []byte(`{}`)}}),
+	} {
+		data, err := src.Files.GetFile(ctx, "schemas/24.7.1/core-fluent-bit.json")
+		if err != nil || string(data) != `{}` {
+			t.Errorf("%s: want: {} != got: %s (%v)", src.Name, data, err)
+		}
+		if _, err := src.Files.GetFile(ctx, OperatorIndexFile); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: want: %v != got: %v", src.Name, ErrNotFound, err)
+		}
+	}
+
+	_, err := OpenSource("missing.zip").Operator.GetImages(ctx)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("want: open error != got: %v", err)
+	}
+}
+
+func TestSource_FilesWithRetryAndVerifier(t *testing.T) {
+	const name = "schemas/24.7.1/core-fluent-bit.json"
+	fsys := fstest.MapFS{
+		name:                        {Data: []byte(`{}`)},
+		"schemas/24.7.1/SHA256SUMS": {Data: []byte(sha256sum(`{}`) + "  core-fluent-bit.json\n")},
+	}
+	var requests atomic.Int32
+	files := http.FileServerFS(fsys)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+name && requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	src := NewURLSource(ts.URL, ts.Client()).
+		WithVerifier(&ChecksumVerifier{}).
+		WithRetry(RetryPolicy{InitialBackoff: time.Millisecond})
+	if data, err := src.Files.GetFile(ctx, name); err != nil || string(data) != `{}` {
+		t.Fatalf("want: {} != got: %s (%v)", data, err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("want: 2 requests != got: %d", got)
+	}
+	if _, err := src.Schemas.GetSchema(ctx, "24.7.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys[name] = &fstest.MapFile{Data: []byte(`{"changed":true}`)}
+	src = NewFSSource("embedded", fsys).WithVerifier(&ChecksumVerifier{})
+	if _, err := src.Files.GetFile(ctx, name); !errors.Is(err, ErrVerification) {
+		t.Errorf("want: %v != got: %v", ErrVerification, err)
+	}
+	if _, err := src.Schemas.GetSchema(ctx, "24.7.1"); !errors.Is(err, ErrVerification) {
+		t.Errorf("want: %v != got: %v", ErrVerification, err)
+	}
+	if _, err := src.Operator.GetImages(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want: %v != got: %v", ErrNotFound, err)
+	}
+}
@@ -0,0 +1,198 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+const (
+	SupportSupported  SupportStatus = "supported"
+	SupportDeprecated SupportStatus = "deprecated"
+	SupportEOL        SupportStatus = "eol"
+	// SupportUnknown is reported by window policies for releases without a
+	// release date.
+	SupportUnknown SupportStatus = "unknown"
+)
+
+var errSupportPolicy = errors.New("a support policy needs either minors or months")
+
+type (
+	SupportStatus string
+
+	// SupportPolicy tells how long releases are supported, either for the
+	// last Minors minor versions or for Months after their release date. Once
+	// out of support a release is deprecated for DeprecationMinors minor
+	// versions or DeprecationMonths, then end of life.
+	SupportPolicy struct {
+		Minors            int `json:"minors,omitempty"`
+		DeprecationMinors int `json:"deprecation_minors,omitempty"`
+		Months            int `json:"months,omitempty"`
+		DeprecationMonths int `json:"deprecation_months,omitempty"`
+	}
+
+	// SupportInfo is the support status of a release. The dates of the status
+	// changes are zero when they are not known, such as the deprecation of a
+	// supported minor version, which depends on future releases.
+	SupportInfo struct {
+		Version      string        `json:"version"`
+		Status       SupportStatus `json:"status"`
+		DeprecatedAt time.Time     `json:"deprecated_at,omitzero"`
+		EOLAt        time.Time     `json:"eol_at,omitzero"`
+	}
+
+	// minorRelease groups the releases of a minor version.
+	minorRelease struct {
+		version    *semver.Version
+		releasedAt time.Time
+	}
+)
+
+// Evaluate returns the support status at now of every release, aliases and
+// pre-releases excluded, in ascending version order. Releases flagged as
+// deprecated or end of life in the index keep that status.
+func (p SupportPolicy) Evaluate(releases Releases, now time.Time) ([]SupportInfo, error) {
+	if (p.Minors > 0) == (p.Months > 0) {
+		return nil, errSupportPolicy
+	}
+
+	type release struct {
+		Release
+		version *semver.Version
+	}
+	var sorted []release
+	for _, r := range releases {
+		if aliasTag.MatchString(r.Tag) {
+			continue
+		}
+		ver, err := semver.NewSemver(r.Tag)
+		if err != nil || ver.Prerelease() != "" {
+			continue
+		}
+		sorted = append(sorted, release{Release: r, version: ver})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].version.LessThan(sorted[j].version)
+	})
+
+	minors := groupMinors(releases)
+	out := make([]SupportInfo, 0, len(sorted))
+	for _, r := range sorted {
+		var info SupportInfo
+		if p.Minors > 0 {
+			info = p.minorSupport(r.version, minors)
+		} else {
+			info = p.windowSupport(r.Release, now)
+		}
+		info.Version = r.Tag
+		// The flags of the v2 index override the policy.
+		switch {
+		case r.EOL:
+			info.Status = SupportEOL
+		case r.Deprecated && info.Status == SupportSupported:
+			info.Status = SupportDeprecated
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// Support returns the support status at now of the release matching version.
+func (p SupportPolicy) Support(releases Releases, version string, now time.Time) (SupportInfo, error) {
+	release, err := releases.Match(version)
+	if err != nil {
+		return SupportInfo{}, err
+	}
+	all, err := p.Evaluate(releases, now)
+	if err != nil {
+		return SupportInfo{}, err
+	}
+	for _, info := range all {
+		if info.Version == release.Tag {
+			return info, nil
+		}
+	}
+	return SupportInfo{}, fmt.Errorf("%s is not a release: %w", release.Tag, ErrNoMatchingImage)
+}
+
+func (p SupportPolicy) windowSupport(release Release, now time.Time) SupportInfo {
+	if release.ReleasedAt.IsZero() {
+		return SupportInfo{Status: SupportUnknown}
+	}
+
+	info := SupportInfo{
+		Status:       SupportSupported,
+		DeprecatedAt: release.ReleasedAt.AddDate(0, p.Months, 0),
+	}
+	info.EOLAt = info.DeprecatedAt.AddDate(0, p.DeprecationMonths, 0)
+	switch {
+	case !now.Before(info.EOLAt):
+		info.Status = SupportEOL
+	case !now.Before(info.DeprecatedAt):
+		info.Status = SupportDeprecated
+	}
+	return info
+}
+
+// minorSupport ranks the minor version of ver among minors, sorted from the
+// newest. A minor version is deprecated by the release of the minor version
+// Minors ranks newer, and end of life by the one DeprecationMinors further.
+func (p SupportPolicy) minorSupport(ver *semver.Version, minors []minorRelease) SupportInfo {
+	rank := indexMinor(minors, ver)
+	info := SupportInfo{Status: SupportSupported}
+	if deprecatedBy := rank - p.Minors; deprecatedBy >= 0 {
+		info.Status = SupportDeprecated
+		info.DeprecatedAt = minors[deprecatedBy].releasedAt
+	}
+	if eolBy := rank - p.Minors - p.DeprecationMinors; eolBy >= 0 {
+		info.Status = SupportEOL
+		info.EOLAt = minors[eolBy].releasedAt
+	}
+	return info
+}
+
+// groupMinors returns the minor versions of the releases from the newest,
+// with the date of their first release when known.
+func groupMinors(releases Releases) []minorRelease {
+	var out []minorRelease
+	for _, r := range releases {
+		if aliasTag.MatchString(r.Tag) {
+			continue
+		}
+		ver, err := semver.NewSemver(r.Tag)
+		if err != nil || ver.Prerelease() != "" {
+			continue
+		}
+
+		i := indexMinor(out, ver)
+		if i < 0 {
+			out = append(out, minorRelease{version: ver, releasedAt: r.ReleasedAt})
+			continue
+		}
+		if !r.ReleasedAt.IsZero() && (out[i].releasedAt.IsZero() || r.ReleasedAt.Before(out[i].releasedAt)) {
+			out[i].releasedAt = r.ReleasedAt
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].version.GreaterThan(out[j].version)
+	})
+	return out
+}
+
+func indexMinor(minors []minorRelease, ver *semver.Version) int {
+	for i, minor := range minors {
+		if sameMinor(minor.version, ver) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameMinor(a, b *semver.Version) bool {
+	sa, sb := a.Segments(), b.Segments()
+	return sa[0] == sb[0] && sa[1] == sb[1]
+}
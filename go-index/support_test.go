@@ -0,0 +1,94 @@
+package index
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSupportPolicy_Evaluate(t *testing.T) {
+	date := func(month int) time.Time {
+		return time.Date(2026, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	}
+	releases := Releases{
+		{Tag: "v1.0.0", ReleasedAt: date(1)},
+		{Tag: "v1.0.1", ReleasedAt: date(2)},
+		{Tag: "v1.1.0", ReleasedAt: date(3)},
+		{Tag: "v1.2.0", ReleasedAt: date(5)},
+		{Tag: "v1.3.0-rc1", ReleasedAt: date(6)},
+		{Tag: "v1.3.0", ReleasedAt: date(7), Deprecated: true},
+		{Tag: "v1"},
+	}
+	now := date(8)
+
+	tt := []struct {
+		name   string
+		policy SupportPolicy
+		want   []SupportInfo
+	}{
+		{
+			name:   "minors",
+			policy: SupportPolicy{Minors: 2, DeprecationMinors: 1},
+			want: []SupportInfo{
+				{Version: "v1.0.0", Status: SupportEOL, DeprecatedAt: date(5), EOLAt: date(7)},
+				{Version: "v1.0.1", Status: SupportEOL, DeprecatedAt: date(5), EOLAt: date(7)},
+				{Version: "v1.1.0", Status: SupportDeprecated, DeprecatedAt: date(7)},
+				{Version: "v1.2.0", Status: SupportSupported},
+				{Version: "v1.3.0", Status: SupportDeprecated},
+			},
+		},
+		{
+			name:   "months",
+			policy: SupportPolicy{Months: 4, DeprecationMonths: 2},
+			want: []SupportInfo{
+				{Version: "v1.0.0", Status: SupportEOL, DeprecatedAt: date(5), EOLAt: date(7)},
+				{Version: "v1.0.1", Status: SupportEOL, DeprecatedAt: date(6), EOLAt: date(8)},
+				{Version: "v1.1.0", Status: SupportDeprecated, DeprecatedAt: date(7), EOLAt: date(9)},
+				{Version: "v1.2.0", Status: SupportSupported, DeprecatedAt: date(9), EOLAt: date(11)},
+				{Version: "v1.3.0", Status: SupportDeprecated, DeprecatedAt: date(11), EOLAt: date(13)},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.policy.Evaluate(releases, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want: %+v\n!= got: %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSupportPolicy_Support(t *testing.T) {
+	releases := Releases{{Tag: "v3.65.0"}, {Tag: "v3.66.0"}, {Tag: "v3.67.0"}}
+	policy := SupportPolicy{Minors: 1}
+
+	got, err := policy.Support(releases, "3.66.0", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SupportInfo{Version: "v3.66.0", Status: SupportEOL}); want != got {
+		t.Errorf("want: %+v != got: %+v", want, got)
+	}
+
+	if _, err := policy.Support(releases, "3.68.0", time.Now()); !errors.Is(err, ErrNoMatchingImage) {
+		t.Errorf("want: %v != got: %v", ErrNoMatchingImage, err)
+	}
+
+	months := SupportPolicy{Months: 6}
+	got, err = months.Support(releases, "3.66.0", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := SupportUnknown; want != got.Status {
+		t.Errorf("want: %v != got: %v", want, got.Status)
+	}
+
+	if _, err := (SupportPolicy{Minors: 1, Months: 1}).Evaluate(releases, time.Now()); err == nil {
+		t.Error("want error for a policy with both minors and months")
+	}
+}
@@ -0,0 +1,228 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+const (
+	// ChecksumsFile is the detached manifest published next to the indexes,
+	// in the format of sha256sum.
+	ChecksumsFile = "SHA256SUMS"
+	// SignatureSuffix is appended to an index file name to locate its
+	// base64 encoded detached signature.
+	SignatureSuffix = ".sig"
+	// BundleManifestFile lists the files of an offline bundle with their
+	// checksums, at its root, see package bundle.
+	BundleManifestFile = "bundle.json"
+)
+
+var ErrVerification = fmt.Errorf("index verification failed")
+
+type (
+	// Document is an index file as fetched, before it is decoded.
+	Document struct {
+		// Name of the file, such as container.index.json.
+		Name string
+		Data []byte
+		// ReadSibling reads another file published next to the document.
+		ReadSibling func(ctx context.Context, name string) ([]byte, error)
+	}
+
+	// Verifier checks the integrity of a fetched document. Integrity failures
+	// are reported with a *VerificationError.
+	Verifier interface {
+		Verify(ctx context.Context, doc Document) error
+	}
+
+	// VerificationError is returned when a document does not match its
+	// checksum or signature. It matches ErrVerification.
+	VerificationError struct {
+		Name   string
+		Reason string
+	}
+
+	// ChecksumVerifier checks documents against the SHA-256 manifest published
+	// next to them.
+	ChecksumVerifier struct {
+		// Manifest is the name of the manifest, defaults to ChecksumsFile.
+		Manifest string
+	}
+
+	// SignatureVerifier checks the detached signature published next to a
+	// document against PublicKey. Ed25519 keys verify the document bytes and
+	// ECDSA keys, as used by cosign sign-blob, verify its SHA-256 digest.
+	SignatureVerifier struct {
+		PublicKey crypto.PublicKey
+	}
+
+	// Verifiers requires every verifier to pass.
+	Verifiers []Verifier
+
+	// BundleFile is a file listed in the manifest of an offline bundle.
+	BundleFile struct {
+		Name   string `json:"name"`
+		Size   int64  `json:"size"`
+		SHA256 string `json:"sha256"`
+	}
+)
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrVerification, e.Name, e.Reason)
+}
+
+func (e *VerificationError) Is(target error) bool {
+	return target == ErrVerification
+}
+
+func (v Verifiers) Verify(ctx context.Context, doc Document) error {
+	for _, verifier := range v {
+		if err := verifier.Verify(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *ChecksumVerifier) Verify(ctx context.Context, doc Document) error {
+	name := v.Manifest
+	if name == "" {
+		name = ChecksumsFile
+	}
+
+	manifest, err := doc.ReadSibling(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return &VerificationError{Name: doc.Name, Reason: "checksum manifest " + name + " not found"}
+	}
+	if err != nil {
+		return fmt.Errorf("cannot get checksum manifest %s: %w", name, err)
+	}
+
+	sums, err := ParseChecksums(manifest)
+	if err != nil {
+		return &VerificationError{Name: doc.Name, Reason: err.Error()}
+	}
+
+	want, ok := sums[doc.Name]
+	if !ok {
+		return &VerificationError{Name: doc.Name, Reason: "not listed in " + name}
+	}
+
+	got := sha256.Sum256(doc.Data)
+	if subtle.ConstantTimeCompare(want, got[:]) != 1 {
+		return &VerificationError{Name: doc.Name, Reason: "checksum mismatch"}
+	}
+	return nil
+}
+
+func (v *SignatureVerifier) Verify(ctx context.Context, doc Document) error {
+	raw, err := doc.ReadSibling(ctx, doc.Name+SignatureSuffix)
+	if errors.Is(err, ErrNotFound) {
+		return &VerificationError{Name: doc.Name, Reason: "signature not found"}
+	}
+	if err != nil {
+		return fmt.Errorf("cannot get signature of %s: %w", doc.Name, err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return &VerificationError{Name: doc.Name, Reason: "malformed signature"}
+	}
+
+	var ok bool
+	switch key := v.PublicKey.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, doc.Data, sig)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(doc.Data)
+		ok = ecdsa.VerifyASN1(key, digest[:], sig)
+	default:
+		return fmt.Errorf("unsupported public key type %T", v.PublicKey)
+	}
+	if !ok {
+		return &VerificationError{Name: doc.Name, Reason: "invalid signature"}
+	}
+	return nil
+}
+
+// ParseChecksums parses a manifest in the format of sha256sum, mapping file
+// names to their digest.
+func ParseChecksums(manifest []byte) (map[string][]byte, error) {
+	out := map[string][]byte{}
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(text, " ")
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("malformed checksum manifest at line %d", line)
+		}
+
+		digest, err := hex.DecodeString(sum)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("malformed checksum at line %d", line)
+		}
+		out[name] = digest
+	}
+	return out, scanner.Err()
+}
+
+// ParsePublicKey parses a PEM encoded PKIX public key, such as cosign.pub.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in public key")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse public key: %w", err)
+	}
+
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// VerifyBundleFiles checks the SHA-256 of every file of fsys listed in the
+// manifest of a bundle. Missing and mismatching files are reported with a
+// *VerificationError.
+func VerifyBundleFiles(fsys fs.FS, files []BundleFile) error {
+	var errs []error
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, &VerificationError{Name: file.Name, Reason: "listed in the bundle manifest but missing"})
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != file.SHA256 {
+			errs = append(errs, &VerificationError{Name: file.Name, Reason: "sha256 " + got + " does not match the bundle manifest"})
+		}
+	}
+	return errors.Join(errs...)
+}
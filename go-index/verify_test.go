@@ -0,0 +1,184 @@
+package index
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+const testIndex = `["v0.1.0","v0.2.0"]`
+
+func sha256sum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestChecksumVerifier(t *testing.T) {
+	tt := []struct {
+		name      string
+		files     fstest.MapFS
+		wantError error
+	}{
+		{
+			name: "valid",
+			files: fstest.MapFS{
+				ContainerIndexFile: {Data: []byte(testIndex)},
+				ChecksumsFile:      {Data: []byte(sha256sum(testIndex) + "  " + ContainerIndexFile + "\n")},
+			},
+		},
+		{
+			name: "tampered",
+			files: fstest.MapFS{
+				ContainerIndexFile: {Data: []byte(`["v0.1.0","v9.9.9"]`)},
+				ChecksumsFile:      {Data: []byte(sha256sum(testIndex) + " *" + ContainerIndexFile + "\n")},
+			},
+			wantError: ErrVerification,
+		},
+		{
+			name: "not listed",
+			files: fstest.MapFS{
+				ContainerIndexFile: {Data: []byte(testIndex)},
+				ChecksumsFile:      {Data: []byte(sha256sum(testIndex) + "  " + OperatorIndexFile + "\n")},
+			},
+			wantError: ErrVerification,
+		},
+		{
+			name: "missing manifest",
+			files: fstest.MapFS{
+				ContainerIndexFile: {Data: []byte(testIndex)},
+			},
+			wantError: ErrVerification,
+		},
+		{
+			name: "malformed index is not a verification failure",
+			files: fstest.MapFS{
+				ContainerIndexFile: {Data: []byte(`{`)},
+				ChecksumsFile:      {Data: []byte(sha256sum(`{`) + "  " + ContainerIndexFile + "\n")},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fetch := &ContainerIndexFSFetcher{FS: tc.files, Verifier: &ChecksumVerifier{}}
+			_, err := fetch.GetImages(ctx)
+			if tc.wantError == nil {
+				if errors.Is(err, ErrVerification) {
+					t.Errorf("unexpected verification error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.wantError) {
+				t.Errorf("error: %v != %v", err, tc.wantError)
+			}
+		})
+	}
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(testIndex))
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecPrivate, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name      string
+		key       crypto.PublicKey
+		sig       []byte
+		status    int
+		wantError error
+	}{
+		{name: "ed25519", key: edPublic, sig: ed25519.Sign(edPrivate, []byte(testIndex))},
+		{name: "ecdsa", key: &ecPrivate.PublicKey, sig: ecSig},
+		{name: "wrong key", key: edPublic, sig: ed25519.Sign(edPrivate, []byte(`[]`)), wantError: ErrVerification},
+		{name: "missing signature", key: edPublic, status: http.StatusNotFound, wantError: ErrVerification},
+		{name: "unavailable", key: edPublic, status: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/"+OperatorIndexFile, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(testIndex))
+			})
+			mux.HandleFunc("/"+OperatorIndexFile+SignatureSuffix, func(w http.ResponseWriter, r *http.Request) {
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+					return
+				}
+				_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(tc.sig) + "\n"))
+			})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			fetch := &OperatorIndexFetcher{
+				URL:      ts.URL + "/" + OperatorIndexFile,
+				Client:   ts.Client(),
+				Verifier: &SignatureVerifier{PublicKey: tc.key},
+			}
+			images, err := fetch.GetImages(context.Background())
+
+			switch {
+			case tc.status == http.StatusServiceUnavailable:
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || errors.Is(err, ErrVerification) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			case tc.wantError != nil:
+				if !errors.Is(err, tc.wantError) {
+					t.Errorf("error: %v != %v", err, tc.wantError)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case len(images) != 2:
+				t.Errorf("unexpected images: %v", images)
+			}
+		})
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !public.Equal(key) {
+		t.Error("parsed key does not match")
+	}
+
+	if _, err := ParsePublicKey([]byte("not a key")); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,71 @@
+package index
+
+import (
+	"sort"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+// sortVersions returns the tags that parse as semantic versions in ascending order.
+func sortVersions(tags []string) []string {
+	var versions semver.Collection
+	for _, tag := range tags {
+		ver, err := semver.NewSemver(tag)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, ver)
+	}
+
+	sort.Sort(versions)
+
+	out := make([]string, 0, len(versions))
+	for _, ver := range versions {
+		out = append(out, ver.Original())
+	}
+	return out
+}
+
+// SortTags returns the unique tags in ascending semantic version order, ties
+// and tags that are not versions being ordered lexically, the latter last.
+func SortTags(tags []string) []string {
+	type tag struct {
+		name    string
+		version *semver.Version
+	}
+
+	seen := map[string]struct{}{}
+	var sorted []tag
+	for _, name := range tags {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		ver, err := semver.NewSemver(name)
+		if err != nil {
+			ver = nil
+		}
+		sorted = append(sorted, tag{name: name, version: ver})
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.version == nil && b.version == nil:
+			return a.name < b.name
+		case a.version == nil || b.version == nil:
+			return b.version == nil
+		}
+		if c := a.version.Compare(b.version); c != 0 {
+			return c < 0
+		}
+		return a.name < b.name
+	})
+
+	out := make([]string, 0, len(sorted))
+	for _, t := range sorted {
+		out = append(out, t.name)
+	}
+	return out
+}
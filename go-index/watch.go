@@ -0,0 +1,249 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	IndexContainer IndexKind = "container"
+	IndexOperator  IndexKind = "operator"
+
+	defaultWatchInterval   = 5 * time.Minute
+	defaultWatchMaxBackoff = time.Hour
+)
+
+type (
+	IndexKind string
+
+	// IndexChange is emitted by the Watcher when tags are added to or removed
+	// from an index.
+	IndexChange struct {
+		Index   IndexKind
+		Added   []string
+		Removed []string
+		At      time.Time
+	}
+
+	// Watcher periodically fetches the indexes and notifies subscribers of the
+	// tags added and removed since the previous fetch. The first successful
+	// fetch of each index is the baseline and produces no change.
+	Watcher struct {
+		// Container and Operator are the fetchers to watch, either can be nil.
+		Container ContainerIndexFetch
+		Operator  OperatorIndexFetch
+		// Interval between fetches, defaults to 5 minutes.
+		Interval time.Duration
+		// MaxBackoff caps the delay after consecutive failures, defaults to 1 hour.
+		MaxBackoff time.Duration
+		// OnError is called for every failed fetch.
+		OnError func(error)
+
+		mu          sync.Mutex
+		nextID      int
+		subscribers map[int]func(context.Context, IndexChange)
+		previous    map[IndexKind][]string
+	}
+
+	watchSubscription struct {
+		ch     chan IndexChange
+		done   chan struct{}
+		once   sync.Once
+		mu     sync.Mutex
+		closed bool
+	}
+)
+
+// OnChange registers fn to be called synchronously for every change. The
+// returned function removes the subscription.
+func (w *Watcher) OnChange(fn func(IndexChange)) (cancel func()) {
+	return w.subscribe(func(_ context.Context, change IndexChange) {
+		fn(change)
+	})
+}
+
+// Subscribe returns a channel receiving every change. Delivery blocks the
+// watcher until the change is received, so buffer should be sized to the
+// expected consumer latency. The channel is closed by cancel.
+func (w *Watcher) Subscribe(buffer int) (changes <-chan IndexChange, cancel func()) {
+	sub := &watchSubscription{
+		ch:   make(chan IndexChange, buffer),
+		done: make(chan struct{}),
+	}
+	unsubscribe := w.subscribe(sub.send)
+
+	return sub.ch, func() {
+		sub.once.Do(func() {
+			close(sub.done)
+			unsubscribe()
+
+			sub.mu.Lock()
+			defer sub.mu.Unlock()
+			sub.closed = true
+			close(sub.ch)
+		})
+	}
+}
+
+func (s *watchSubscription) send(ctx context.Context, change IndexChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.ch <- change:
+	case <-s.done:
+	case <-ctx.Done():
+	}
+}
+
+func (w *Watcher) subscribe(fn func(context.Context, IndexChange)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subscribers == nil {
+		w.subscribers = map[int]func(context.Context, IndexChange){}
+	}
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subscribers, id)
+	}
+}
+
+// Run polls the indexes until ctx is cancelled, backing off exponentially
+// while fetches fail. It always returns a non-nil error.
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	maxBackoff := w.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultWatchMaxBackoff
+	}
+
+	failures := 0
+	for {
+		wait := interval
+		if _, err := w.Poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if w.OnError != nil {
+				w.OnError(err)
+			}
+			failures++
+			wait = backoff(interval, maxBackoff, failures)
+		} else {
+			failures = 0
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll fetches the indexes once, notifies subscribers and returns the changes.
+func (w *Watcher) Poll(ctx context.Context) ([]IndexChange, error) {
+	var (
+		changes []IndexChange
+		errs    []error
+	)
+
+	if w.Container != nil {
+		images, err := w.Container.GetImages(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot get container index: %w", err))
+		} else if change, ok := w.update(IndexContainer, images); ok {
+			changes = append(changes, change)
+		}
+	}
+
+	if w.Operator != nil {
+		images, err := w.Operator.GetImages(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot get operator index: %w", err))
+		} else if change, ok := w.update(IndexOperator, images); ok {
+			changes = append(changes, change)
+		}
+	}
+
+	w.mu.Lock()
+	subscribers := make([]func(context.Context, IndexChange), 0, len(w.subscribers))
+	for id := 0; id < w.nextID; id++ {
+		if fn, ok := w.subscribers[id]; ok {
+			subscribers = append(subscribers, fn)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, change := range changes {
+		for _, fn := range subscribers {
+			fn(ctx, change)
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+func (w *Watcher) update(kind IndexKind, tags []string) (IndexChange, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.previous == nil {
+		w.previous = map[IndexKind][]string{}
+	}
+	previous, seen := w.previous[kind]
+	w.previous[kind] = tags
+	if !seen {
+		return IndexChange{}, false
+	}
+
+	change := IndexChange{
+		Index:   kind,
+		Added:   missingTags(tags, previous),
+		Removed: missingTags(previous, tags),
+		At:      time.Now(),
+	}
+	return change, len(change.Added) > 0 || len(change.Removed) > 0
+}
+
+// missingTags returns the tags of a that are not in b, in the order of a.
+func missingTags(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, tag := range b {
+		in[tag] = struct{}{}
+	}
+
+	var out []string
+	for _, tag := range a {
+		if _, ok := in[tag]; !ok {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// backoff doubles interval for every failure up to limit.
+func backoff(interval, limit time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 0; i < failures && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
@@ -0,0 +1,145 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Poll(t *testing.T) {
+	responses := []ContainerImages{
+		{"v0.2.1", "v0.2.2"},
+		{"v0.2.1", "v0.2.2"},
+		{"v0.2.2", "v0.2.3", "v0.2.4"},
+	}
+	calls := 0
+	w := &Watcher{
+		Container: &ContainerIndexFetchMock{
+			GetImagesFunc: func(ctx context.Context) (ContainerImages, error) {
+				out := responses[calls]
+				calls++
+				return out, nil
+			},
+		},
+	}
+
+	var got []IndexChange
+	cancel := w.OnChange(func(change IndexChange) {
+		got = append(got, change)
+	})
+	defer cancel()
+
+	ctx := context.Background()
+	for range responses {
+		if _, err := w.Poll(ctx); err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+	}
+
+	if want := 1; len(got) != want {
+		t.Fatalf("want: %v changes != got: %v", want, len(got))
+	}
+	if want, got := []string{"v0.2.3", "v0.2.4"}, got[0].Added; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+	if want, got := []string{"v0.2.1"}, got[0].Removed; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+	if want, got := IndexContainer, got[0].Index; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		calls    int
+		failures int
+	)
+	w := &Watcher{
+		Operator: &OperatorIndexFetchMock{
+			GetImagesFunc: func(ctx context.Context) (OperatorImages, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				switch calls {
+				case 1:
+					return OperatorImages{"v1.0.0"}, nil
+				case 2:
+					return nil, http.ErrHandlerTimeout
+				default:
+					return OperatorImages{"v1.0.0", "v1.1.0"}, nil
+				}
+			},
+		},
+		Interval:   time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failures++
+		},
+	}
+
+	changes, unsubscribe := w.Subscribe(1)
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- w.Run(ctx)
+	}()
+
+	select {
+	case change := <-changes:
+		if want, got := []string{"v1.1.0"}, change.Added; !reflect.DeepEqual(want, got) {
+			t.Errorf("want: %v != got: %v", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("error: %v != %v", err, context.Canceled)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want, got := 1, failures; want != got {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
+
+func TestWatcher_SubscribeCancel(t *testing.T) {
+	w := &Watcher{}
+	changes, cancel := w.Subscribe(0)
+	cancel()
+	cancel()
+
+	if _, ok := <-changes; ok {
+		t.Error("channel not closed after cancel")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tt := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: time.Second},
+		{failures: 1, want: 2 * time.Second},
+		{failures: 3, want: 8 * time.Second},
+		{failures: 100, want: time.Minute},
+	}
+
+	for _, tc := range tt {
+		if got := backoff(time.Second, time.Minute, tc.failures); tc.want != got {
+			t.Errorf("failures %d want: %v != got: %v", tc.failures, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+// aliasTag matches floating tags such as v1 and v1.2, which point to the
+// latest release of a major or minor version.
+var aliasTag = regexp.MustCompile(`^v?\d+(\.\d+)?$`)
+
+// NormalizeTags returns the canonical order of an index: the unique release
+// tags in ascending semantic version order, then the aliases such as v1 and
+// v1.2 in ascending order, then any other tag in lexical order.
+func NormalizeTags(tags []string) []string {
+	var releases, aliases []string
+	for _, tag := range SortTags(tags) {
+		if aliasTag.MatchString(tag) {
+			aliases = append(aliases, tag)
+		} else {
+			releases = append(releases, tag)
+		}
+	}
+
+	// SortTags puts the tags that are not versions last, keep them there.
+	i := len(releases)
+	for i > 0 {
+		if _, err := semver.NewSemver(releases[i-1]); err == nil {
+			break
+		}
+		i--
+	}
+	out := make([]string, 0, len(releases)+len(aliases))
+	out = append(out, releases[:i]...)
+	out = append(out, aliases...)
+	return append(out, releases[i:]...)
+}
+
+// FormatIndex rewrites an index document in its canonical form, see
+// NormalizeTags, MarshalTags and MarshalReleases. The format of the document,
+// legacy or v2, is kept.
+func FormatIndex(data []byte) ([]byte, error) {
+	var releases Releases
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("could not decode index: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return MarshalReleases(releases)
+	}
+	return MarshalTags(NormalizeTags(releases.Tags()))
+}
+
+// FormatMappings rewrites the operator mappings in their canonical form, see
+// MarshalMappings.
+func FormatMappings(data []byte) ([]byte, error) {
+	var mappings OperatorMappings
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("could not decode operator mappings: %w", err)
+	}
+	return MarshalMappings(mappings)
+}
+
+// MarshalTags encodes tags as the indented JSON array committed in this
+// repository.
+func MarshalTags(tags []string) ([]byte, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	return marshalIndent(tags)
+}
+
+func marshalIndent(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to name and renames it
+// over name, so that a failure never leaves a partially written file behind.
+func WriteFileAtomic(name string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file for %s: %w", name, err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write %s: %w", name, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write %s: %w", name, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write %s: %w", name, err)
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("cannot replace %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSortTags(t *testing.T) {
+	got := SortTags([]string{"v1.2.1", "v1.2.0", "latest", "v1", "v0.4.9", "v0.4.8", "v1.2.0", "v1.10.0", "edge"})
+	want := []string{"v0.4.8", "v0.4.9", "v1", "v1.2.0", "v1.2.1", "v1.10.0", "edge", "latest"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{"v1.2.1", "v0.1", "v1.2.0", "latest", "v1", "v0.4.9", "v0", "v0.4.8", "v1.2.0", "v1.10.0", "edge"})
+	want := []string{"v0.4.8", "v0.4.9", "v1.2.0", "v1.2.1", "v1.10.0", "v0", "v0.1", "v1", "edge", "latest"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}
+
+func TestFormatIndex(t *testing.T) {
+	got, err := FormatIndex([]byte(`["v0.2.0","v0","v0.1.1",  "v0.2.0"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[\n  \"v0.1.1\",\n  \"v0.2.0\",\n  \"v0\"\n]\n"
+	if want != string(got) {
+		t.Errorf("want: %q != got: %q", want, got)
+	}
+
+	again, err := FormatIndex(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(again) {
+		t.Errorf("want: %q != got: %q", got, again)
+	}
+
+	if _, err := FormatIndex([]byte(`{}`)); err == nil {
+		t.Error("want error for a document that is not an index")
+	}
+}
+
+func TestMarshalMappings(t *testing.T) {
+	got, err := MarshalMappings(OperatorMappings{
+		LatestMapping: "image:3",
+		"v1.9.0":      "image:1",
+		"v1.10.0":     "image:2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\n  \"v1.10.0\": \"image:2\",\n  \"v1.9.0\": \"image:1\",\n  \"latest\": \"image:3\"\n}\n"
+	if want != string(got) {
+		t.Errorf("want: %s != got: %s", want, got)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, ContainerIndexFile)
+
+	data, err := MarshalTags(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(name, data, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[]\n"; want != string(got) {
+		t.Errorf("want: %q != got: %q", want, got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(entries); want != got {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "file"), data, 0o644); err == nil {
+		t.Error("expected an error writing to a missing directory")
+	}
+}
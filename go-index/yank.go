@@ -0,0 +1,149 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	semver "github.com/hashicorp/go-version"
+)
+
+const (
+	// ContainerYankedFile and OperatorYankedFile list the yanked releases,
+	// next to the index they apply to.
+	ContainerYankedFile = "container.yanked.json"
+	OperatorYankedFile  = "operator.yanked.json"
+)
+
+type (
+	// YankedVersion is a release that must not be resolved anymore, although
+	// its tag is kept in the index.
+	YankedVersion struct {
+		Version string `json:"version"`
+		Reason  string `json:"reason"`
+	}
+
+	// Denylist lists the yanked releases of an index.
+	Denylist []YankedVersion
+
+	// DenylistFetch is implemented by the fetchers able to read the yanked
+	// releases published next to an index.
+	DenylistFetch interface {
+		GetDenylist(ctx context.Context) (Denylist, error)
+	}
+
+	// ErrVersionYanked is returned by Match for a yanked release.
+	ErrVersionYanked struct {
+		Version string
+		Reason  string
+	}
+)
+
+func (e *ErrVersionYanked) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("version %s has been yanked", e.Version)
+	}
+	return fmt.Sprintf("version %s has been yanked: %s", e.Version, e.Reason)
+}
+
+// Lookup returns the entry yanking tag, comparing versions semantically.
+func (d Denylist) Lookup(tag string) (YankedVersion, bool) {
+	ver, err := semver.NewSemver(tag)
+	for _, yanked := range d {
+		if yanked.Version == tag {
+			return yanked, true
+		}
+		if err != nil {
+			continue
+		}
+		if other, err := semver.NewSemver(yanked.Version); err == nil && other.Equal(ver) {
+			return yanked, true
+		}
+	}
+	return YankedVersion{}, false
+}
+
+// Filter returns the tags that are not yanked, in order.
+func (d Denylist) Filter(tags []string) []string {
+	if len(d) == 0 {
+		return tags
+	}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if _, yanked := d.Lookup(tag); !yanked {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// check returns an *ErrVersionYanked when tag is yanked.
+func (d Denylist) check(tag string) error {
+	if yanked, ok := d.Lookup(tag); ok {
+		return &ErrVersionYanked{Version: tag, Reason: yanked.Reason}
+	}
+	return nil
+}
+
+// withoutYanked returns the releases that are not yanked.
+func (d Denylist) withoutYanked(releases Releases) Releases {
+	if len(d) == 0 {
+		return releases
+	}
+	out := make(Releases, 0, len(releases))
+	for _, release := range releases {
+		if _, yanked := d.Lookup(release.Tag); !yanked {
+			out = append(out, release)
+		}
+	}
+	return out
+}
+
+// GetDenylist reads the yanked releases published next to the index.
+func (c *ContainerIndexFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	var out Denylist
+	url := c.url()
+	err := fetchJSON(ctx, c.Client, url[:strings.LastIndex(url, "/")+1]+ContainerYankedFile, c.Verifier, &out)
+	return out, err
+}
+
+// GetDenylist reads the yanked releases stored next to the index.
+func (c *ContainerIndexFSFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	var out Denylist
+	err := readJSON(ctx, c.FS, path.Join(path.Dir(c.name()), ContainerYankedFile), c.Verifier, &out)
+	return out, err
+}
+
+// GetDenylist reads the yanked releases published next to the index.
+func (c *OperatorIndexFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	var out Denylist
+	url := c.url()
+	err := fetchJSON(ctx, c.Client, url[:strings.LastIndex(url, "/")+1]+OperatorYankedFile, c.Verifier, &out)
+	return out, err
+}
+
+// GetDenylist reads the yanked releases stored next to the index.
+func (c *OperatorIndexFSFetcher) GetDenylist(ctx context.Context) (Denylist, error) {
+	var out Denylist
+	err := readJSON(ctx, c.FS, path.Join(path.Dir(c.name()), OperatorYankedFile), c.Verifier, &out)
+	return out, err
+}
+
+// fetchDenylist reads the yanked releases of fetcher, an index without
+// denylist yanking nothing.
+func fetchDenylist(ctx context.Context, fetcher any) (Denylist, error) {
+	f, ok := fetcher.(DenylistFetch)
+	if !ok {
+		return nil, nil
+	}
+	out, err := f.GetDenylist(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get yanked versions: %w", err)
+	}
+	return out, nil
+}
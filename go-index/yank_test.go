@@ -0,0 +1,61 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContainer_yanked(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		ContainerIndexFile:  &fstest.MapFile{Data: []byte(`["v1.0.0","v1.1.0","v1.2.0","v1.3.0-rc1"]`)},
+		ContainerYankedFile: &fstest.MapFile{Data: []byte(`[{"version":"1.2.0","reason":"broken TLS"},{"version":"v1.3.0-rc1","reason":"bad build"}]`)},
+	}
+	container := &Container{Fetcher: &ContainerIndexRetryFetcher{Fetcher: &ContainerIndexFSFetcher{FS: fsys}}}
+
+	last, err := container.Last(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v1.1.0"; want != last {
+		t.Errorf("want: %v != got: %v", want, last)
+	}
+
+	latest, err := container.Latest(ctx, ChannelEdge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v1.1.0"; want != latest {
+		t.Errorf("want: %v != got: %v", want, latest)
+	}
+
+	_, err = container.Match(ctx, "v1.2.0")
+	var yanked *ErrVersionYanked
+	if !errors.As(err, &yanked) {
+		t.Fatalf("want: %T != got: %v", yanked, err)
+	}
+	if want := "broken TLS"; want != yanked.Reason {
+		t.Errorf("want: %v != got: %v", want, yanked.Reason)
+	}
+
+	if _, err := container.Match(ctx, "1.1.0"); err != nil {
+		t.Errorf("want: <nil> != got: %v", err)
+	}
+
+	// Without a denylist nothing is yanked.
+	delete(fsys, ContainerYankedFile)
+	if last, err = container.Last(ctx); err != nil || last != "v1.3.0-rc1" {
+		t.Errorf("want: v1.3.0-rc1 != got: %v, %v", last, err)
+	}
+}
+
+func TestDenylist_Filter(t *testing.T) {
+	denylist := Denylist{{Version: "v2.0.0"}, {Version: "latest"}}
+	got := denylist.Filter([]string{"v1.0.0", "2.0.0", "latest", "edge"})
+	if want := []string{"v1.0.0", "edge"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %v != got: %v", want, got)
+	}
+}